@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/playbymail/fh/internal/cerrs"
+	"github.com/playbymail/fh/internal/config"
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/galaxy"
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/spf13/cobra"
+)
+
+// setupPhase is the phase of turn 0, which holds the game before the first orders.
+const setupPhase = "setup"
+
+// rngModeKey is the game metadata key for the rng mode of the game,
+// which is chosen when the galaxy is created.
+const rngModeKey = "rng"
+
+// rngDerivationKey is the game metadata key for the seed derivation of the
+// game. Games without one were created with rng.DerivationV1.
+const rngDerivationKey = "rng_derivation"
+
+// rngSeedKey is the game metadata key for the seed the galaxy was created
+// with. The later setup commands use the same seed.
+const rngSeedKey = "rng_seed"
+
+// rngTraceKey is the game metadata key that turns on rng tracing for the game.
+const rngTraceKey = "rng_trace"
+
+// runCreateGalaxy generates a galaxy and saves it as the snapshot for turn 0.
+func runCreateGalaxy(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	seed, _ := cmd.Flags().GetString("seed")
+	rngMode, _ := cmd.Flags().GetString("rng")
+	rngDerivation, _ := cmd.Flags().GetString("rng-derivation")
+	traceRNG, _ := cmd.Flags().GetBool("trace-rng")
+	species, _ := cmd.Flags().GetInt("species")
+	stars, _ := cmd.Flags().GetInt("stars")
+	radius, _ := cmd.Flags().GetInt("radius")
+	lessCrowded, _ := cmd.Flags().GetBool("less-crowded")
+	if suggest, _ := cmd.Flags().GetBool("suggest-values"); suggest {
+		return suggestGalaxy(species, stars, radius, lessCrowded)
+	}
+	if storePath == "" || gameID == "" {
+		return fmt.Errorf("--store and --game are required to create a galaxy")
+	}
+
+	params, err := galaxy.NewParams(species, stars, radius, lessCrowded)
+	if err != nil {
+		return err
+	}
+	mode, err := rng.ParseMode(rngMode)
+	if err != nil {
+		return err
+	}
+	derivation, err := rng.ParseDerivation(rngDerivation)
+	if err != nil {
+		return err
+	}
+	if seed == "" {
+		if seed, err = newSeed(mode); err != nil {
+			return err
+		}
+	}
+	factory, err := newGameRNG(mode, derivation, seed, gameID, traceRNG)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	ctx := cmd.Context()
+	if _, err := st.GetGame(ctx, gameID); errors.Is(err, cerrs.ErrNotExist) {
+		if err := st.CreateGame(ctx, gameID, gameID); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	// A galaxy can only be created once, before the first turn.
+	if turn, err := st.GetCurrentTurn(ctx, gameID); err == nil {
+		return fmt.Errorf("game %s already has a galaxy (turn %d): %w", gameID, turn.Num, cerrs.ErrExists)
+	} else if !errors.Is(err, cerrs.ErrNotExist) {
+		return err
+	}
+
+	g, err := galaxy.Generate(params, factory)
+	if err != nil {
+		return err
+	}
+	state, err := world.NewState(g.Entities())
+	if err != nil {
+		return err
+	}
+	ms, ok := st.(store.MetaStore)
+	if !ok {
+		return fmt.Errorf("store does not keep game settings: %w", cerrs.ErrNotImplemented)
+	}
+	if err := ms.SetGameMeta(ctx, gameID, rngModeKey, string(mode)); err != nil {
+		return err
+	}
+	if err := ms.SetGameMeta(ctx, gameID, rngDerivationKey, string(derivation)); err != nil {
+		return err
+	}
+	if err := ms.SetGameMeta(ctx, gameID, rngSeedKey, seed); err != nil {
+		return err
+	}
+	if traceRNG {
+		if err := ms.SetGameMeta(ctx, gameID, rngTraceKey, "on"); err != nil {
+			return err
+		}
+	}
+	if err := st.CreateTurn(ctx, gameID, 0, setupPhase); err != nil {
+		return err
+	}
+	if err := state.Save(ctx, st, gameID, 0); err != nil {
+		return err
+	}
+	if err := factory.save(ctx, st, gameID, "galaxy"); err != nil {
+		return err
+	}
+
+	fmt.Printf("created galaxy for %d species: %d stars, %d planets, %d natural wormholes, radius %d parsecs\n",
+		params.Species, len(g.Stars), len(g.Planets), g.NumWormholes(), params.Radius)
+	fmt.Printf("rng: %s %s, seed: %s\n", mode, derivation, seed)
+	return nil
+}
+
+// runCreateHomeSystemTemplates generates the home system templates and adds
+// them to the snapshot for turn 0. The galaxy must already exist.
+func runCreateHomeSystemTemplates(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	seed, _ := cmd.Flags().GetString("seed")
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	ctx := cmd.Context()
+	state, err := loadSetup(cmd, st, gameID)
+	if err != nil {
+		return err
+	}
+	if len(state.ByKind(world.KindHomeSystem)) != 0 {
+		return fmt.Errorf("game %s already has home system templates: %w", gameID, cerrs.ErrExists)
+	}
+	factory, err := gameFactory(cmd, st, gameID, seed)
+	if err != nil {
+		return err
+	}
+
+	o := state.Mutate()
+	for _, t := range galaxy.GenerateHomeSystemTemplates(factory) {
+		o.Upsert(t)
+		fmt.Printf("created template for %d planets, potential %d\n", t.NumPlanets, t.Potential)
+	}
+	if err := o.Commit().Save(ctx, st, gameID, 0); err != nil {
+		return err
+	}
+	if err := factory.save(ctx, st, gameID, "home-system-templates"); err != nil {
+		return err
+	}
+	fmt.Printf("seed: %s\n", factory.seed)
+	return nil
+}
+
+// runCreateSpecies creates the species in the configuration file and adds
+// them to the snapshot for turn 0.
+func runCreateSpecies(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	configPath, _ := cmd.Flags().GetString("config")
+	radius, _ := cmd.Flags().GetInt("radius")
+	seed, _ := cmd.Flags().GetString("seed")
+
+	configs, err := config.LoadSpecies(configPath)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	state, err := loadSetup(cmd, st, gameID)
+	if err != nil {
+		return err
+	}
+	factory, err := gameFactory(cmd, st, gameID, seed)
+	if err != nil {
+		return err
+	}
+	o := state.Mutate()
+	created, err := galaxy.AddSpecies(o, configs, radius, factory)
+	if err != nil {
+		return err
+	}
+	if err := o.Commit().Save(cmd.Context(), st, gameID, 0); err != nil {
+		return err
+	}
+	if err := factory.save(cmd.Context(), st, gameID, speciesPhase(created)); err != nil {
+		return err
+	}
+
+	for _, sp := range created {
+		fmt.Printf("created SP%02d %s, home planet at %s\n", sp.Number, sp.Name, sp.Home)
+	}
+	fmt.Printf("seed: %s\n", factory.seed)
+	return nil
+}
+
+// speciesPhase returns the rng trace phase of a run of create species.
+// It names the species created, so a later run does not replace the trace.
+func speciesPhase(created []*world.Species) string {
+	if len(created) == 0 {
+		return "species"
+	}
+	return fmt.Sprintf("species/SP%02d-SP%02d", created[0].Number, created[len(created)-1].Number)
+}
+
+// loadSetup loads the snapshot for turn 0, which is only allowed to change
+// before the first turn is run.
+func loadSetup(cmd *cobra.Command, st store.Store, gameID string) (*world.State, error) {
+	ctx := cmd.Context()
+	turn, err := st.GetCurrentTurn(ctx, gameID)
+	if errors.Is(err, cerrs.ErrNotExist) {
+		return nil, fmt.Errorf("game %s has no galaxy, run fh create galaxy first: %w", gameID, err)
+	} else if err != nil {
+		return nil, err
+	}
+	if turn.Num != 0 {
+		return nil, fmt.Errorf("game %s is already at turn %d", gameID, turn.Num)
+	}
+	return world.Load(ctx, st, gameID, 0)
+}
+
+// gameRNG is the random number generator of a game for a setup command.
+type gameRNG struct {
+	rng.Factory // traced if the game has rng tracing on
+
+	seed   string
+	legacy rng.Scoped // the single stream in legacy mode, nil in keyed mode
+}
+
+// legacyStateName is the name of the legacy stream in the rng state of
+// turn 0. Each setup command continues the stream where the last one left
+// it, as if the whole setup ran in one process.
+const legacyStateName = "legacy"
+
+// newGameRNG creates the generator for the seed in the mode and derivation,
+// salted with the game ID, and traced if trace is set.
+func newGameRNG(mode rng.Mode, derivation rng.Derivation, seed, gameID string, trace bool) (*gameRNG, error) {
+	factory, err := rng.NewFactoryForMode(mode, derivation, seed, gameID)
+	if err != nil {
+		return nil, err
+	}
+	g := &gameRNG{Factory: factory, seed: seed}
+	if mode == rng.ModeLegacy {
+		g.legacy = factory.For()
+	}
+	if trace {
+		g.Factory = rng.NewTracer(factory)
+	}
+	return g, nil
+}
+
+// restore resumes the legacy stream from the state saved with turn 0.
+// It does nothing in keyed mode or if no state has been saved.
+func (g *gameRNG) restore(ctx context.Context, st store.Store, gameID string) error {
+	if g.legacy == nil {
+		return nil
+	}
+	rs, ok := st.(store.RNGStateStore)
+	if !ok {
+		return nil
+	}
+	states, err := rs.LoadRNGState(ctx, gameID, 0)
+	if err != nil {
+		return err
+	}
+	if _, ok := states[legacyStateName]; !ok {
+		return nil
+	}
+	return rng.Checkpoint(states).Restore(legacyStateName, g.legacy)
+}
+
+// save saves the trace of the draws made in a phase of turn 0 and, in
+// legacy mode, the state of the stream for the next setup command.
+func (g *gameRNG) save(ctx context.Context, st store.Store, gameID, phase string) error {
+	if err := saveRNGTrace(ctx, st, gameID, 0, phase, g.Factory); err != nil {
+		return err
+	}
+	if g.legacy == nil {
+		return nil
+	}
+	rs, ok := st.(store.RNGStateStore)
+	if !ok {
+		return fmt.Errorf("legacy rng needs a store that keeps rng state: %w", cerrs.ErrNotImplemented)
+	}
+	c := rng.Checkpoint{}
+	if err := c.Save(legacyStateName, g.legacy); err != nil {
+		return err
+	}
+	return rs.SaveRNGState(ctx, gameID, 0, c)
+}
+
+// gameFactory returns the generator for the seed in the rng mode and
+// derivation of the game. The seed defaults to the one recorded with the
+// game, and a different one is an error; it is random only for games
+// created before seeds were recorded. Games without a recorded mode, or in
+// a store without game metadata, use keyed RNG. In legacy mode the stream
+// continues from the last setup command.
+func gameFactory(cmd *cobra.Command, st store.Store, gameID, seed string) (*gameRNG, error) {
+	var meta map[string]string
+	if ms, ok := st.(store.MetaStore); ok {
+		var err error
+		if meta, err = ms.GetGameMeta(cmd.Context(), gameID); err != nil {
+			return nil, err
+		}
+	}
+	mode, err := rng.ParseMode(meta[rngModeKey])
+	if err != nil {
+		return nil, fmt.Errorf("game %s: %w", gameID, err)
+	}
+	derivation, err := rng.ParseDerivation(meta[rngDerivationKey])
+	if err != nil {
+		return nil, fmt.Errorf("game %s: %w", gameID, err)
+	}
+	if stored := meta[rngSeedKey]; seed == "" {
+		seed = stored
+	} else if stored != "" && seed != stored {
+		return nil, fmt.Errorf("game %s was created with a different seed, leave out --seed to use it", gameID)
+	}
+	if seed == "" {
+		if seed, err = newSeed(mode); err != nil {
+			return nil, err
+		}
+	}
+	g, err := newGameRNG(mode, derivation, seed, gameID, meta[rngTraceKey] == "on")
+	if err != nil {
+		return nil, err
+	}
+	if err := g.restore(cmd.Context(), st, gameID); err != nil {
+		return nil, fmt.Errorf("game %s: %w", gameID, err)
+	}
+	return g, nil
+}
+
+// saveRNGTrace saves the trace of the draws made in a phase with the turn.
+// It does nothing if the factory is not traced, and fails if the store
+// does not keep RNG traces.
+func saveRNGTrace(ctx context.Context, st store.Store, gameID string, turnNum int, phase string, factory rng.Factory) error {
+	tracer, ok := factory.(*rng.Tracer)
+	if !ok {
+		return nil
+	}
+	ts, ok := st.(store.RNGTraceStore)
+	if !ok {
+		return fmt.Errorf("rng tracing is on, but the store does not keep rng traces: %w", cerrs.ErrNotImplemented)
+	}
+	data, err := json.Marshal(tracer.Trace())
+	if err != nil {
+		return err
+	}
+	return ts.SaveRNGTrace(ctx, gameID, turnNum, phase, data)
+}
+
+// suggestGalaxy prints the recommended galaxy for the number of species
+// and warns about any stars or radius the game master gave.
+func suggestGalaxy(species, stars, radius int, lessCrowded bool) error {
+	s, err := galaxy.Suggest(species, stars, radius, lessCrowded)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("species:            %6d\n", s.Species)
+	fmt.Printf("stars:              %6d  (recommended %d)\n", s.Stars, s.Recommended.Stars)
+	fmt.Printf("radius:             %6d  (recommended %d)\n", s.Radius, s.Recommended.Radius)
+	fmt.Printf("expected planets:   %6d\n", s.ExpectedPlanets)
+	fmt.Printf("expected wormholes: %6d\n", s.ExpectedWormholes)
+	for _, w := range s.Warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	return nil
+}
+
+// newSeed returns a random seed for games created without one.
+// Legacy seeds are decimal numbers, as the C engine takes them.
+func newSeed(mode rng.Mode) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if mode == rng.ModeLegacy {
+		return strconv.FormatUint(binary.LittleEndian.Uint64(b), 10), nil
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/spf13/cobra"
+)
+
+// runCreate runs a create command with the flags set as on the command line.
+// Flags that are not set have the defaults of fh create galaxy.
+func runCreate(t *testing.T, run func(*cobra.Command, []string) error, flags ...string) error {
+	t.Helper()
+	cmd := &cobra.Command{RunE: run}
+	cmd.SetContext(context.Background())
+	fs := cmd.Flags()
+	fs.String("store", "", "")
+	fs.String("game", "", "")
+	fs.String("seed", "", "")
+	fs.String("config", "", "")
+	fs.String("rng", "keyed", "")
+	fs.String("rng-derivation", "v2", "")
+	fs.Bool("trace-rng", false, "")
+	fs.Int("species", 0, "")
+	fs.Int("stars", 0, "")
+	fs.Int("radius", 0, "")
+	fs.Bool("suggest-values", false, "")
+	fs.Bool("less-crowded", false, "")
+	if err := fs.Parse(flags); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	return run(cmd, nil)
+}
+
+// newSetupStore creates a store with a galaxy and home system templates
+// for four species, created with the extra galaxy flags.
+func newSetupStore(t *testing.T, galaxyFlags ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	st, err := store.NewSQLiteStore(path, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	st.Close()
+
+	flags := append([]string{"--store", path, "--game", "test", "--species", "4"}, galaxyFlags...)
+	if err := runCreate(t, runCreateGalaxy, flags...); err != nil {
+		t.Fatalf("create galaxy: %v", err)
+	}
+	if err := runCreate(t, runCreateHomeSystemTemplates, "--store", path, "--game", "test"); err != nil {
+		t.Fatalf("create home-system-templates: %v", err)
+	}
+	return path
+}
+
+// writeSpecies writes a species configuration file for the named species.
+func writeSpecies(t *testing.T, names ...string) string {
+	t.Helper()
+	var list []string
+	for _, name := range names {
+		list = append(list, fmt.Sprintf(`{"email": "%s@example.com", "name": "%s", "homeworld": "%s Prime",
+			"govt-name": "%s Union", "govt-type": "Democracy",
+			"tech-ml": 4, "tech-gv": 4, "tech-ls": 4, "tech-bi": 3}`, name, name, name, name))
+	}
+	path := filepath.Join(t.TempDir(), "species.json")
+	if err := os.WriteFile(path, []byte("["+strings.Join(list, ",")+"]"), 0644); err != nil {
+		t.Fatalf("failed to write species: %v", err)
+	}
+	return path
+}
+
+func TestCreateSpeciesTracePerRun(t *testing.T) {
+	path := newSetupStore(t, "--trace-rng")
+	for _, names := range [][]string{{"Alpha", "Beta"}, {"Gamma", "Delta"}} {
+		if err := runCreate(t, runCreateSpecies, "--store", path, "--game", "test", "--radius", "1", "--config", writeSpecies(t, names...)); err != nil {
+			t.Fatalf("create species %v: %v", names, err)
+		}
+	}
+
+	st, err := store.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	traces, err := st.LoadRNGTraces(context.Background(), "test", 0)
+	if err != nil {
+		t.Fatalf("failed to load rng traces: %v", err)
+	}
+	var phases []string
+	for phase := range traces {
+		phases = append(phases, phase)
+	}
+	slices.Sort(phases)
+	want := []string{"galaxy", "home-system-templates", "species/SP01-SP02", "species/SP03-SP04"}
+	if !slices.Equal(phases, want) {
+		t.Errorf("expected phases %v, got %v", want, phases)
+	}
+}
+
+func TestCreateRecordsSeed(t *testing.T) {
+	path := newSetupStore(t, "--seed", "gamma")
+
+	st, err := store.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	meta, err := st.GetGameMeta(context.Background(), "test")
+	st.Close()
+	if err != nil {
+		t.Fatalf("failed to get game meta: %v", err)
+	}
+	if meta[rngSeedKey] != "gamma" {
+		t.Errorf("expected seed gamma, got %q", meta[rngSeedKey])
+	}
+
+	config := writeSpecies(t, "Alpha")
+	if err := runCreate(t, runCreateSpecies, "--store", path, "--game", "test", "--radius", "1", "--config", config, "--seed", "delta"); err == nil {
+		t.Error("expected error for a seed that is not the seed of the game")
+	}
+	if err := runCreate(t, runCreateSpecies, "--store", path, "--game", "test", "--radius", "1", "--config", config, "--seed", "gamma"); err != nil {
+		t.Errorf("create species with the seed of the game: %v", err)
+	}
+}
+
+func TestCreateRecordsRandomSeed(t *testing.T) {
+	path := newSetupStore(t)
+
+	st, err := store.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	meta, err := st.GetGameMeta(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("failed to get game meta: %v", err)
+	}
+	if meta[rngSeedKey] == "" {
+		t.Error("expected the random seed to be recorded")
+	}
+}
+
+func TestCreateLegacyContinuesStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	st, err := store.NewSQLiteStore(path, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	st.Close()
+
+	if err := runCreate(t, runCreateGalaxy, "--store", path, "--game", "test", "--species", "4", "--rng", "legacy", "--seed", "42", "--trace-rng"); err != nil {
+		t.Fatalf("create galaxy: %v", err)
+	}
+	st, err = store.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	states, err := st.LoadRNGState(context.Background(), "test", 0)
+	st.Close()
+	if err != nil {
+		t.Fatalf("failed to load rng state: %v", err)
+	}
+
+	if err := runCreate(t, runCreateHomeSystemTemplates, "--store", path, "--game", "test"); err != nil {
+		t.Fatalf("create home-system-templates: %v", err)
+	}
+	st, err = store.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	traces, err := st.LoadRNGTraces(context.Background(), "test", 0)
+	st.Close()
+	if err != nil {
+		t.Fatalf("failed to load rng traces: %v", err)
+	}
+	var trace rng.Trace
+	if err := json.Unmarshal(traces["home-system-templates"], &trace); err != nil {
+		t.Fatalf("failed to decode trace: %v", err)
+	}
+	if len(trace.Draws) == 0 {
+		t.Fatal("expected draws in the home-system-templates trace")
+	}
+
+	// the templates are drawn from the stream where the galaxy left it
+	r := rng.NewAlgorithmM(0)
+	if err := rng.Checkpoint(states).Restore(legacyStateName, r); err != nil {
+		t.Fatalf("failed to restore the stream after the galaxy: %v", err)
+	}
+	for i, d := range trace.Draws {
+		var want string
+		switch d.Method {
+		case "Intn":
+			want = strconv.Itoa(r.Intn(d.N))
+		case "Uint64":
+			want = strconv.FormatUint(r.Uint64(), 10)
+		case "Float64":
+			want = strconv.FormatFloat(r.Float64(), 'g', -1, 64)
+		}
+		if d.Value != want {
+			t.Fatalf("draw %d: expected %s from the stream after the galaxy, got %s", i, want, d.Value)
+		}
+	}
+}
+
+func TestCreateJSONStore(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.NewJSONStore(dir, true)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	st.Close()
+
+	if err := runCreate(t, runCreateGalaxy, "--store", dir, "--game", "test", "--species", "4"); err != nil {
+		t.Fatalf("create galaxy: %v", err)
+	}
+	if err := runCreate(t, runCreateHomeSystemTemplates, "--store", dir, "--game", "test"); err != nil {
+		t.Fatalf("create home-system-templates: %v", err)
+	}
+
+	st, err = store.OpenJSONStore(dir)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	entities, err := st.LoadSnapshot(context.Background(), "test", 0)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if len(entities) == 0 {
+		t.Error("expected the galaxy in the JSON store")
+	}
+}
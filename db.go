@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the game database",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema migrations",
+}
+
+var dbMigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openMigrateStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		statuses, err := st.MigrationStatus(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tSTATUS\tAPPLIED AT\tNOTES")
+		for _, s := range statuses {
+			state, notes := "pending", ""
+			if s.Applied {
+				state = "applied"
+			}
+			switch {
+			case s.Unknown:
+				notes = "unknown to this version of fh"
+			case s.Modified:
+				notes = "checksum mismatch"
+			case !s.CanRevert:
+				notes = "no down migration"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, state, s.AppliedAt, notes)
+		}
+		return w.Flush()
+	},
+}
+
+var dbMigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openMigrateStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if err := st.UpgradeSchema(cmd.Context()); err != nil {
+			return err
+		}
+		version, err := st.GetSchemaVersion(cmd.Context())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("schema version %s\n", version)
+		return nil
+	},
+}
+
+var dbMigrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recently applied migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		if steps < 1 {
+			return fmt.Errorf("--steps must be at least 1, got %d", steps)
+		}
+
+		st, err := openMigrateStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		if err := st.DowngradeSchema(cmd.Context(), steps); err != nil {
+			return err
+		}
+		version, err := st.GetSchemaVersion(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if version == "" {
+			fmt.Println("schema version none")
+		} else {
+			fmt.Printf("schema version %s\n", version)
+		}
+		return nil
+	},
+}
+
+// openStore opens the store at the path given by --store: a JSON store if
+// the path is a directory, otherwise a SQLite database.
+func openStore(path string) (store.Store, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		st, err := store.OpenJSONStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+	st, err := store.OpenSQLiteStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// openMigrateStore opens the store named by --store without applying
+// pending migrations, so that status and down see the database as it is.
+func openMigrateStore(cmd *cobra.Command) (*store.SQLiteStore, error) {
+	path, _ := cmd.Flags().GetString("store")
+	return store.OpenSQLiteStoreNoUpgrade(path)
+}
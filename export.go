@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/playbymail/fh/internal/cerrs"
+	"github.com/playbymail/fh/internal/config"
+	"github.com/playbymail/fh/internal/data/legacy"
+	"github.com/playbymail/fh/internal/data/snapshot"
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/spf13/cobra"
+)
+
+// runExportSpecies writes each species in the snapshot for the turn to its
+// own file, SPnn.json, in the format of the C JSON writer.
+func runExportSpecies(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	turnNum, _ := cmd.Flags().GetInt("turn")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	state, err := world.Load(cmd.Context(), st, gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	species := state.ByKind(world.KindSpecies)
+	if len(species) == 0 {
+		return fmt.Errorf("game %s has no species in turn %d: %w", gameID, turnNum, cerrs.ErrNotExist)
+	}
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return err
+	}
+	for _, sp := range species {
+		name := filepath.Join(outputPath, string(sp.ID())+".json")
+		if err := config.WriteCJSON(name, sp); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	fmt.Printf("exported %d species to %s\n", len(species), outputPath)
+	return nil
+}
+
+// runExportLegacy writes the snapshot for the turn as the binary data files
+// of the C engine, so the C tools can be run on the game.
+func runExportLegacy(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	turnNum, _ := cmd.Flags().GetInt("turn")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	state, err := world.Load(cmd.Context(), st, gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	var order *legacy.Order
+	if ms, ok := st.(store.MetaStore); ok {
+		meta, err := ms.GetGameMeta(cmd.Context(), gameID)
+		if err != nil {
+			return err
+		}
+		if data, ok := meta[legacyOrderKey]; ok {
+			if err := json.Unmarshal([]byte(data), &order); err != nil {
+				return fmt.Errorf("game %s: %s: %w", gameID, legacyOrderKey, err)
+			}
+		}
+	}
+	files, err := legacy.FromState(state, order)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return err
+	}
+	if err := files.Write(outputPath); err != nil {
+		return err
+	}
+	fmt.Printf("exported turn %d to %s: %d stars, %d planets, %d species\n",
+		turnNum, outputPath, len(files.Stars), len(files.Planets), len(files.Species))
+	return nil
+}
+
+// runExportSnapshot writes the snapshot for the turn as a directory of JSON
+// files with a manifest, which can be imported with fh import snapshot.
+func runExportSnapshot(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	turnNum, _ := cmd.Flags().GetInt("turn")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	state, err := world.Load(cmd.Context(), st, gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	if state.Len() == 0 {
+		return fmt.Errorf("game %s has no snapshot for turn %d: %w", gameID, turnNum, cerrs.ErrNotExist)
+	}
+	m := &snapshot.Manifest{EngineVersion: version.String(), Game: gameID, Turn: turnNum}
+	if err := snapshot.Write(outputPath, m, state.Entities()); err != nil {
+		return err
+	}
+	fmt.Printf("exported %d entities in %d files to %s\n", state.Len(), len(m.Files), outputPath)
+	fmt.Printf("content hash: %s\n", m.ContentHash)
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/playbymail/fh/internal/cerrs"
+	"github.com/playbymail/fh/internal/data/legacy"
+	"github.com/playbymail/fh/internal/data/snapshot"
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/spf13/cobra"
+)
+
+// ordersPhase is the phase of an imported turn, which is waiting for orders.
+const ordersPhase = "orders"
+
+// legacyOrderKey is the game metadata key for the order of the records in
+// the C data files the game was imported from, as JSON. fh export legacy
+// writes the records in the same order.
+const legacyOrderKey = "legacy_order"
+
+// runImportLegacy reads the binary data files of the C engine and saves them
+// as the snapshot for a turn, so a running game can move to this engine.
+// The turn defaults to the turn number in galaxy.dat and must come after
+// the latest turn of the game.
+func runImportLegacy(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+
+	files, err := legacy.Read(dir)
+	if err != nil {
+		return err
+	}
+	entities, err := files.Entities()
+	if err != nil {
+		return err
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		return err
+	}
+	for _, w := range files.CheckLocations() {
+		fmt.Printf("warning: %s\n", w)
+	}
+	if namplas, ships := files.Deleted(); namplas+ships > 0 {
+		fmt.Printf("warning: %d deleted namplas and %d deleted ships are not imported, export writes them back\n", namplas, ships)
+	}
+	order, err := files.Order()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+
+	turnNum := int(files.Galaxy.TurnNumber)
+	if cmd.Flags().Changed("turn") {
+		turnNum, _ = cmd.Flags().GetInt("turn")
+	}
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+	if err := importState(cmd.Context(), st, gameID, turnNum, state, map[string]string{legacyOrderKey: string(data)}); err != nil {
+		return err
+	}
+
+	fmt.Printf("imported turn %d: %d stars, %d planets, %d species, %d colonies, %d ships\n", turnNum,
+		len(state.ByKind(world.KindStar)), len(state.ByKind(world.KindPlanet)), len(state.ByKind(world.KindSpecies)),
+		len(state.ByKind(world.KindColony)), len(state.ByKind(world.KindShip)))
+	return nil
+}
+
+// runImportSnapshot reads a snapshot directory written by fh export snapshot
+// and saves it as the snapshot for a turn. The game and turn default to
+// those in the manifest. With --dry-run, the snapshot is only checked.
+func runImportSnapshot(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+	storePath, _ := cmd.Flags().GetString("store")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	m, entities, err := snapshot.Read(dir)
+	if err != nil {
+		return err
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		return err
+	}
+	gameID, turnNum := m.Game, m.Turn
+	if cmd.Flags().Changed("game") {
+		gameID, _ = cmd.Flags().GetString("game")
+	}
+	if cmd.Flags().Changed("turn") {
+		turnNum, _ = cmd.Flags().GetInt("turn")
+	}
+	fmt.Printf("snapshot of game %s turn %d from fh %s: %d entities, %s\n",
+		m.Game, m.Turn, m.EngineVersion, state.Len(), m.ContentHash)
+	if dryRun {
+		fmt.Printf("dry run: snapshot is valid, not imported\n")
+		return nil
+	}
+	if storePath == "" {
+		return fmt.Errorf("--store is required unless --dry-run is set")
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+	if err := importState(cmd.Context(), st, gameID, turnNum, state, nil); err != nil {
+		return err
+	}
+	fmt.Printf("imported game %s turn %d\n", gameID, turnNum)
+	return nil
+}
+
+// importState saves the state as a new turn of the game, creating the game
+// if it does not exist. The turn must come after the latest turn of the game.
+// The metadata is set on the game before the turn is created. The turn is
+// removed again if the state cannot be saved.
+func importState(ctx context.Context, st store.Store, gameID string, turnNum int, state *world.State, meta map[string]string) error {
+	if gameID == "" {
+		return fmt.Errorf("missing game id")
+	}
+	if turnNum < 0 {
+		return fmt.Errorf("invalid turn %d", turnNum)
+	}
+	phase := ordersPhase
+	if turnNum == 0 {
+		phase = setupPhase
+	}
+
+	if _, err := st.GetGame(ctx, gameID); errors.Is(err, cerrs.ErrNotExist) {
+		if err := st.CreateGame(ctx, gameID, gameID); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if turn, err := st.GetCurrentTurn(ctx, gameID); err == nil && turn.Num >= turnNum {
+		return fmt.Errorf("game %s already has turn %d: %w", gameID, turn.Num, cerrs.ErrExists)
+	} else if err != nil && !errors.Is(err, cerrs.ErrNotExist) {
+		return err
+	}
+	if len(meta) > 0 {
+		ms, ok := st.(store.MetaStore)
+		if !ok {
+			return fmt.Errorf("store does not keep game metadata: %w", cerrs.ErrNotImplemented)
+		}
+		for key, value := range meta {
+			if err := ms.SetGameMeta(ctx, gameID, key, value); err != nil {
+				return err
+			}
+		}
+	}
+	if err := st.CreateTurn(ctx, gameID, turnNum, phase); err != nil {
+		return err
+	}
+	if err := state.Save(ctx, st, gameID, turnNum); err != nil {
+		// remove the empty turn, so it does not hide the latest turn and
+		// the import can be run again
+		if derr := st.DeleteTurn(ctx, gameID, turnNum); derr != nil {
+			return fmt.Errorf("%w (and failed to remove turn %d: %v)", err, turnNum, derr)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// failingSnapshotStore is a store that cannot save snapshots.
+type failingSnapshotStore struct {
+	*store.MemoryStore
+}
+
+var errSnapshotFailed = errors.New("snapshot failed")
+
+func (s failingSnapshotStore) SaveSnapshot(ctx context.Context, gameID string, turnNum int, entities []store.Entity) error {
+	return errSnapshotFailed
+}
+
+func TestImportStateRemovesTurnOnFailure(t *testing.T) {
+	ctx := context.Background()
+	state, err := world.NewState([]world.Entity{&world.Galaxy{EntityID: ids.Galaxy(), Radius: 8}})
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	mem := store.NewMemoryStore()
+	if err := importState(ctx, mem, "test", 3, state, nil); err != nil {
+		t.Fatalf("import turn 3: %v", err)
+	}
+
+	if err := importState(ctx, failingSnapshotStore{mem}, "test", 4, state, nil); !errors.Is(err, errSnapshotFailed) {
+		t.Fatalf("expected the snapshot error, got %v", err)
+	}
+	turn, err := mem.GetCurrentTurn(ctx, "test")
+	if err != nil {
+		t.Fatalf("failed to get current turn: %v", err)
+	}
+	if turn.Num != 3 {
+		t.Errorf("expected the failed turn to be removed, current turn is %d", turn.Num)
+	}
+
+	// and the import can be run again
+	if err := importState(ctx, mem, "test", 4, state, nil); err != nil {
+		t.Errorf("import turn 4 again: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/playbymail/fh/internal/cerrs"
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/spf13/cobra"
+)
+
+// runInspectRNG prints the rng draws saved with a turn. With --key, only
+// the draws from streams whose keys start with the given keys are printed,
+// so a game master can show the draws behind a single order.
+func runInspectRNG(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+	turnNum, _ := cmd.Flags().GetInt("turn")
+	keys, _ := cmd.Flags().GetStringArray("key")
+	phase, _ := cmd.Flags().GetString("phase")
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	ts, ok := st.(store.RNGTraceStore)
+	if !ok {
+		return fmt.Errorf("store does not keep rng traces: %w", cerrs.ErrNotImplemented)
+	}
+	traces, err := ts.LoadRNGTraces(cmd.Context(), gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	if len(traces) == 0 {
+		return fmt.Errorf("game %s turn %d has no rng traces, is rng tracing on?", gameID, turnNum)
+	}
+	var phases []string
+	for name := range traces {
+		if phase == "" || name == phase {
+			phases = append(phases, name)
+		}
+	}
+	if len(phases) == 0 {
+		return fmt.Errorf("game %s turn %d has no rng trace for phase %q", gameID, turnNum, phase)
+	}
+	sort.Strings(phases)
+
+	for _, name := range phases {
+		var trace rng.Trace
+		if err := json.Unmarshal(traces[name], &trace); err != nil {
+			return fmt.Errorf("phase %s: %w", name, err)
+		}
+		draws := trace.Match(keys...)
+		fmt.Printf("phase %s: %d streams, %d draws, %d matching\n", name, len(trace.Streams), len(trace.Draws), len(draws))
+		for _, d := range draws {
+			call := d.Method + "()"
+			if d.Method == "Intn" {
+				call = fmt.Sprintf("Intn(%d)", d.N)
+			}
+			fmt.Printf("  %s #%d %s = %s\n", strings.Join(trace.Streams[d.Stream].Keys, "/"), d.Index, call, d.Value)
+		}
+	}
+	return nil
+}
@@ -8,13 +8,17 @@ func (e Error) Error() string {
 }
 
 const (
-	ErrNotImplemented      = Error("not implemented")
-	ErrExists              = Error("already exists")
-	ErrNotExist            = Error("does not exist")
-	ErrNotOpened           = Error("failed to open")
-	ErrSchemaSetupFailed   = Error("schema setup failed")
-	ErrSchemaUpgradeFailed = Error("schema upgrade failed")
-	ErrSchemaTooNew        = Error("schema version is too new")
-	ErrSchemaTooOld        = Error("schema version is too old")
-	ErrNoMigrations        = Error("no migrations found")
+	ErrNotImplemented        = Error("not implemented")
+	ErrExists                = Error("already exists")
+	ErrInvalidName           = Error("invalid name")
+	ErrNotExist              = Error("does not exist")
+	ErrNotOpened             = Error("failed to open")
+	ErrSchemaSetupFailed     = Error("schema setup failed")
+	ErrSchemaUpgradeFailed   = Error("schema upgrade failed")
+	ErrSchemaTooNew          = Error("schema version is too new")
+	ErrSchemaTooOld          = Error("schema version is too old")
+	ErrNoMigrations          = Error("no migrations found")
+	ErrNoDownMigration       = Error("no down migration")
+	ErrMigrationChecksum     = Error("migration checksum mismatch")
+	ErrSchemaDowngradeFailed = Error("schema downgrade failed")
 )
@@ -0,0 +1,53 @@
+package cerrs
+
+import "fmt"
+
+// ErrGameNotFound is returned when a game does not exist.
+// It wraps ErrNotExist.
+type ErrGameNotFound struct {
+	GameID string
+}
+
+func (e *ErrGameNotFound) Error() string {
+	return fmt.Sprintf("game %q: %s", e.GameID, ErrNotExist)
+}
+
+func (e *ErrGameNotFound) Unwrap() error {
+	return ErrNotExist
+}
+
+// ErrTurnNotFound is returned when a turn does not exist in a game.
+// Turn is -1 when the game has no turns at all.
+// It wraps ErrNotExist.
+type ErrTurnNotFound struct {
+	GameID string
+	Turn   int
+}
+
+func (e *ErrTurnNotFound) Error() string {
+	if e.Turn < 0 {
+		return fmt.Sprintf("game %q: no turns: %s", e.GameID, ErrNotExist)
+	}
+	return fmt.Sprintf("game %q: turn %d: %s", e.GameID, e.Turn, ErrNotExist)
+}
+
+func (e *ErrTurnNotFound) Unwrap() error {
+	return ErrNotExist
+}
+
+// ErrReportNotFound is returned when no report exists for an actor and mime type.
+// It wraps ErrNotExist.
+type ErrReportNotFound struct {
+	GameID string
+	Turn   int
+	Actor  string
+	Mime   string
+}
+
+func (e *ErrReportNotFound) Error() string {
+	return fmt.Sprintf("game %q: turn %d: report %q %q: %s", e.GameID, e.Turn, e.Actor, e.Mime, ErrNotExist)
+}
+
+func (e *ErrReportNotFound) Unwrap() error {
+	return ErrNotExist
+}
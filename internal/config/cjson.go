@@ -0,0 +1,345 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarshalCJSON returns v encoded the way the C programs write JSON with cJSON,
+// so Go output can be compared byte for byte with C golden files:
+//
+//   - objects and arrays are indented with 4 spaces;
+//   - the output ends with a newline;
+//   - every field is written, ignoring omitempty; nil slices and maps are
+//     written as empty arrays and objects, and nil pointers as null;
+//   - numbers are doubles, written like cJSON's print_number: integral
+//     values without a fraction, others with %1.15g, or %1.17g if that
+//     is needed to read back the same value;
+//   - strings are not HTML escaped.
+//
+// Field names and the text of types implementing encoding.TextMarshaler or
+// json.Marshaler are the same as for encoding/json.
+func MarshalCJSON(v any) ([]byte, error) {
+	w := &cjsonWriter{}
+	if err := w.value(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	w.buf.WriteByte('\n')
+	return w.buf.Bytes(), nil
+}
+
+// WriteCJSON writes v to the file in the format of MarshalCJSON.
+func WriteCJSON(name string, v any) error {
+	data, err := MarshalCJSON(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+type cjsonWriter struct {
+	buf bytes.Buffer
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (w *cjsonWriter) value(v reflect.Value, depth int) error {
+	if !v.IsValid() {
+		w.buf.WriteString("null")
+		return nil
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		w.buf.WriteString("null")
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		data, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return err
+		}
+		return w.raw(data, depth)
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		w.string(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return w.value(v.Elem(), depth)
+	case reflect.Bool:
+		w.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.number(float64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.number(float64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		w.number(v.Float())
+	case reflect.String:
+		w.string(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			// byte slices are base64 strings, as in encoding/json
+			data, err := json.Marshal(v.Interface())
+			if err != nil {
+				return err
+			}
+			return w.raw(data, depth)
+		}
+		w.buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			w.separator(i, depth+1)
+			if err := w.value(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		w.close(']', v.Len(), depth)
+	case reflect.Map:
+		return w.mapValue(v, depth)
+	case reflect.Struct:
+		return w.structValue(v, depth)
+	default:
+		return fmt.Errorf("cjson: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// separator starts the i'th element of an array or object.
+func (w *cjsonWriter) separator(i, depth int) {
+	if i > 0 {
+		w.buf.WriteByte(',')
+	}
+	w.newline(depth)
+}
+
+// close ends an array or object with n elements.
+func (w *cjsonWriter) close(c byte, n, depth int) {
+	if n > 0 {
+		w.newline(depth)
+	}
+	w.buf.WriteByte(c)
+}
+
+func (w *cjsonWriter) newline(depth int) {
+	w.buf.WriteByte('\n')
+	w.buf.WriteString(strings.Repeat("    ", depth))
+}
+
+func (w *cjsonWriter) key(i int, name string, depth int) {
+	w.separator(i, depth)
+	w.string(name)
+	w.buf.WriteString(": ")
+}
+
+func (w *cjsonWriter) mapValue(v reflect.Value, depth int) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		var key string
+		switch {
+		case k.Type().Implements(textMarshalerType):
+			text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return err
+			}
+			key = string(text)
+		case k.Kind() == reflect.String:
+			key = k.String()
+		case k.CanInt():
+			key = strconv.FormatInt(k.Int(), 10)
+		case k.CanUint():
+			key = strconv.FormatUint(k.Uint(), 10)
+		default:
+			return fmt.Errorf("cjson: unsupported map key type %s", k.Type())
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	w.buf.WriteByte('{')
+	for i, e := range entries {
+		w.key(i, e.key, depth+1)
+		if err := w.value(e.value, depth+1); err != nil {
+			return err
+		}
+	}
+	w.close('}', len(entries), depth)
+	return nil
+}
+
+func (w *cjsonWriter) structValue(v reflect.Value, depth int) error {
+	w.buf.WriteByte('{')
+	n := 0
+	if err := w.fields(v, &n, depth); err != nil {
+		return err
+	}
+	w.close('}', n, depth)
+	return nil
+}
+
+// fields writes the fields of a struct, flattening embedded structs the
+// way encoding/json does. n counts the fields written so far.
+func (w *cjsonWriter) fields(v reflect.Value, n *int, depth int) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				if err := w.fields(fv, n, depth); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		w.key(*n, name, depth+1)
+		*n++
+		if err := w.value(fv, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// number writes a number the way cJSON prints a double.
+func (w *cjsonWriter) number(d float64) {
+	switch {
+	case math.IsNaN(d) || math.IsInf(d, 0):
+		w.buf.WriteString("null")
+	case d == math.Trunc(d) && d >= math.MinInt32 && d <= math.MaxInt32:
+		w.buf.WriteString(strconv.FormatInt(int64(d), 10))
+	default:
+		s := fmt.Sprintf("%1.15g", d)
+		if f, err := strconv.ParseFloat(s, 64); err != nil || f != d {
+			s = fmt.Sprintf("%1.17g", d)
+		}
+		w.buf.WriteString(s)
+	}
+}
+
+// string writes a string the way cJSON escapes it: only quotes, backslashes
+// and control characters are escaped.
+func (w *cjsonWriter) string(s string) {
+	w.buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			w.buf.WriteByte('\\')
+			w.buf.WriteByte(c)
+		case '\b':
+			w.buf.WriteString(`\b`)
+		case '\f':
+			w.buf.WriteString(`\f`)
+		case '\n':
+			w.buf.WriteString(`\n`)
+		case '\r':
+			w.buf.WriteString(`\r`)
+		case '\t':
+			w.buf.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				fmt.Fprintf(&w.buf, `\u%04x`, c)
+			} else {
+				w.buf.WriteByte(c)
+			}
+		}
+	}
+	w.buf.WriteByte('"')
+}
+
+// raw rewrites JSON produced by a json.Marshaler in the cJSON format.
+func (w *cjsonWriter) raw(data []byte, depth int) error {
+	// Walk the tokens rather than decoding into a map, which would lose
+	// the order of the object keys.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return w.tokens(dec, depth)
+}
+
+// tokens copies one JSON value from the decoder.
+func (w *cjsonWriter) tokens(dec *json.Decoder, depth int) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := 0
+		isObject := tok == '{'
+		w.buf.WriteByte(byte(tok))
+		for dec.More() {
+			if isObject {
+				k, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				w.key(n, k.(string), depth+1)
+			} else {
+				w.separator(n, depth+1)
+			}
+			if err := w.tokens(dec, depth+1); err != nil {
+				return err
+			}
+			n++
+		}
+		end, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		w.close(byte(end.(json.Delim)), n, depth)
+	case json.Number:
+		f, err := tok.Float64()
+		if err != nil {
+			return err
+		}
+		w.number(f)
+	case string:
+		w.string(tok)
+	case bool:
+		w.buf.WriteString(strconv.FormatBool(tok))
+	case nil:
+		w.buf.WriteString("null")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/world"
+)
+
+func TestMarshalCJSON_Species(t *testing.T) {
+	species := []*SpeciesConfig{
+		{Email: "a@example.com", GovtName: "Council", GovtType: "Democracy", Homeworld: "Earth", Name: "Humans", ML: 4, GV: 4, LS: 4, BI: 3},
+		{Email: "b@example.com", Name: "Zorgons", Experimental: &Experimental{EconUnits: 500, MakeBridges: true}},
+	}
+	want := `[
+    {
+        "email": "a@example.com",
+        "govt-name": "Council",
+        "govt-type": "Democracy",
+        "homeworld": "Earth",
+        "name": "Humans",
+        "tech-ml": 4,
+        "tech-gv": 4,
+        "tech-ls": 4,
+        "tech-bi": 3,
+        "experimental": null
+    },
+    {
+        "email": "b@example.com",
+        "govt-name": "",
+        "govt-type": "",
+        "homeworld": "",
+        "name": "Zorgons",
+        "tech-ml": 0,
+        "tech-gv": 0,
+        "tech-ls": 0,
+        "tech-bi": 0,
+        "experimental": {
+            "x-econ-units": 500,
+            "x-bridges": true,
+            "x-ma-base": 0,
+            "x-mi-base": 0,
+            "x-ship-yards": 0,
+            "x-tech-bi": 0,
+            "x-tech-gv": 0,
+            "x-tech-ls": 0,
+            "x-tech-ma": 0,
+            "x-tech-mi": 0,
+            "x-tech-ml": 0
+        }
+    }
+]
+`
+	got, err := MarshalCJSON(species)
+	if err != nil {
+		t.Fatalf("MarshalCJSON() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("MarshalCJSON() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalCJSON_World(t *testing.T) {
+	colony := &world.Colony{
+		EntityID: "COLONY:1:PLANET:1,2,3,4",
+		Name:     "Earth",
+		Species:  1,
+		PlanetID: "PLANET:1,2,3,4",
+		Location: world.Location{Coords: world.Coords{X: 1, Y: 2, Z: 3}, Orbit: 4},
+		Status:   world.HomePlanet | world.Populated,
+		Items:    world.Inventory{world.IU: 10, world.AU: 20},
+	}
+	got, err := MarshalCJSON(colony)
+	if err != nil {
+		t.Fatalf("MarshalCJSON() error = %v", err)
+	}
+	// embedded structs are flattened and map keys are sorted, as in encoding/json
+	want, err := json.MarshalIndent(colony, "", "    ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want)+"\n" {
+		t.Errorf("MarshalCJSON() =\n%s\nwant\n%s", got, want)
+	}
+
+	// nil slices and maps are written as empty ones
+	species := &world.Species{EntityID: "SP01", Number: 1, Name: "Humans"}
+	got, err = MarshalCJSON(species)
+	if err != nil {
+		t.Fatalf("MarshalCJSON() error = %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"neutral_gases", "poison_gases", "contacts", "allies", "enemies"} {
+		if v, ok := fields[name].([]any); !ok || len(v) != 0 {
+			t.Errorf("%s = %v, want []", name, fields[name])
+		}
+	}
+
+	// every entity round trips
+	planet := &world.Planet{EntityID: "PLANET:1,2,3,4", Gases: []world.GasPercent{{Gas: world.O2, Percent: 20}}, MiningDifficulty: 250}
+	for _, e := range []world.Entity{colony, species, planet} {
+		data, err := MarshalCJSON(e)
+		if err != nil {
+			t.Fatalf("MarshalCJSON(%s) error = %v", e.ID(), err)
+		}
+		clone := reflect.New(reflect.TypeOf(e).Elem()).Interface()
+		if err := json.Unmarshal(data, clone); err != nil {
+			t.Fatalf("%s: %v", e.ID(), err)
+		}
+		again, err := MarshalCJSON(clone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("%s did not round trip:\n%s\n%s", e.ID(), data, again)
+		}
+	}
+}
+
+func TestMarshalCJSON_Values(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{1, "1"},
+		{-7, "-7"},
+		{1.0, "1"},
+		{1.5, "1.5"},
+		{0.1, "0.1"},
+		{2.0 / 3, "0.66666666666666663"},
+		{1e20, "1e+20"},
+		{int64(1) << 40, "1099511627776"},
+		{math.NaN(), "null"},
+		{"<a&b>", `"<a&b>"`},
+		{"tab\there \"quoted\" \\ \x01", `"tab\there \"quoted\" \\ \u0001"`},
+		{[]int{}, "[]"},
+		{[]int(nil), "[]"},
+		{map[string]int{}, "{}"},
+		{[]int{1, 2}, "[\n    1,\n    2\n]"},
+		{world.O2, `"O2"`},
+		{(*Experimental)(nil), "null"},
+	}
+	for _, tt := range tests {
+		got, err := MarshalCJSON(tt.v)
+		if err != nil {
+			t.Errorf("MarshalCJSON(%v) error = %v", tt.v, err)
+			continue
+		}
+		if string(got) != tt.want+"\n" {
+			t.Errorf("MarshalCJSON(%v) = %q, want %q", tt.v, got, tt.want+"\n")
+		}
+	}
+}
@@ -2,10 +2,26 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/mail"
 	"os"
+	"strings"
 )
 
+// TechPoints is the total of the four starting tech levels a player chooses.
+const TechPoints = 15
+
+// MaxNameLength is the longest name allowed for a species, government or
+// homeworld. The C data files store names in 32 byte arrays.
+const MaxNameLength = 31
+
+// GovtTypes are the allowed values of govt-type.
+var GovtTypes = []string{
+	"Anarchy", "Aristocracy", "Democracy", "Dictatorship", "Hive Mind",
+	"Monarchy", "Oligarchy", "Plutocracy", "Republic", "Technocracy", "Theocracy",
+}
+
 type Experimental struct {
 	EconUnits   int  `json:"x-econ-units"`
 	MakeBridges bool `json:"x-bridges"`
@@ -103,11 +119,110 @@ func LoadSpecies(filename string) ([]*SpeciesConfig, error) {
 		return nil, fmt.Errorf("%s contains too many species (expect 0..%d, got %d)", filename, maxSpecies, len(species))
 	}
 
+	if err := validateSpecies(species); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return species, nil
+}
+
+// validateSpecies checks each species and the list as a whole.
+// It returns every problem found, each with the index of the species.
+func validateSpecies(species []*SpeciesConfig) error {
+	var errs []error
+	report := func(i int, err error) {
+		errs = append(errs, fmt.Errorf("species %d: %w", i, err))
+	}
+
+	names := map[string]int{}
+	homeworlds := map[string]int{}
+	emails := map[string]int{}
+	unique := func(i int, seen map[string]int, field, value string) {
+		key := strings.ToUpper(value)
+		if value == "" {
+			return
+		} else if j, ok := seen[key]; ok {
+			report(i, fmt.Errorf("%s %q is already used by species %d", field, value, j))
+			return
+		}
+		seen[key] = i
+	}
+
 	for i, s := range species {
+		if s == nil {
+			report(i, fmt.Errorf("missing species"))
+			continue
+		}
 		if err := s.Validate(); err != nil {
-			return nil, fmt.Errorf("%s: species %d: %w", filename, i, err)
+			report(i, err)
+		}
+		for _, field := range []struct{ name, value string }{
+			{"name", s.Name}, {"homeworld", s.Homeworld}, {"govt-name", s.GovtName},
+		} {
+			if err := validName(field.value); err != nil {
+				report(i, fmt.Errorf("%s: %w", field.name, err))
+			}
+		}
+		if !validGovtType(s.GovtType) {
+			report(i, fmt.Errorf("govt-type %q must be one of %s", s.GovtType, strings.Join(GovtTypes, ", ")))
+		}
+		if err := validEmail(s.Email); err != nil {
+			report(i, fmt.Errorf("email: %w", err))
 		}
+		if total := s.ML + s.GV + s.LS + s.BI; total != TechPoints {
+			report(i, fmt.Errorf("tech-ml, tech-gv, tech-ls and tech-bi must total %d, got %d", TechPoints, total))
+		}
+		unique(i, names, "name", s.Name)
+		unique(i, homeworlds, "homeworld", s.Homeworld)
+		unique(i, emails, "email", s.Email)
 	}
+	return errors.Join(errs...)
+}
 
-	return species, nil
+// validName checks that a name can be printed in reports and used in orders.
+// Names start with a letter and contain only letters, digits, single spaces,
+// apostrophes, hyphens and periods. Commas and semicolons separate order
+// arguments and comments, so they are not allowed.
+func validName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("missing")
+	case len(name) > MaxNameLength:
+		return fmt.Errorf("%q is longer than %d characters", name, MaxNameLength)
+	case !isLetter(rune(name[0])):
+		return fmt.Errorf("%q must start with a letter", name)
+	case strings.HasSuffix(name, " ") || strings.Contains(name, "  "):
+		return fmt.Errorf("%q has extra spaces", name)
+	}
+	for _, r := range name {
+		if !isLetter(r) && !('0' <= r && r <= '9') && !strings.ContainsRune(" '-.", r) {
+			return fmt.Errorf("%q contains %q", name, r)
+		}
+	}
+	return nil
+}
+
+func isLetter(r rune) bool {
+	return 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z'
+}
+
+func validGovtType(govtType string) bool {
+	for _, t := range GovtTypes {
+		if strings.EqualFold(t, govtType) {
+			return true
+		}
+	}
+	return false
+}
+
+// validEmail checks that the email is a plain address, without a display name.
+func validEmail(email string) error {
+	if email == "" {
+		return fmt.Errorf("missing")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return fmt.Errorf("%q is not a valid address", email)
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -21,10 +22,10 @@ func TestLoadSpecies(t *testing.T) {
 				"homeworld": "Earth",
 				"govt-name": "United Earth",
 				"govt-type": "Democracy",
-				"tech-ml": 10,
-				"tech-gv": 12,
-				"tech-ls": 8,
-				"tech-bi": 15,
+				"tech-ml": 4,
+				"tech-gv": 4,
+				"tech-ls": 4,
+				"tech-bi": 3,
 				"experimental": {
 					"x-econ-units": 1000,
 					"x-bridges": true,
@@ -46,18 +47,26 @@ func TestLoadSpecies(t *testing.T) {
 			name: "multiple species",
 			json: `[
 				{
+					"email": "a@example.com",
 					"name": "Species A",
+					"homeworld": "Alpha",
+					"govt-name": "The Council",
+					"govt-type": "Oligarchy",
 					"tech-ml": 5,
 					"tech-gv": 5,
-					"tech-ls": 5,
-					"tech-bi": 5
+					"tech-ls": 3,
+					"tech-bi": 2
 				},
 				{
+					"email": "b@example.com",
 					"name": "Species B",
-					"tech-ml": 10,
-					"tech-gv": 10,
-					"tech-ls": 10,
-					"tech-bi": 10
+					"homeworld": "Beta",
+					"govt-name": "The Hive",
+					"govt-type": "hive mind",
+					"tech-ml": 3,
+					"tech-gv": 4,
+					"tech-ls": 4,
+					"tech-bi": 4
 				}
 			]`,
 			wantErr:   false,
@@ -133,6 +142,80 @@ func TestLoadSpecies(t *testing.T) {
 	}
 }
 
+func TestLoadSpecies_SetValidation(t *testing.T) {
+	species := func(email, name, homeworld string) string {
+		return `{"email": "` + email + `", "name": "` + name + `", "homeworld": "` + homeworld + `",
+			"govt-name": "Council", "govt-type": "Democracy",
+			"tech-ml": 4, "tech-gv": 4, "tech-ls": 4, "tech-bi": 3}`
+	}
+	tests := []struct {
+		name string
+		json string
+		want []string // each problem, with its species index
+	}{
+		{
+			name: "duplicate names",
+			json: `[` + species("a@example.com", "Humans", "Earth") + `,` + species("b@example.com", "HUMANS", "Mars") + `]`,
+			want: []string{`species 1: name "HUMANS" is already used by species 0`},
+		},
+		{
+			name: "duplicate homeworlds and emails",
+			json: `[` + species("a@example.com", "Humans", "Earth") + `,` + species("b@example.com", "Martians", "Mars") + `,` +
+				species("a@example.com", "Venusians", "earth") + `]`,
+			want: []string{
+				`species 2: homeworld "earth" is already used by species 0`,
+				`species 2: email "a@example.com" is already used by species 0`,
+			},
+		},
+		{
+			name: "bad emails",
+			json: `[` + species("not an email", "Humans", "Earth") + `,` + species("Bob <b@example.com>", "Martians", "Mars") + `]`,
+			want: []string{`species 0: email: "not an email"`, `species 1: email: "Bob <b@example.com>"`},
+		},
+		{
+			name: "bad names",
+			json: `[` + species("a@example.com", "Humans, Inc", "1st Earth") + `,` +
+				species("b@example.com", "The Very Long Name Of The Martians", "Mars  Prime") + `]`,
+			want: []string{
+				`species 0: name: "Humans, Inc" contains ','`,
+				`species 0: homeworld: "1st Earth" must start with a letter`,
+				`species 1: name: "The Very Long Name Of The Martians" is longer than 31 characters`,
+				`species 1: homeworld: "Mars  Prime" has extra spaces`,
+			},
+		},
+		{
+			name: "govt type and tech points",
+			json: `[{"email": "a@example.com", "name": "Humans", "homeworld": "Earth", "govt-name": "Council", "govt-type": "Bureaucracy",
+				"tech-ml": 5, "tech-gv": 5, "tech-ls": 5, "tech-bi": 5}]`,
+			want: []string{
+				`species 0: govt-type "Bureaucracy" must be one of`,
+				`species 0: tech-ml, tech-gv, tech-ls and tech-bi must total 15, got 20`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "species.json")
+			if err := os.WriteFile(filename, []byte(tt.json), 0644); err != nil {
+				t.Fatalf("failed to write test file: %v", err)
+			}
+			_, err := LoadSpecies(filename)
+			if err == nil {
+				t.Fatalf("LoadSpecies() expected error")
+			}
+			lines := strings.Split(strings.TrimPrefix(err.Error(), filename+": "), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("LoadSpecies() error =\n%v\nwant %d problems", err, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(lines[i], want) {
+					t.Errorf("problem %d = %q, want %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
+
 func TestLoadSpecies_FileNotFound(t *testing.T) {
 	_, err := LoadSpecies("/nonexistent/file.json")
 	if err == nil {
@@ -0,0 +1,300 @@
+package legacy
+
+import (
+	"fmt"
+
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Entities converts the data files to world entities: the galaxy, the stars
+// and their planets, and each species with its colonies and ships. Deleted
+// namplas and ships are dropped; Deleted counts them, and Order keeps them
+// so FromState can write them back.
+func (f *Files) Entities() ([]world.Entity, error) {
+	list := []world.Entity{&world.Galaxy{
+		EntityID:    ids.Galaxy(),
+		DNumSpecies: int(f.Galaxy.DNumSpecies),
+		NumSpecies:  int(f.Galaxy.NumSpecies),
+		Radius:      int(f.Galaxy.Radius),
+		TurnNumber:  int(f.Galaxy.TurnNumber),
+	}}
+
+	planets := map[world.Location]world.ID{}
+	for i := range f.Stars {
+		sd := &f.Stars[i]
+		c := world.Coords{X: int(sd.X), Y: int(sd.Y), Z: int(sd.Z)}
+		star := &world.Star{
+			EntityID:   ids.Star(c.X, c.Y, c.Z),
+			Coords:     c,
+			Type:       world.StarType(sd.Type),
+			Color:      world.StarColor(sd.Color),
+			Size:       int(sd.Size),
+			HomeSystem: sd.HomeSystem != 0,
+			Wormhole:   sd.WormHere != 0,
+			VisitedBy:  speciesSet(sd.VisitedBy),
+			Message:    int(sd.Message),
+		}
+		if star.Wormhole {
+			star.WormholeExit = world.Coords{X: int(sd.WormX), Y: int(sd.WormY), Z: int(sd.WormZ)}
+		}
+		first, n := int(sd.PlanetIndex), int(sd.NumPlanets)
+		if first < 0 || n < 0 || first+n > len(f.Planets) {
+			return nil, fmt.Errorf("star %s: planets %d-%d are not in planets.dat", c, first, first+n-1)
+		}
+		list = append(list, star)
+		for orbit := 1; orbit <= n; orbit++ {
+			p := planet(&f.Planets[first+orbit-1], star, orbit)
+			star.Planets = append(star.Planets, p.EntityID)
+			planets[p.Location] = p.EntityID
+			list = append(list, p)
+		}
+	}
+
+	for sp := 1; sp <= int(f.Galaxy.NumSpecies); sp++ {
+		sf, ok := f.Species[sp]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing", SpeciesFileName(sp))
+		}
+		list = append(list, species(sp, &sf.Species))
+		for i := range sf.Namplas {
+			nd := &sf.Namplas[i]
+			if nd.PN == DeletedPN {
+				continue
+			}
+			colony := colony(sp, nd)
+			planetID, ok := planets[colony.Location]
+			if !ok {
+				return nil, fmt.Errorf("%s: PL %s: no planet at %s", SpeciesFileName(sp), colony.Name, colony.Location)
+			}
+			colony.PlanetID = planetID
+			colony.EntityID = ids.Colony(sp, planetID)
+			list = append(list, colony)
+		}
+		for i := range sf.Ships {
+			sd := &sf.Ships[i]
+			if sd.PN == DeletedPN {
+				continue
+			}
+			list = append(list, ship(sp, sd))
+		}
+	}
+	return list, nil
+}
+
+// Deleted returns the number of deleted namplas and ships in the species
+// files, which Entities drops.
+func (f *Files) Deleted() (namplas, ships int) {
+	for _, sf := range f.Species {
+		for _, nd := range sf.Namplas {
+			if nd.PN == DeletedPN {
+				namplas++
+			}
+		}
+		for _, sd := range sf.Ships {
+			if sd.PN == DeletedPN {
+				ships++
+			}
+		}
+	}
+	return namplas, ships
+}
+
+// CheckLocations compares locations.dat with the locations rebuilt from the
+// colonies and ships and returns a message for every difference. The C
+// engine rebuilds the file each turn, so differences mean the files are
+// from different turns. Nothing is checked without locations.dat.
+func (f *Files) CheckLocations() []string {
+	if len(f.Locations) == 0 {
+		return nil
+	}
+	want := map[LocationData]bool{}
+	for _, l := range f.locations() {
+		want[l] = true
+	}
+	var warnings []string
+	for _, l := range f.Locations {
+		if !want[l] {
+			warnings = append(warnings, fmt.Sprintf("locations.dat: SP%02d has nothing at %d %d %d", l.S, l.X, l.Y, l.Z))
+		}
+		delete(want, l)
+	}
+	for _, l := range f.locations() {
+		if want[l] {
+			warnings = append(warnings, fmt.Sprintf("locations.dat: SP%02d at %d %d %d is missing", l.S, l.X, l.Y, l.Z))
+		}
+	}
+	return warnings
+}
+
+// locations returns the systems where each species has a populated colony
+// or a ship that did not jump away, in the order the C engine writes them.
+func (f *Files) locations() []LocationData {
+	var list []LocationData
+	seen := map[LocationData]bool{}
+	add := func(l LocationData) {
+		if !seen[l] {
+			seen[l] = true
+			list = append(list, l)
+		}
+	}
+	for sp := 1; sp <= int(f.Galaxy.NumSpecies); sp++ {
+		sf, ok := f.Species[sp]
+		if !ok {
+			continue
+		}
+		for _, nd := range sf.Namplas {
+			if nd.PN != DeletedPN && world.ColonyStatus(nd.Status).Has(world.Populated) {
+				add(LocationData{S: int8(sp), X: nd.X, Y: nd.Y, Z: nd.Z})
+			}
+		}
+		for _, sd := range sf.Ships {
+			status := world.ShipStatus(sd.Status)
+			if sd.PN != DeletedPN && status != world.ForcedJump && status != world.JumpedInCombat {
+				add(LocationData{S: int8(sp), X: sd.X, Y: sd.Y, Z: sd.Z})
+			}
+		}
+	}
+	return list
+}
+
+func planet(pd *PlanetData, star *world.Star, orbit int) *world.Planet {
+	p := &world.Planet{
+		EntityID:         ids.Planet(star.X, star.Y, star.Z, orbit),
+		StarID:           star.EntityID,
+		Location:         world.Location{Coords: star.Coords, Orbit: orbit},
+		TemperatureClass: int(pd.TemperatureClass),
+		PressureClass:    int(pd.PressureClass),
+		Special:          world.PlanetSpecial(pd.Special),
+		Diameter:         int(pd.Diameter),
+		Gravity:          int(pd.Gravity),
+		MiningDifficulty: int(pd.MiningDifficulty),
+		MDIncrease:       int(pd.MDIncrease),
+		EconEfficiency:   int(pd.EconEfficiency),
+		Message:          int(pd.Message),
+	}
+	for i, gas := range pd.Gas {
+		if gas != 0 {
+			p.Gases = append(p.Gases, world.GasPercent{Gas: world.Gas(gas), Percent: int(pd.GasPercent[i])})
+		}
+	}
+	return p
+}
+
+func species(sp int, sd *SpeciesData) *world.Species {
+	s := &world.Species{
+		EntityID:         ids.Species(sp),
+		Number:           sp,
+		Name:             cstring(sd.Name[:]),
+		GovtName:         cstring(sd.GovtName[:]),
+		GovtType:         cstring(sd.GovtType[:]),
+		Home:             world.Location{Coords: world.Coords{X: int(sd.X), Y: int(sd.Y), Z: int(sd.Z)}, Orbit: int(sd.PN)},
+		RequiredGas:      world.Gas(sd.RequiredGas),
+		RequiredGasMin:   int(sd.RequiredGasMin),
+		RequiredGasMax:   int(sd.RequiredGasMax),
+		NeutralGases:     gases(sd.NeutralGas[:]),
+		PoisonGases:      gases(sd.PoisonGas[:]),
+		AutoOrders:       sd.AutoOrders != 0,
+		HPOriginalBase:   int(sd.HPOriginalBase),
+		EconUnits:        int(sd.EconUnits),
+		FleetCost:        int(sd.FleetCost),
+		FleetPercentCost: int(sd.FleetPercentCost),
+		Contacts:         speciesSet(sd.Contact),
+		Allies:           speciesSet(sd.Ally),
+		Enemies:          speciesSet(sd.Enemy),
+	}
+	for i := range sd.TechLevel {
+		tech := world.Tech(i)
+		s.TechLevel.Set(tech, int(sd.TechLevel[i]))
+		s.InitTechLevel.Set(tech, int(sd.InitTechLevel[i]))
+		s.TechKnowledge.Set(tech, int(sd.TechKnowledge[i]))
+		s.TechEPs.Set(tech, int(sd.TechEPs[i]))
+	}
+	return s
+}
+
+func colony(sp int, nd *NamplaData) *world.Colony {
+	return &world.Colony{
+		Name:         cstring(nd.Name[:]),
+		Species:      sp,
+		Location:     world.Location{Coords: world.Coords{X: int(nd.X), Y: int(nd.Y), Z: int(nd.Z)}, Orbit: int(nd.PN)},
+		Status:       world.ColonyStatus(nd.Status),
+		Hiding:       nd.Hiding != 0,
+		Hidden:       nd.Hidden != 0,
+		SiegeEff:     int(nd.SiegeEff),
+		Shipyards:    int(nd.Shipyards),
+		IUsNeeded:    int(nd.IUsNeeded),
+		AUsNeeded:    int(nd.AUsNeeded),
+		AutoIUs:      int(nd.AutoIUs),
+		AutoAUs:      int(nd.AutoAUs),
+		IUsToInstall: int(nd.IUsToInstall),
+		AUsToInstall: int(nd.AUsToInstall),
+		MIBase:       int(nd.MIBase),
+		MABase:       int(nd.MABase),
+		PopUnits:     int(nd.PopUnits),
+		Items:        inventory(nd.ItemQuantity[:]),
+		UseOnAmbush:  int(nd.UseOnAmbush),
+		Message:      int(nd.Message),
+		Special:      int(nd.Special),
+	}
+}
+
+func ship(sp int, sd *ShipData) *world.Ship {
+	name := cstring(sd.Name[:])
+	return &world.Ship{
+		EntityID:           ids.Ship(sp, name),
+		Name:               name,
+		Species:            sp,
+		Location:           world.Location{Coords: world.Coords{X: int(sd.X), Y: int(sd.Y), Z: int(sd.Z)}, Orbit: int(sd.PN)},
+		Status:             world.ShipStatus(sd.Status),
+		Type:               world.ShipType(sd.Type),
+		Dest:               world.Coords{X: int(sd.DestX), Y: int(sd.DestY), Z: int(sd.DestZ)},
+		JustJumped:         sd.JustJumped != 0,
+		ArrivedViaWormhole: sd.ArrivedViaWormhole != 0,
+		Class:              world.ShipClass(sd.Class),
+		Tonnage:            int(sd.Tonnage),
+		Cargo:              inventory(sd.ItemQuantity[:]),
+		Age:                int(sd.Age),
+		RemainingCost:      int(sd.RemainingCost),
+		LoadingPoint:       int(sd.LoadingPoint),
+		UnloadingPoint:     int(sd.UnloadingPoint),
+		Special:            int(sd.Special),
+	}
+}
+
+// inventory returns the non-zero item quantities, or nil if there are none.
+func inventory[T int16 | int32](quantities []T) world.Inventory {
+	var inv world.Inventory
+	for i, qty := range quantities {
+		if qty != 0 {
+			if inv == nil {
+				inv = world.Inventory{}
+			}
+			inv[world.Item(i)] = int(qty)
+		}
+	}
+	return inv
+}
+
+// gases returns the non-zero gases in the list.
+func gases(list []int8) []world.Gas {
+	var out []world.Gas
+	for _, g := range list {
+		if g != 0 {
+			out = append(out, world.Gas(g))
+		}
+	}
+	return out
+}
+
+// speciesSet returns the species in a C bitmask. Species n is bit
+// (n-1)%32 of word (n-1)/32.
+func speciesSet(mask [NumContactWords]uint32) world.SpeciesSet {
+	var set world.SpeciesSet
+	for i := 0; i < MaxSpecies; i++ {
+		if mask[i/32]&(1<<(i%32)) != 0 {
+			set = append(set, i+1)
+		}
+	}
+	return set
+}
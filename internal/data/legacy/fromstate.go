@@ -0,0 +1,372 @@
+package legacy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// FromState converts world state to the data files, the inverse of Entities.
+// With the Order of the imported files, the records are written in the
+// same places: deleted records are written back as they were read, and a
+// colony or ship that no longer exists leaves a deleted record, so the
+// indexes of the records after it do not change. Records that are not in
+// the order follow: stars in ID order, namplas with the home planet first
+// and then in ID order, ships in ID order. Without an order, every record
+// is written that way. Each star is followed by its planets in orbit order.
+// locations.dat is rebuilt the way the C engine does it. Reserved fields
+// are zero.
+//
+// It is an error if a value does not fit in its C field.
+func FromState(s *world.State, order *Order) (*Files, error) {
+	e, ok := s.GetEntity(ids.Galaxy())
+	if !ok {
+		return nil, fmt.Errorf("no galaxy")
+	}
+	g := e.(*world.Galaxy)
+	p := &packer{where: "galaxy"}
+	f := &Files{
+		Galaxy: GalaxyData{
+			DNumSpecies: p.i32(g.DNumSpecies, "d_num_species"),
+			NumSpecies:  p.i32(g.NumSpecies, "num_species"),
+			Radius:      p.i32(g.Radius, "radius"),
+			TurnNumber:  p.i32(g.TurnNumber, "turn_number"),
+		},
+		Species: map[int]*SpeciesFile{},
+	}
+	if g.NumSpecies > MaxSpecies {
+		return nil, fmt.Errorf("galaxy has %d species, the C engine allows %d", g.NumSpecies, MaxSpecies)
+	}
+
+	planetIndex := map[world.ID]int{}
+	var stars []*world.Star
+	written := map[world.ID]bool{}
+	for _, id := range order.stars() {
+		if e, ok := s.GetEntity(id); ok && !written[id] {
+			stars = append(stars, e.(*world.Star))
+			written[id] = true
+		}
+	}
+	for _, e := range s.ByKind(world.KindStar) {
+		if !written[e.ID()] {
+			stars = append(stars, e.(*world.Star))
+		}
+	}
+	for _, star := range stars {
+		p.where = "star " + star.Coords.String()
+		sd := StarData{
+			X:           p.i8(star.X, "x"),
+			Y:           p.i8(star.Y, "y"),
+			Z:           p.i8(star.Z, "z"),
+			Type:        p.i8(int(star.Type), "type"),
+			Color:       p.i8(int(star.Color), "color"),
+			Size:        p.i8(star.Size, "size"),
+			NumPlanets:  p.i8(len(star.Planets), "num_planets"),
+			HomeSystem:  flag(star.HomeSystem),
+			PlanetIndex: p.i16(len(f.Planets), "planet_index"),
+			Message:     p.i32(star.Message, "message"),
+			VisitedBy:   p.mask(star.VisitedBy, "visited_by"),
+		}
+		if star.Wormhole {
+			sd.WormHere = 1
+			sd.WormX = p.i8(star.WormholeExit.X, "worm_x")
+			sd.WormY = p.i8(star.WormholeExit.Y, "worm_y")
+			sd.WormZ = p.i8(star.WormholeExit.Z, "worm_z")
+		}
+		f.Stars = append(f.Stars, sd)
+		for _, id := range star.Planets {
+			e, ok := s.GetEntity(id)
+			if !ok {
+				return nil, fmt.Errorf("star %s: missing planet %s", star.Coords, id)
+			}
+			planetIndex[id] = len(f.Planets)
+			f.Planets = append(f.Planets, p.planet(e.(*world.Planet)))
+		}
+	}
+
+	for sp := 1; sp <= g.NumSpecies; sp++ {
+		species, ok := s.Species(sp)
+		if !ok {
+			return nil, fmt.Errorf("missing species %d", sp)
+		}
+		sf := &SpeciesFile{Species: p.species(species)}
+		colonies := s.ColoniesOf(sp)
+		sort.SliceStable(colonies, func(i, j int) bool {
+			return colonies[i].Location == species.Home && colonies[j].Location != species.Home
+		})
+		addColony := func(c *world.Colony) error {
+			index, ok := planetIndex[c.PlanetID]
+			if !ok {
+				return fmt.Errorf("PL %s: missing planet %s", c.Name, c.PlanetID)
+			}
+			sf.Namplas = append(sf.Namplas, p.nampla(c, index))
+			written[c.EntityID] = true
+			return nil
+		}
+		so := order.species(sp)
+		for _, r := range so.Namplas {
+			e, live := s.GetEntity(r.ID)
+			switch {
+			case r.Deleted != nil:
+				var nd NamplaData
+				if err := unpack(r.Deleted, &nd); err != nil {
+					return nil, fmt.Errorf("SP%02d: deleted nampla: %w", sp, err)
+				}
+				sf.Namplas = append(sf.Namplas, nd)
+			case live && !written[r.ID]:
+				if err := addColony(e.(*world.Colony)); err != nil {
+					return nil, err
+				}
+			default:
+				// the colony is gone, a deleted record keeps its place
+				sf.Namplas = append(sf.Namplas, NamplaData{PN: DeletedPN})
+			}
+		}
+		for _, c := range colonies {
+			if !written[c.EntityID] {
+				if err := addColony(c); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, r := range so.Ships {
+			e, live := s.GetEntity(r.ID)
+			switch {
+			case r.Deleted != nil:
+				var sd ShipData
+				if err := unpack(r.Deleted, &sd); err != nil {
+					return nil, fmt.Errorf("SP%02d: deleted ship: %w", sp, err)
+				}
+				sf.Ships = append(sf.Ships, sd)
+			case live && !written[r.ID]:
+				sf.Ships = append(sf.Ships, p.ship(e.(*world.Ship)))
+				written[r.ID] = true
+			default:
+				// the ship is gone, a deleted record keeps its place
+				sf.Ships = append(sf.Ships, ShipData{PN: DeletedPN})
+			}
+		}
+		for _, e := range s.ByOwner(sp) {
+			if ship, ok := e.(*world.Ship); ok && !written[ship.EntityID] {
+				sf.Ships = append(sf.Ships, p.ship(ship))
+			}
+		}
+		sf.Species.NumNamplas = int32(len(sf.Namplas))
+		sf.Species.NumShips = int32(len(sf.Ships))
+		f.Species[sp] = sf
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	f.Locations = f.locations()
+	return f, nil
+}
+
+// stars returns the star IDs of the order, which may be nil.
+func (o *Order) stars() []world.ID {
+	if o == nil {
+		return nil
+	}
+	return o.Stars
+}
+
+// species returns the order of a species file, which is empty for a nil
+// order or a species that was not imported.
+func (o *Order) species(sp int) *SpeciesOrder {
+	if o == nil || o.Species[sp] == nil {
+		return &SpeciesOrder{}
+	}
+	return o.Species[sp]
+}
+
+// packer narrows values to the sizes of the C fields and records the first
+// value that does not fit.
+type packer struct {
+	where string // the record being packed, for errors
+	err   error
+}
+
+func (p *packer) check(v, lo, hi int, field string) {
+	if (v < lo || v > hi) && p.err == nil {
+		p.err = fmt.Errorf("%s: %s %d does not fit in the C field (%d to %d)", p.where, field, v, lo, hi)
+	}
+}
+
+func (p *packer) i8(v int, field string) int8 {
+	p.check(v, math.MinInt8, math.MaxInt8, field)
+	return int8(v)
+}
+
+func (p *packer) i16(v int, field string) int16 {
+	p.check(v, math.MinInt16, math.MaxInt16, field)
+	return int16(v)
+}
+
+func (p *packer) i32(v int, field string) int32 {
+	p.check(v, math.MinInt32, math.MaxInt32, field)
+	return int32(v)
+}
+
+func (p *packer) name(b []byte, s, field string) {
+	if !setCString(b, s) && p.err == nil {
+		p.err = fmt.Errorf("%s: %s %q is longer than %d characters", p.where, field, s, len(b)-1)
+	}
+}
+
+// mask returns the C bitmask for the species in the set.
+func (p *packer) mask(set world.SpeciesSet, field string) [NumContactWords]uint32 {
+	var mask [NumContactWords]uint32
+	for _, sp := range set {
+		p.check(sp, 1, MaxSpecies, field)
+		if sp >= 1 && sp <= MaxSpecies {
+			mask[(sp-1)/32] |= 1 << ((sp - 1) % 32)
+		}
+	}
+	return mask
+}
+
+func (p *packer) gases(list []world.Gas, field string, dst []int8) {
+	p.check(len(list), 0, len(dst), field)
+	for i, g := range list {
+		if i < len(dst) {
+			dst[i] = p.i8(int(g), field)
+		}
+	}
+}
+
+func (p *packer) planet(planet *world.Planet) PlanetData {
+	p.where = "planet " + planet.Location.String()
+	pd := PlanetData{
+		TemperatureClass: p.i8(planet.TemperatureClass, "temperature_class"),
+		PressureClass:    p.i8(planet.PressureClass, "pressure_class"),
+		Special:          p.i8(int(planet.Special), "special"),
+		Diameter:         p.i16(planet.Diameter, "diameter"),
+		Gravity:          p.i16(planet.Gravity, "gravity"),
+		MiningDifficulty: p.i16(planet.MiningDifficulty, "mining_difficulty"),
+		EconEfficiency:   p.i16(planet.EconEfficiency, "econ_efficiency"),
+		MDIncrease:       p.i16(planet.MDIncrease, "md_increase"),
+		Message:          p.i32(planet.Message, "message"),
+	}
+	p.check(len(planet.Gases), 0, len(pd.Gas), "gases")
+	for i, g := range planet.Gases {
+		if i < len(pd.Gas) {
+			pd.Gas[i] = p.i8(int(g.Gas), "gas")
+			pd.GasPercent[i] = p.i8(g.Percent, "gas_percent")
+		}
+	}
+	return pd
+}
+
+func (p *packer) species(s *world.Species) SpeciesData {
+	p.where = s.String()
+	sd := SpeciesData{
+		X:                p.i8(s.Home.X, "x"),
+		Y:                p.i8(s.Home.Y, "y"),
+		Z:                p.i8(s.Home.Z, "z"),
+		PN:               p.i8(s.Home.Orbit, "pn"),
+		RequiredGas:      p.i8(int(s.RequiredGas), "required_gas"),
+		RequiredGasMin:   p.i8(s.RequiredGasMin, "required_gas_min"),
+		RequiredGasMax:   p.i8(s.RequiredGasMax, "required_gas_max"),
+		AutoOrders:       flag(s.AutoOrders),
+		HPOriginalBase:   p.i32(s.HPOriginalBase, "hp_original_base"),
+		EconUnits:        p.i32(s.EconUnits, "econ_units"),
+		FleetCost:        p.i32(s.FleetCost, "fleet_cost"),
+		FleetPercentCost: p.i32(s.FleetPercentCost, "fleet_percent_cost"),
+		Contact:          p.mask(s.Contacts, "contact"),
+		Ally:             p.mask(s.Allies, "ally"),
+		Enemy:            p.mask(s.Enemies, "enemy"),
+	}
+	p.name(sd.Name[:], s.Name, "name")
+	p.name(sd.GovtName[:], s.GovtName, "govt_name")
+	p.name(sd.GovtType[:], s.GovtType, "govt_type")
+	p.gases(s.NeutralGases, "neutral_gas", sd.NeutralGas[:])
+	p.gases(s.PoisonGases, "poison_gas", sd.PoisonGas[:])
+	for i := range sd.TechLevel {
+		tech := world.Tech(i)
+		sd.TechLevel[i] = p.i16(s.TechLevel.Get(tech), "tech_level")
+		sd.InitTechLevel[i] = p.i16(s.InitTechLevel.Get(tech), "init_tech_level")
+		sd.TechKnowledge[i] = p.i16(s.TechKnowledge.Get(tech), "tech_knowledge")
+		sd.TechEPs[i] = p.i32(s.TechEPs.Get(tech), "tech_eps")
+	}
+	return sd
+}
+
+func (p *packer) nampla(c *world.Colony, planetIndex int) NamplaData {
+	p.where = "PL " + c.Name
+	nd := NamplaData{
+		X:            p.i8(c.X, "x"),
+		Y:            p.i8(c.Y, "y"),
+		Z:            p.i8(c.Z, "z"),
+		PN:           p.i8(c.Orbit, "pn"),
+		Status:       p.i8(int(c.Status), "status"),
+		Hiding:       flag(c.Hiding),
+		Hidden:       flag(c.Hidden),
+		PlanetIndex:  p.i16(planetIndex, "planet_index"),
+		SiegeEff:     p.i16(c.SiegeEff, "siege_eff"),
+		Shipyards:    p.i16(c.Shipyards, "shipyards"),
+		IUsNeeded:    p.i16(c.IUsNeeded, "IUs_needed"),
+		AUsNeeded:    p.i16(c.AUsNeeded, "AUs_needed"),
+		AutoIUs:      p.i16(c.AutoIUs, "auto_IUs"),
+		AutoAUs:      p.i16(c.AutoAUs, "auto_AUs"),
+		IUsToInstall: p.i16(c.IUsToInstall, "IUs_to_install"),
+		AUsToInstall: p.i16(c.AUsToInstall, "AUs_to_install"),
+		MIBase:       p.i32(c.MIBase, "mi_base"),
+		MABase:       p.i32(c.MABase, "ma_base"),
+		PopUnits:     p.i32(c.PopUnits, "pop_units"),
+		UseOnAmbush:  p.i32(c.UseOnAmbush, "use_on_ambush"),
+		Message:      p.i32(c.Message, "message"),
+		Special:      p.i32(c.Special, "special"),
+	}
+	p.name(nd.Name[:], c.Name, "name")
+	for item, qty := range c.Items {
+		p.check(int(item), 0, MaxItems-1, "item")
+		if item >= 0 && item < MaxItems {
+			nd.ItemQuantity[item] = p.i32(qty, "item_quantity")
+		}
+	}
+	return nd
+}
+
+func (p *packer) ship(s *world.Ship) ShipData {
+	p.where = s.String()
+	sd := ShipData{
+		X:                  p.i8(s.X, "x"),
+		Y:                  p.i8(s.Y, "y"),
+		Z:                  p.i8(s.Z, "z"),
+		PN:                 p.i8(s.Orbit, "pn"),
+		Status:             p.i8(int(s.Status), "status"),
+		Type:               p.i8(int(s.Type), "type"),
+		DestX:              p.i8(s.Dest.X, "dest_x"),
+		DestY:              p.i8(s.Dest.Y, "dest_y"),
+		DestZ:              p.i8(s.Dest.Z, "dest_z"),
+		JustJumped:         flag(s.JustJumped),
+		ArrivedViaWormhole: flag(s.ArrivedViaWormhole),
+		Class:              p.i16(int(s.Class), "class"),
+		Tonnage:            p.i16(s.Tonnage, "tonnage"),
+		Age:                p.i16(s.Age, "age"),
+		RemainingCost:      p.i16(s.RemainingCost, "remaining_cost"),
+		LoadingPoint:       p.i16(s.LoadingPoint, "loading_point"),
+		UnloadingPoint:     p.i16(s.UnloadingPoint, "unloading_point"),
+		Special:            p.i32(s.Special, "special"),
+	}
+	p.name(sd.Name[:], s.Name, "name")
+	for item, qty := range s.Cargo {
+		p.check(int(item), 0, MaxItems-1, "item")
+		if item >= 0 && item < MaxItems {
+			sd.ItemQuantity[item] = p.i16(qty, "item_quantity")
+		}
+	}
+	return sd
+}
+
+func flag(b bool) int8 {
+	if b {
+		return 1
+	}
+	return 0
+}
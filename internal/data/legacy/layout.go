@@ -0,0 +1,210 @@
+// Package legacy reads and writes the binary data files of the C engine.
+//
+// The files are the C structs written with fwrite on x86, so every value is
+// little-endian and the structs include the padding the C compiler adds to
+// align them. The Go types below spell out that padding so encoding/binary
+// reads and writes them byte for byte. A char is a signed byte, a short is
+// 16 bits, and an int or long is 32 bits.
+//
+//	galaxy.dat     galaxy_data
+//	stars.dat      int32 count, then star_data records
+//	planets.dat    int32 count, then planet_data records
+//	spNN.dat       species_data, then its nampla_data and ship_data records
+//	locations.dat  sp_loc_data records, to the end of the file
+package legacy
+
+import "encoding/binary"
+
+// Limits of the C engine.
+const (
+	MaxSpecies      = 100
+	MaxItems        = 38
+	NumContactWords = (MaxSpecies-1)/32 + 1 // 32 species per word
+	// DeletedPN marks namplas and ships that have been deleted.
+	DeletedPN = 99
+)
+
+// byteOrder is the byte order of the C data files.
+var byteOrder = binary.LittleEndian
+
+// GalaxyData is galaxy_data, 16 bytes.
+type GalaxyData struct {
+	DNumSpecies int32
+	NumSpecies  int32
+	Radius      int32
+	TurnNumber  int32
+}
+
+// StarData is binary_star_data_t, 52 bytes.
+type StarData struct {
+	X, Y, Z     int8
+	Type        int8
+	Color       int8
+	Size        int8
+	NumPlanets  int8
+	HomeSystem  int8
+	WormHere    int8
+	WormX       int8
+	WormY       int8
+	WormZ       int8
+	Reserved1   int16
+	Reserved2   int16
+	PlanetIndex int16 // index of the first planet in planets.dat
+	_           [2]byte
+	Message     int32
+	VisitedBy   [NumContactWords]uint32
+	Reserved3   int32
+	Reserved4   int32
+	Reserved5   int32
+}
+
+// PlanetData is binary_planet_data_t, 40 bytes.
+type PlanetData struct {
+	TemperatureClass int8
+	PressureClass    int8
+	Special          int8
+	Reserved1        int8
+	Gas              [4]int8
+	GasPercent       [4]int8
+	Reserved2        int16
+	Diameter         int16
+	Gravity          int16
+	MiningDifficulty int16
+	EconEfficiency   int16
+	MDIncrease       int16
+	Message          int32
+	Reserved3        int32
+	Reserved4        int32
+	Reserved5        int32
+}
+
+// SpeciesData is binary_species_data_t, 264 bytes.
+type SpeciesData struct {
+	Name             [32]byte
+	GovtName         [32]byte
+	GovtType         [32]byte
+	X, Y, Z, PN      int8
+	RequiredGas      int8
+	RequiredGasMin   int8
+	RequiredGasMax   int8
+	Reserved5        int8
+	NeutralGas       [6]int8
+	PoisonGas        [6]int8
+	AutoOrders       int8
+	Reserved3        int8
+	Reserved4        int16
+	TechLevel        [6]int16
+	InitTechLevel    [6]int16
+	TechKnowledge    [6]int16
+	NumNamplas       int32
+	NumShips         int32
+	TechEPs          [6]int32
+	HPOriginalBase   int32
+	EconUnits        int32
+	FleetCost        int32
+	FleetPercentCost int32
+	Contact          [NumContactWords]uint32
+	Ally             [NumContactWords]uint32
+	Enemy            [NumContactWords]uint32
+	Padding          [12]byte
+}
+
+// NamplaData is binary_nampla_data_t, a named planet, 272 bytes.
+type NamplaData struct {
+	Name         [32]byte
+	X, Y, Z, PN  int8
+	Status       int8
+	Reserved1    int8
+	Hiding       int8
+	Hidden       int8
+	PlanetIndex  int16
+	SiegeEff     int16
+	Shipyards    int16
+	Reserved4    int16
+	IUsNeeded    int16
+	AUsNeeded    int16
+	AutoIUs      int16
+	AutoAUs      int16
+	Reserved5    int16
+	IUsToInstall int16
+	AUsToInstall int16
+	_            [2]byte
+	MIBase       int32
+	MABase       int32
+	PopUnits     int32
+	ItemQuantity [MaxItems]int32
+	Reserved6    int32
+	UseOnAmbush  int32
+	Message      int32
+	Special      int32
+	Padding      [28]byte
+}
+
+// ShipData is binary_ship_data_t, 172 bytes.
+type ShipData struct {
+	Name               [32]byte
+	X, Y, Z, PN        int8
+	Status             int8
+	Type               int8
+	DestX, DestY       int8
+	DestZ              int8
+	JustJumped         int8
+	ArrivedViaWormhole int8
+	Reserved1          int8
+	Reserved2          int16
+	Reserved3          int16
+	Class              int16
+	Tonnage            int16
+	ItemQuantity       [MaxItems]int16
+	Age                int16
+	RemainingCost      int16
+	Reserved4          int16
+	LoadingPoint       int16
+	UnloadingPoint     int16
+	_                  [2]byte
+	Special            int32
+	Padding            [28]byte
+}
+
+// LocationData is sp_loc_data, a star system where a species has a colony
+// or a ship, 4 bytes.
+type LocationData struct {
+	S, X, Y, Z int8
+}
+
+// SpeciesFile is the contents of spNN.dat.
+type SpeciesFile struct {
+	Species SpeciesData
+	Namplas []NamplaData
+	Ships   []ShipData
+}
+
+// Files are the contents of the data files for one turn.
+type Files struct {
+	Galaxy    GalaxyData
+	Stars     []StarData
+	Planets   []PlanetData
+	Species   map[int]*SpeciesFile // by species number
+	Locations []LocationData
+}
+
+// cstring returns the C string in b, up to the first NUL.
+func cstring(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// setCString copies s into b as a NUL-terminated C string.
+// It reports false if s does not fit.
+func setCString(b []byte, s string) bool {
+	if len(s) >= len(b) {
+		return false
+	}
+	clear(b)
+	copy(b, s)
+	return true
+}
@@ -0,0 +1,454 @@
+package legacy
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+func TestLayoutSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		size int
+	}{
+		{"galaxy_data", GalaxyData{}, 16},
+		{"star_data", StarData{}, 52},
+		{"planet_data", PlanetData{}, 40},
+		{"species_data", SpeciesData{}, 264},
+		{"nampla_data", NamplaData{}, 272},
+		{"ship_data", ShipData{}, 172},
+		{"sp_loc_data", LocationData{}, 4},
+	}
+	for _, tt := range tests {
+		if got := binary.Size(tt.v); got != tt.size {
+			t.Errorf("%s is %d bytes, want %d", tt.name, got, tt.size)
+		}
+	}
+}
+
+// testFiles returns a small galaxy with one species, one colony, one
+// deleted colony and one ship.
+func testFiles() *Files {
+	f := &Files{
+		Galaxy: GalaxyData{DNumSpecies: 2, NumSpecies: 1, Radius: 8, TurnNumber: 12},
+		Stars: []StarData{
+			{X: 1, Y: 2, Z: 3, Type: 2, Color: 4, Size: 5, NumPlanets: 2, HomeSystem: 1, PlanetIndex: 0},
+			{X: 4, Y: 5, Z: 6, Type: 1, Color: 1, Size: 9, NumPlanets: 1, WormHere: 1, WormX: 1, WormY: 2, WormZ: 3, PlanetIndex: 2},
+		},
+		Planets: []PlanetData{
+			{TemperatureClass: 11, PressureClass: 9, Special: 1, Gas: [4]int8{7, 5}, GasPercent: [4]int8{20, 80}, Diameter: 12, Gravity: 100, MiningDifficulty: 250, EconEfficiency: 100},
+			{TemperatureClass: 3, PressureClass: 0, Diameter: 4, Gravity: 40, MiningDifficulty: 300, MDIncrease: 5, EconEfficiency: 100},
+			{TemperatureClass: 30, PressureClass: 29, Gas: [4]int8{13}, GasPercent: [4]int8{100}, Diameter: 200, Gravity: 900, MiningDifficulty: 900, EconEfficiency: 100, Message: 7},
+		},
+		Species: map[int]*SpeciesFile{1: {
+			Species: SpeciesData{
+				X: 1, Y: 2, Z: 3, PN: 1,
+				RequiredGas: 7, RequiredGasMin: 10, RequiredGasMax: 40,
+				NeutralGas:    [6]int8{1, 3, 5, 6, 11, 12},
+				PoisonGas:     [6]int8{2, 4, 8, 9, 10, 13},
+				TechLevel:     [6]int16{10, 10, 4, 4, 4, 3},
+				InitTechLevel: [6]int16{10, 10, 4, 4, 4, 3},
+				TechEPs:       [6]int32{0, 0, 5},
+				NumNamplas:    2,
+				NumShips:      1,
+				EconUnits:     123,
+				Contact:       [NumContactWords]uint32{1 << 1, 1 << 0},
+				Ally:          [NumContactWords]uint32{1 << 1},
+			},
+			Namplas: []NamplaData{
+				{X: 1, Y: 2, Z: 3, PN: 1, Status: 1 | 8, Shipyards: 1, MIBase: 250, MABase: 250, PopUnits: 1500, ItemQuantity: [MaxItems]int32{9: 10}},
+				{X: 4, Y: 5, Z: 6, PN: DeletedPN},
+			},
+			Ships: []ShipData{
+				{X: 1, Y: 2, Z: 3, PN: 0, Type: 0, Class: 16, Tonnage: 2, ItemQuantity: [MaxItems]int16{10: 5}},
+			},
+		}},
+		Locations: []LocationData{{S: 1, X: 1, Y: 2, Z: 3}},
+	}
+	sp := f.Species[1]
+	copy(sp.Species.Name[:], "Humans")
+	copy(sp.Species.GovtName[:], "Council")
+	copy(sp.Species.GovtType[:], "Democracy")
+	copy(sp.Namplas[0].Name[:], "Terra")
+	copy(sp.Namplas[1].Name[:], "Gone")
+	copy(sp.Ships[0].Name[:], "Pioneer")
+	return f
+}
+
+// writeFiles writes the files the way the C engine does.
+func writeFiles(t *testing.T, dir string, f *Files) {
+	t.Helper()
+	write := func(name string, values ...any) {
+		fp, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fp.Close()
+		for _, v := range values {
+			if err := binary.Write(fp, binary.LittleEndian, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("galaxy.dat", f.Galaxy)
+	write("stars.dat", int32(len(f.Stars)), f.Stars)
+	write("planets.dat", int32(len(f.Planets)), f.Planets)
+	for sp, sf := range f.Species {
+		write(SpeciesFileName(sp), sf.Species, sf.Namplas, sf.Ships)
+	}
+	write("locations.dat", f.Locations)
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	want := testFiles()
+	writeFiles(t, dir, want)
+
+	got, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.Galaxy != want.Galaxy || len(got.Stars) != 2 || len(got.Planets) != 3 || len(got.Locations) != 1 {
+		t.Fatalf("Read() = %+v", got)
+	}
+	if got.Stars[1] != want.Stars[1] || got.Planets[2] != want.Planets[2] {
+		t.Errorf("stars or planets differ")
+	}
+	sp := got.Species[1]
+	if sp == nil || sp.Species != want.Species[1].Species || len(sp.Namplas) != 2 || len(sp.Ships) != 1 || sp.Ships[0] != want.Species[1].Ships[0] {
+		t.Errorf("species file differs: %+v", sp)
+	}
+	if w := got.CheckLocations(); len(w) != 0 {
+		t.Errorf("CheckLocations() = %v", w)
+	}
+
+	// a short file is an error
+	if err := os.Truncate(filepath.Join(dir, "sp01.dat"), 300); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(dir); err == nil {
+		t.Errorf("Read() of a short file expected error")
+	}
+}
+
+func TestEntities(t *testing.T) {
+	entities, err := testFiles().Entities()
+	if err != nil {
+		t.Fatalf("Entities() error = %v", err)
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		t.Fatalf("NewState() error = %v", err)
+	}
+
+	e, _ := state.GetEntity(ids.Galaxy())
+	if g := e.(*world.Galaxy); g.NumSpecies != 1 || g.TurnNumber != 12 {
+		t.Errorf("galaxy = %v", g)
+	}
+	e, ok := state.GetEntity(ids.Star(4, 5, 6))
+	if !ok {
+		t.Fatal("no star at 4 5 6")
+	}
+	star := e.(*world.Star)
+	if !star.Wormhole || star.WormholeExit != (world.Coords{X: 1, Y: 2, Z: 3}) || len(star.Planets) != 1 || star.Planets[0] != ids.Planet(4, 5, 6, 1) {
+		t.Errorf("star = %+v", star)
+	}
+	e, _ = state.GetEntity(ids.Planet(1, 2, 3, 1))
+	planet := e.(*world.Planet)
+	if planet.GasPercent(world.O2) != 20 || len(planet.Gases) != 2 || planet.Special != world.IdealHomePlanet {
+		t.Errorf("planet = %+v", planet)
+	}
+
+	sp, ok := state.Species(1)
+	if !ok {
+		t.Fatal("no species 1")
+	}
+	if sp.Name != "Humans" || sp.GovtType != "Democracy" || sp.RequiredGas != world.O2 || len(sp.NeutralGases) != 6 || sp.EconUnits != 123 {
+		t.Errorf("species = %+v", sp)
+	}
+	if sp.TechLevel != (world.TechLevels{MI: 10, MA: 10, ML: 4, GV: 4, LS: 4, BI: 3}) || sp.TechEPs.ML != 5 {
+		t.Errorf("tech levels = %+v, %+v", sp.TechLevel, sp.TechEPs)
+	}
+	if !sp.Contacts.Has(2) || !sp.Contacts.Has(33) || len(sp.Contacts) != 2 || !sp.Allies.Has(2) || len(sp.Enemies) != 0 {
+		t.Errorf("contacts %v, allies %v, enemies %v", sp.Contacts, sp.Allies, sp.Enemies)
+	}
+
+	colonies := state.ColoniesOf(1)
+	if len(colonies) != 1 {
+		t.Fatalf("colonies = %v", colonies)
+	}
+	if c := colonies[0]; c.Name != "Terra" || c.PlanetID != ids.Planet(1, 2, 3, 1) || !c.Status.Has(world.HomePlanet) || c.Items.Get(world.Item(9)) != 10 || len(c.Items) != 1 {
+		t.Errorf("colony = %+v", c)
+	}
+	ships := state.ShipsAt(1, world.Coords{X: 1, Y: 2, Z: 3})
+	if len(ships) != 1 || ships[0].Name != "Pioneer" || ships[0].Class != world.ShipClass(16) || ships[0].Cargo.Get(world.Item(10)) != 5 {
+		t.Errorf("ships = %v", ships)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	f := testFiles()
+	f.Species[1].Ships = append([]ShipData{{PN: DeletedPN}}, f.Species[1].Ships...)
+	if namplas, ships := f.Deleted(); namplas != 1 || ships != 1 {
+		t.Errorf("Deleted() = %d, %d, want 1, 1", namplas, ships)
+	}
+
+	order, err := f.Order()
+	if err != nil {
+		t.Fatalf("Order() error = %v", err)
+	}
+	if want := []world.ID{ids.Star(1, 2, 3), ids.Star(4, 5, 6)}; !reflect.DeepEqual(order.Stars, want) {
+		t.Errorf("Stars = %v, want %v", order.Stars, want)
+	}
+	so := order.Species[1]
+	if len(so.Namplas) != 2 || so.Namplas[0].ID != ids.Colony(1, ids.Planet(1, 2, 3, 1)) || so.Namplas[1].ID != "" || len(so.Namplas[1].Deleted) != 272 {
+		t.Errorf("Namplas = %+v", so.Namplas)
+	}
+	if len(so.Ships) != 2 || so.Ships[0].ID != "" || len(so.Ships[0].Deleted) != 172 || so.Ships[1].ID != ids.Ship(1, "Pioneer") {
+		t.Errorf("Ships = %+v", so.Ships)
+	}
+
+	// the IDs are those of the entities
+	entities, err := f.Entities()
+	if err != nil {
+		t.Fatalf("Entities() error = %v", err)
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		t.Fatalf("NewState() error = %v", err)
+	}
+	for _, id := range []world.ID{order.Stars[0], order.Stars[1], so.Namplas[0].ID, so.Ships[1].ID} {
+		if _, ok := state.GetEntity(id); !ok {
+			t.Errorf("no entity %s", id)
+		}
+	}
+}
+
+func TestEntitiesErrors(t *testing.T) {
+	f := testFiles()
+	f.Stars[1].NumPlanets = 2
+	if _, err := f.Entities(); err == nil {
+		t.Errorf("Entities() with planets past the end expected error")
+	}
+
+	f = testFiles()
+	f.Species[1].Namplas[0].PN = 3
+	if _, err := f.Entities(); err == nil {
+		t.Errorf("Entities() with a colony on a missing planet expected error")
+	}
+
+	f = testFiles()
+	f.Locations = append(f.Locations, LocationData{S: 1, X: 4, Y: 5, Z: 6})
+	f.Species[1].Ships[0].X = 9
+	if w := f.CheckLocations(); len(w) != 2 {
+		t.Errorf("CheckLocations() = %v", w)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	f := testFiles()
+	want, got := t.TempDir(), t.TempDir()
+	writeFiles(t, want, f)
+	if err := f.Write(got); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, name := range []string{"galaxy.dat", "stars.dat", "planets.dat", "sp01.dat", "locations.dat"} {
+		a, err := os.ReadFile(filepath.Join(want, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(got, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(a) != string(b) {
+			t.Errorf("%s differs from the C layout", name)
+		}
+	}
+
+	f.Species[1].Species.NumShips = 2
+	if err := f.Write(got); err == nil {
+		t.Errorf("Write() with a wrong ship count expected error")
+	}
+}
+
+func TestFromState(t *testing.T) {
+	f := testFiles()
+	entities, err := f.Entities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromState(state, nil)
+	if err != nil {
+		t.Fatalf("FromState() error = %v", err)
+	}
+
+	// without an order, the deleted nampla is dropped
+	want := testFiles()
+	sp := want.Species[1]
+	sp.Namplas = sp.Namplas[:1]
+	sp.Species.NumNamplas = 1
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromState() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// and converting back gives the same entities
+	again, err := got.Entities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, entities) {
+		t.Errorf("Entities() after FromState() differ")
+	}
+
+	// with the order, it is written back
+	order, err := f.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = FromState(state, order)
+	if err != nil {
+		t.Fatalf("FromState() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, testFiles()) {
+		t.Errorf("FromState() with order =\n%+v\nwant\n%+v", got, testFiles())
+	}
+
+	// a ship that is gone leaves a deleted record, and a new one follows
+	o := state.Mutate()
+	o.Delete(ids.Ship(1, "Pioneer"))
+	o.Upsert(&world.Ship{EntityID: ids.Ship(1, "Scout"), Name: "Scout", Species: 1, Location: world.Location{Coords: world.Coords{X: 1, Y: 2, Z: 3}}})
+	got, err = FromState(o.Commit(), order)
+	if err != nil {
+		t.Fatalf("FromState() error = %v", err)
+	}
+	if ships := got.Species[1].Ships; len(ships) != 2 || ships[0].PN != DeletedPN || cstring(ships[1].Name[:]) != "Scout" || got.Species[1].Species.NumShips != 2 {
+		t.Errorf("ships = %+v", ships)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// stars that are not in ID order, a deleted nampla ahead of a live one
+	// that a ship loads at, and a deleted ship ahead of ships that are not
+	// in ID order
+	f := testFiles()
+	f.Stars[0], f.Stars[1] = f.Stars[1], f.Stars[0]
+	f.Stars[0].PlanetIndex, f.Stars[1].PlanetIndex = 0, 1
+	f.Planets = []PlanetData{f.Planets[2], f.Planets[0], f.Planets[1]}
+	sp := f.Species[1]
+	sp.Namplas[0].PlanetIndex = 1
+	outpost := NamplaData{X: 4, Y: 5, Z: 6, PN: 1, PlanetIndex: 0, MIBase: 10, MABase: 10}
+	copy(outpost.Name[:], "Outpost")
+	sp.Namplas = append(sp.Namplas, outpost)
+	sp.Species.NumNamplas = 3
+	pioneer := sp.Ships[0]
+	pioneer.LoadingPoint, pioneer.UnloadingPoint = 2, 0
+	zephyr := sp.Ships[0]
+	zephyr.Name = [32]byte{}
+	copy(zephyr.Name[:], "Zephyr")
+	gone := ShipData{X: 4, Y: 5, Z: 6, PN: DeletedPN, Tonnage: 3}
+	copy(gone.Name[:], "Lost")
+	sp.Ships = []ShipData{gone, zephyr, pioneer}
+	sp.Species.NumShips = 3
+
+	want, got := t.TempDir(), t.TempDir()
+	writeFiles(t, want, f)
+	read, err := Read(want)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	entities, err := read.Entities()
+	if err != nil {
+		t.Fatalf("Entities() error = %v", err)
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		t.Fatalf("NewState() error = %v", err)
+	}
+	order, err := read.Order()
+	if err != nil {
+		t.Fatalf("Order() error = %v", err)
+	}
+	// the order is kept with the game as JSON
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order = nil
+	if err := json.Unmarshal(data, &order); err != nil {
+		t.Fatal(err)
+	}
+	files, err := FromState(state, order)
+	if err != nil {
+		t.Fatalf("FromState() error = %v", err)
+	}
+	if err := files.Write(got); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, name := range []string{"galaxy.dat", "stars.dat", "planets.dat", "sp01.dat", "locations.dat"} {
+		a, err := os.ReadFile(filepath.Join(want, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(got, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(a) != string(b) {
+			t.Errorf("%s differs after a round trip", name)
+		}
+	}
+}
+
+func TestFromStateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(o *world.Overlay)
+	}{
+		{"diameter", func(o *world.Overlay) {
+			e, _ := o.Edit(ids.Planet(1, 2, 3, 2))
+			e.(*world.Planet).Diameter = 40000
+		}},
+		{"name", func(o *world.Overlay) {
+			e, _ := o.Edit(ids.Species(1))
+			e.(*world.Species).Name = strings.Repeat("x", 32)
+		}},
+		{"gases", func(o *world.Overlay) {
+			e, _ := o.Edit(ids.Species(1))
+			sp := e.(*world.Species)
+			sp.PoisonGases = append(sp.PoisonGases, world.O2)
+		}},
+		{"missing species", func(o *world.Overlay) {
+			o.Delete(ids.Species(1))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities, err := testFiles().Entities()
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, err := world.NewState(entities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := state.Mutate()
+			tt.edit(o)
+			if _, err := FromState(o.Commit(), nil); err == nil {
+				t.Errorf("FromState() expected error")
+			}
+		})
+	}
+}
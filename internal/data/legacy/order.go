@@ -0,0 +1,85 @@
+package legacy
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Order is the order of the records in the data files of an imported game.
+// The world has no place for it, so the importer keeps it with the game and
+// gives it back to FromState, which writes the records in the same places.
+type Order struct {
+	Stars   []world.ID            `json:"stars"`
+	Species map[int]*SpeciesOrder `json:"species"`
+}
+
+// SpeciesOrder is the order of the records in a species file. Ships refer
+// to namplas by their index in the file, so deleted records are kept to
+// hold the place of the records after them.
+type SpeciesOrder struct {
+	Namplas []Record `json:"namplas"`
+	Ships   []Record `json:"ships"`
+}
+
+// Record is a record in a species file: the ID of a colony or ship, or a
+// deleted record as it was read.
+type Record struct {
+	ID      world.ID `json:"id,omitempty"`
+	Deleted []byte   `json:"deleted,omitempty"`
+}
+
+// Order returns the order of the records in the files. The IDs are those
+// given by Entities.
+func (f *Files) Order() (*Order, error) {
+	o := &Order{Species: map[int]*SpeciesOrder{}}
+	for _, sd := range f.Stars {
+		o.Stars = append(o.Stars, ids.Star(int(sd.X), int(sd.Y), int(sd.Z)))
+	}
+	for sp, sf := range f.Species {
+		so := &SpeciesOrder{}
+		for i := range sf.Namplas {
+			nd := &sf.Namplas[i]
+			if nd.PN == DeletedPN {
+				data, err := pack(nd)
+				if err != nil {
+					return nil, err
+				}
+				so.Namplas = append(so.Namplas, Record{Deleted: data})
+				continue
+			}
+			planetID := ids.Planet(int(nd.X), int(nd.Y), int(nd.Z), int(nd.PN))
+			so.Namplas = append(so.Namplas, Record{ID: ids.Colony(sp, planetID)})
+		}
+		for i := range sf.Ships {
+			sd := &sf.Ships[i]
+			if sd.PN == DeletedPN {
+				data, err := pack(sd)
+				if err != nil {
+					return nil, err
+				}
+				so.Ships = append(so.Ships, Record{Deleted: data})
+				continue
+			}
+			so.Ships = append(so.Ships, Record{ID: ids.Ship(sp, cstring(sd.Name[:]))})
+		}
+		o.Species[sp] = so
+	}
+	return o, nil
+}
+
+// pack returns a record in the layout of the C engine.
+func pack(v any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// unpack reads a record packed by pack.
+func unpack(data []byte, v any) error {
+	return binary.Read(bytes.NewReader(data), binary.LittleEndian, v)
+}
@@ -0,0 +1,116 @@
+package legacy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Read reads the data files in the directory. The species files are read
+// for each species in galaxy.dat. locations.dat is optional.
+func Read(dir string) (*Files, error) {
+	f := &Files{Species: map[int]*SpeciesFile{}}
+
+	if err := readFile(filepath.Join(dir, "galaxy.dat"), func(r io.Reader) error {
+		return binary.Read(r, byteOrder, &f.Galaxy)
+	}); err != nil {
+		return nil, err
+	}
+	if n := f.Galaxy.NumSpecies; n < 0 || n > MaxSpecies || n > f.Galaxy.DNumSpecies {
+		return nil, fmt.Errorf("galaxy.dat: bad number of species %d of %d", n, f.Galaxy.DNumSpecies)
+	}
+
+	if err := readFile(filepath.Join(dir, "stars.dat"), func(r io.Reader) (err error) {
+		f.Stars, err = readCounted[StarData](r)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if err := readFile(filepath.Join(dir, "planets.dat"), func(r io.Reader) (err error) {
+		f.Planets, err = readCounted[PlanetData](r)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	for sp := 1; sp <= int(f.Galaxy.NumSpecies); sp++ {
+		sf := &SpeciesFile{}
+		if err := readFile(filepath.Join(dir, SpeciesFileName(sp)), func(r io.Reader) error {
+			if err := binary.Read(r, byteOrder, &sf.Species); err != nil {
+				return err
+			}
+			var err error
+			if sf.Namplas, err = readN[NamplaData](r, int(sf.Species.NumNamplas)); err != nil {
+				return err
+			}
+			sf.Ships, err = readN[ShipData](r, int(sf.Species.NumShips))
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		f.Species[sp] = sf
+	}
+
+	err := readFile(filepath.Join(dir, "locations.dat"), func(r io.Reader) error {
+		for {
+			var loc LocationData
+			if err := binary.Read(r, byteOrder, &loc); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			f.Locations = append(f.Locations, loc)
+		}
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SpeciesFileName returns the name of the data file for the species.
+func SpeciesFileName(sp int) string {
+	return fmt.Sprintf("sp%02d.dat", sp)
+}
+
+// readFile opens the file and calls read with a buffered reader.
+// Errors are prefixed with the file name.
+func readFile(name string, read func(io.Reader) error) error {
+	fp, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	if err := read(bufio.NewReader(fp)); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("file is too short")
+		}
+		return fmt.Errorf("%s: %w", filepath.Base(name), err)
+	}
+	return nil
+}
+
+// readCounted reads an int32 count and then that many records.
+func readCounted[T any](r io.Reader) ([]T, error) {
+	var n int32
+	if err := binary.Read(r, byteOrder, &n); err != nil {
+		return nil, err
+	}
+	return readN[T](r, int(n))
+}
+
+// readN reads n records.
+func readN[T any](r io.Reader, n int) ([]T, error) {
+	if n < 0 || n > 1<<20 {
+		return nil, fmt.Errorf("bad record count %d", n)
+	}
+	list := make([]T, n)
+	if err := binary.Read(r, byteOrder, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
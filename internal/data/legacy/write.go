@@ -0,0 +1,68 @@
+package legacy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Write writes the data files to the directory, replacing existing files.
+// The species files are written for each species in the galaxy.
+func (f *Files) Write(dir string) error {
+	if err := writeFile(filepath.Join(dir, "galaxy.dat"), f.Galaxy); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, "stars.dat"), int32(len(f.Stars)), f.Stars); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, "planets.dat"), int32(len(f.Planets)), f.Planets); err != nil {
+		return err
+	}
+	for sp := 1; sp <= int(f.Galaxy.NumSpecies); sp++ {
+		sf, ok := f.Species[sp]
+		if !ok {
+			return fmt.Errorf("%s: missing species %d", SpeciesFileName(sp), sp)
+		}
+		if int(sf.Species.NumNamplas) != len(sf.Namplas) || int(sf.Species.NumShips) != len(sf.Ships) {
+			return fmt.Errorf("%s: species has %d namplas and %d ships, found %d and %d", SpeciesFileName(sp),
+				sf.Species.NumNamplas, sf.Species.NumShips, len(sf.Namplas), len(sf.Ships))
+		}
+		if err := writeFile(filepath.Join(dir, SpeciesFileName(sp)), sf.Species, sf.Namplas, sf.Ships); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(dir, "locations.dat"), f.Locations)
+}
+
+// writeFile creates the file and writes the values in the C layout.
+// Errors are prefixed with the file name.
+func writeFile(name string, values ...any) error {
+	fp, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fp)
+	err = writeValues(w, values)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(name), err)
+	}
+	return nil
+}
+
+func writeValues(w io.Writer, values []any) error {
+	for _, v := range values {
+		if err := binary.Write(w, byteOrder, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package snapshot writes and reads world snapshots as directories of JSON
+// files, so they can be attached to bug reports and imported into another
+// store.
+//
+// A snapshot directory holds one file per entity kind, with the species in
+// a directory of their own, and a manifest that describes the files:
+//
+//	manifest.json
+//	galaxy.json
+//	stars.json
+//	planets.json
+//	colonies.json
+//	ships.json
+//	home_systems.json
+//	species/SP01.json ...
+//
+// Kind files hold a JSON array of entities in ID order; species files hold
+// a single entity. Entities are encoded as they are in the store.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// SchemaVersion is the version of the snapshot format.
+// It changes when the layout of the directory or the encoding of an entity
+// changes in a way that older versions cannot read.
+const SchemaVersion = 1
+
+// ManifestName is the name of the manifest in a snapshot directory.
+const ManifestName = "manifest.json"
+
+// Manifest describes a snapshot directory.
+type Manifest struct {
+	SchemaVersion int    `json:"schema_version"`
+	EngineVersion string `json:"engine_version"` // version of fh that wrote the snapshot
+	Game          string `json:"game"`
+	Turn          int    `json:"turn"`
+	// ContentHash is the SHA-256 of the file list, see Hash.
+	ContentHash string `json:"content_hash"`
+	Files       []File `json:"files"` // sorted by name
+}
+
+// File is an entity file in a snapshot directory.
+type File struct {
+	Name     string `json:"name"` // relative to the directory, with forward slashes
+	Kind     string `json:"kind"`
+	Entities int    `json:"entities"`
+	SHA256   string `json:"sha256"` // of the file contents, in hex
+}
+
+// Hash returns the content hash of the files: the SHA-256, in hex, of one
+// line per file in the format of sha256sum, in name order.
+func Hash(files []File) string {
+	sorted := append([]File(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	h := sha256.New()
+	for _, f := range sorted {
+		fmt.Fprintf(h, "%s  %s\n", f.SHA256, f.Name)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// fileName returns the file that holds the entity.
+func fileName(e world.Entity) string {
+	switch e.Kind() {
+	case world.KindGalaxy:
+		return "galaxy.json"
+	case world.KindStar:
+		return "stars.json"
+	case world.KindPlanet:
+		return "planets.json"
+	case world.KindColony:
+		return "colonies.json"
+	case world.KindShip:
+		return "ships.json"
+	case world.KindSpecies:
+		return path.Join("species", string(e.ID())+".json")
+	case world.KindHomeSystem:
+		return "home_systems.json"
+	}
+	return e.Kind() + ".json"
+}
+
+// Write writes the entities and the manifest to the directory, creating it
+// if needed. It fills in the schema version, the files and the content hash
+// of the manifest; the caller sets the engine version, game and turn.
+// Files from an earlier snapshot in the directory are not removed, but only
+// the files in the manifest are part of the snapshot.
+func Write(dir string, m *Manifest, entities []world.Entity) error {
+	// group the entities by file, keeping them in ID order
+	sorted := make([]struct {
+		key ids.Key
+		e   world.Entity
+	}, len(entities))
+	for i, e := range entities {
+		sorted[i].key, sorted[i].e = ids.KeyOf(e.ID()), e
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].key.Compare(sorted[j].key) < 0 })
+	groups := map[string][]json.RawMessage{}
+	kinds := map[string]string{}
+	for _, item := range sorted {
+		e := item.e
+		se, err := world.Encode(e)
+		if err != nil {
+			return err
+		}
+		name := fileName(e)
+		groups[name] = append(groups[name], se.Data)
+		kinds[name] = e.Kind()
+	}
+
+	m.SchemaVersion = SchemaVersion
+	m.Files = nil
+	for name, list := range groups {
+		var data []byte
+		var err error
+		if kinds[name] == world.KindSpecies {
+			data, err = json.MarshalIndent(list[0], "", "  ")
+		} else {
+			data, err = json.MarshalIndent(list, "", "  ")
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		data = append(data, '\n')
+		if err := writeFile(dir, name, data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		m.Files = append(m.Files, File{Name: name, Kind: kinds[name], Entities: len(list), SHA256: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+	m.ContentHash = Hash(m.Files)
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(dir, ManifestName, append(data, '\n'))
+}
+
+// writeFile writes a file in the snapshot directory, creating the
+// directories in its name.
+func writeFile(dir, name string, data []byte) error {
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("%s: invalid file name", name)
+	}
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// Read reads a snapshot directory written by Write. It checks the schema
+// version, the hash of every file and the content hash, and decodes the
+// entities with the world codecs.
+func Read(dir string) (*Manifest, []world.Entity, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", ManifestName, err)
+	}
+	if m.SchemaVersion < 1 || m.SchemaVersion > SchemaVersion {
+		return nil, nil, fmt.Errorf("%s: unsupported schema version %d (want %d)", ManifestName, m.SchemaVersion, SchemaVersion)
+	}
+	if hash := Hash(m.Files); hash != m.ContentHash {
+		return nil, nil, fmt.Errorf("%s: content hash is %s, want %s", ManifestName, hash, m.ContentHash)
+	}
+
+	var entities []world.Entity
+	for _, f := range m.Files {
+		list, err := readFile(dir, f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		entities = append(entities, list...)
+	}
+	return m, entities, nil
+}
+
+// readFile reads and decodes an entity file, checking its hash and the
+// number of entities.
+func readFile(dir string, f File) ([]world.Entity, error) {
+	if f.Name == "" || path.IsAbs(f.Name) || strings.Contains(f.Name, "..") || strings.Contains(f.Name, `\`) {
+		return nil, fmt.Errorf("invalid file name")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Name)))
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != f.SHA256 {
+		return nil, fmt.Errorf("sha256 is %x, want %s", sum, f.SHA256)
+	}
+
+	var list []json.RawMessage
+	if f.Kind == world.KindSpecies {
+		list = []json.RawMessage{data}
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if len(list) != f.Entities {
+		return nil, fmt.Errorf("has %d entities, want %d", len(list), f.Entities)
+	}
+	entities := make([]world.Entity, 0, len(list))
+	for _, raw := range list {
+		var id struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return nil, err
+		}
+		e, err := world.Decode(store.Entity{ID: id.ID, Kind: f.Kind, Data: raw})
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, e)
+	}
+	return entities, nil
+}
@@ -0,0 +1,191 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+func testEntities() []world.Entity {
+	return []world.Entity{
+		&world.Galaxy{EntityID: ids.Galaxy(), DNumSpecies: 2, NumSpecies: 2, Radius: 10},
+		&world.Star{EntityID: ids.Star(10, 2, 3), Coords: world.Coords{X: 10, Y: 2, Z: 3}, Planets: []world.ID{ids.Planet(10, 2, 3, 1)}},
+		&world.Star{EntityID: ids.Star(9, 2, 3), Coords: world.Coords{X: 9, Y: 2, Z: 3}},
+		&world.Planet{EntityID: ids.Planet(10, 2, 3, 1), StarID: ids.Star(10, 2, 3), Location: world.Location{Coords: world.Coords{X: 10, Y: 2, Z: 3}, Orbit: 1}},
+		&world.Species{EntityID: ids.Species(1), Number: 1, Name: "Humans"},
+		&world.Species{EntityID: ids.Species(2), Number: 2, Name: "Zorgons"},
+		&world.Ship{EntityID: ids.Ship(1, "Pioneer"), Name: "Pioneer", Species: 1},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manifest{EngineVersion: "0.15.0", Game: "alpha", Turn: 3}
+	if err := Write(dir, m, testEntities()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := []File{
+		{Name: "galaxy.json", Kind: world.KindGalaxy, Entities: 1},
+		{Name: "planets.json", Kind: world.KindPlanet, Entities: 1},
+		{Name: "ships.json", Kind: world.KindShip, Entities: 1},
+		{Name: "species/SP01.json", Kind: world.KindSpecies, Entities: 1},
+		{Name: "species/SP02.json", Kind: world.KindSpecies, Entities: 1},
+		{Name: "stars.json", Kind: world.KindStar, Entities: 2},
+	}
+	if len(m.Files) != len(want) {
+		t.Fatalf("Files = %+v", m.Files)
+	}
+	for i, f := range m.Files {
+		if f.Name != want[i].Name || f.Kind != want[i].Kind || f.Entities != want[i].Entities || len(f.SHA256) != 64 {
+			t.Errorf("Files[%d] = %+v, want %+v", i, f, want[i])
+		}
+	}
+	if m.SchemaVersion != SchemaVersion || m.ContentHash != Hash(m.Files) {
+		t.Errorf("manifest = %+v", m)
+	}
+
+	// the manifest on disk matches
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Game != "alpha" || got.Turn != 3 || got.EngineVersion != "0.15.0" || got.ContentHash != m.ContentHash {
+		t.Errorf("manifest.json = %+v", got)
+	}
+
+	// stars are in ID order and species files hold a single entity
+	var stars []world.Star
+	data, err = os.ReadFile(filepath.Join(dir, "stars.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &stars); err != nil || len(stars) != 2 || stars[0].X != 9 {
+		t.Errorf("stars.json = %s (%v)", data, err)
+	}
+	var species world.Species
+	data, err = os.ReadFile(filepath.Join(dir, "species", "SP02.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &species); err != nil || species.Name != "Zorgons" {
+		t.Errorf("SP02.json = %s (%v)", data, err)
+	}
+
+	// the same entities in any order give the same hash
+	entities := testEntities()
+	entities[0], entities[6] = entities[6], entities[0]
+	again := &Manifest{}
+	if err := Write(t.TempDir(), again, entities); err != nil {
+		t.Fatal(err)
+	}
+	if again.ContentHash != m.ContentHash {
+		t.Errorf("content hash changed with the order of the entities")
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := []File{{Name: "a.json", SHA256: "00"}, {Name: "b.json", SHA256: "11"}}
+	b := []File{a[1], a[0]}
+	if Hash(a) != Hash(b) {
+		t.Errorf("Hash() depends on the order of the files")
+	}
+	b[0].SHA256 = "12"
+	if Hash(a) == Hash(b) {
+		t.Errorf("Hash() did not change with the contents")
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manifest{EngineVersion: "0.15.0", Game: "alpha", Turn: 3}
+	if err := Write(dir, m, testEntities()); err != nil {
+		t.Fatal(err)
+	}
+	got, entities, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.Game != "alpha" || got.Turn != 3 || got.ContentHash != m.ContentHash {
+		t.Errorf("Read() manifest = %+v", got)
+	}
+	if !reflect.DeepEqual(byID(entities), byID(testEntities())) {
+		t.Errorf("Read() entities differ from those written")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(t *testing.T, dir string, m *Manifest)
+	}{
+		{"schema version", func(t *testing.T, dir string, m *Manifest) {
+			m.SchemaVersion = SchemaVersion + 1
+			writeManifest(t, dir, m)
+		}},
+		{"content hash", func(t *testing.T, dir string, m *Manifest) {
+			m.Files = m.Files[1:]
+			writeManifest(t, dir, m)
+		}},
+		{"file changed", func(t *testing.T, dir string, m *Manifest) {
+			if err := os.WriteFile(filepath.Join(dir, "ships.json"), []byte("[]\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"file missing", func(t *testing.T, dir string, m *Manifest) {
+			if err := os.Remove(filepath.Join(dir, "species", "SP01.json")); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"file outside the directory", func(t *testing.T, dir string, m *Manifest) {
+			m.Files[0].Name = "../galaxy.json"
+			m.ContentHash = Hash(m.Files)
+			writeManifest(t, dir, m)
+		}},
+		{"wrong kind", func(t *testing.T, dir string, m *Manifest) {
+			m.Files[0].Kind = world.KindStar
+			writeManifest(t, dir, m)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := &Manifest{Game: "alpha"}
+			if err := Write(dir, m, testEntities()); err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(t, dir, m)
+			if _, _, err := Read(dir); err == nil {
+				t.Errorf("Read() expected error")
+			}
+		})
+	}
+}
+
+func writeManifest(t *testing.T, dir string, m *Manifest) {
+	t.Helper()
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func byID(entities []world.Entity) map[world.ID]world.Entity {
+	m := map[world.ID]world.Entity{}
+	for _, e := range entities {
+		m[e.ID()] = e
+	}
+	return m
+}
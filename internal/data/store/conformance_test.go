@@ -0,0 +1,35 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/data/store/storetest"
+)
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), false)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		return st
+	})
+}
+
+func TestJSONStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		st, err := store.NewJSONStore(filepath.Join(t.TempDir(), "game"), false)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		return st
+	})
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemoryStore()
+	})
+}
@@ -0,0 +1,791 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playbymail/fh/internal/cerrs"
+)
+
+// jsonSchemaVersion is the layout version written to schema.json.
+const jsonSchemaVersion = "0001_initial"
+
+// JSONStore implements Store as a directory tree of pretty-printed JSON files.
+//
+// The layout is
+//
+//	<root>/schema.json
+//	<root>/games/<game>/game.json
+//	<root>/games/<game>/turns/<turn>/turn.json
+//	<root>/games/<game>/turns/<turn>/snapshot.json
+//	<root>/games/<game>/turns/<turn>/orders/<actor>.json
+//	<root>/games/<game>/turns/<turn>/rng_state.json
+//	<root>/games/<game>/turns/<turn>/rng_trace/<phase>
+//	<root>/games/<game>/turns/<turn>/reports/<actor>/<mime>
+//
+// Path components are escaped with url.PathEscape, and names that would
+// still refer to another directory ("", "." and "..") are rejected. Every
+// file is written to a temporary file and renamed into place, so a crash
+// never leaves a partial file.
+type JSONStore struct {
+	root string
+	mu   sync.RWMutex
+}
+
+// OpenJSONStore opens an existing JSON store.
+func OpenJSONStore(root string) (*JSONStore, error) {
+	sb, err := os.Stat(root)
+	if os.IsNotExist(err) {
+		return nil, cerrs.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !sb.IsDir() {
+		return nil, errors.Join(cerrs.ErrNotOpened, fmt.Errorf("%s: not a directory", root))
+	}
+
+	store := &JSONStore{root: root}
+
+	// Check and upgrade schema if needed
+	version, err := store.GetSchemaVersion(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if version != jsonSchemaVersion {
+		if err := store.UpgradeSchema(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// NewJSONStore creates a new JSON store.
+func NewJSONStore(root string, force bool) (*JSONStore, error) {
+	_, err := os.Stat(root)
+	exists := !os.IsNotExist(err)
+	if exists {
+		if !force {
+			return nil, cerrs.ErrExists
+		}
+		if err := os.RemoveAll(root); err != nil {
+			return nil, errors.Join(cerrs.ErrExists, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "games"), 0o755); err != nil {
+		return nil, errors.Join(cerrs.ErrNotOpened, err)
+	}
+
+	store := &JSONStore{root: root}
+	if err := store.writeJSON(store.schemaPath(), jsonSchema{Version: jsonSchemaVersion}); err != nil {
+		return nil, errors.Join(cerrs.ErrSchemaSetupFailed, err)
+	}
+
+	return store, nil
+}
+
+// jsonSchema is the on-disk format of schema.json.
+type jsonSchema struct {
+	Version string `json:"version"`
+}
+
+// jsonGame is the on-disk format of game.json.
+type jsonGame struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	CreatedAt string            `json:"created_at"`
+	Meta      map[string]string `json:"meta,omitempty"`
+}
+
+// jsonTurn is the on-disk format of turn.json.
+type jsonTurn struct {
+	GameID    string `json:"game_id"`
+	Num       int    `json:"num"`
+	Phase     string `json:"phase"`
+	StartedAt string `json:"started_at"`
+	EndedAt   string `json:"ended_at"`
+}
+
+// jsonEntity is the on-disk format of an entity in snapshot.json.
+// Data that is compact JSON is stored inline so that it can be read and
+// diffed; anything else is stored base64-encoded in Blob.
+type jsonEntity struct {
+	ID   string          `json:"id"`
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data,omitempty"`
+	Blob []byte          `json:"blob,omitempty"`
+}
+
+// jsonOrder is the on-disk format of an order in <actor>.json.
+type jsonOrder struct {
+	Seq        int    `json:"seq"`
+	Raw        string `json:"raw"`
+	Normalized string `json:"normalized"`
+	Status     string `json:"status"`
+	Error      string `json:"error"`
+}
+
+// CreateGame creates a new game.
+func (s *JSONStore) CreateGame(ctx context.Context, id, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.gamePath(id)); err == nil {
+		return fmt.Errorf("game %q: %w", id, cerrs.ErrExists)
+	}
+	if err := os.MkdirAll(s.gameDir(id), 0o755); err != nil {
+		return err
+	}
+	return s.writeJSON(s.gamePath(id), jsonGame{ID: id, Name: name, CreatedAt: now()})
+}
+
+// GetGame retrieves game metadata.
+func (s *JSONStore) GetGame(ctx context.Context, id string) (*Game, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var game jsonGame
+	if err := s.readJSON(s.gamePath(id), &game); err != nil {
+		if errors.Is(err, cerrs.ErrNotExist) {
+			return nil, &cerrs.ErrGameNotFound{GameID: id}
+		}
+		return nil, err
+	}
+	return &Game{ID: game.ID, Name: game.Name, CreatedAt: game.CreatedAt}, nil
+}
+
+// SetGameMeta sets a game metadata value, replacing any existing value.
+// The metadata is kept in game.json.
+func (s *JSONStore) SetGameMeta(ctx context.Context, gameID, key, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var game jsonGame
+	if err := s.readJSON(s.gamePath(gameID), &game); err != nil {
+		if errors.Is(err, cerrs.ErrNotExist) {
+			return &cerrs.ErrGameNotFound{GameID: gameID}
+		}
+		return err
+	}
+	if game.Meta == nil {
+		game.Meta = map[string]string{}
+	}
+	game.Meta[key] = value
+	return s.writeJSON(s.gamePath(gameID), game)
+}
+
+// GetGameMeta retrieves the game metadata.
+func (s *JSONStore) GetGameMeta(ctx context.Context, gameID string) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var game jsonGame
+	if err := s.readJSON(s.gamePath(gameID), &game); err != nil {
+		if errors.Is(err, cerrs.ErrNotExist) {
+			return nil, &cerrs.ErrGameNotFound{GameID: gameID}
+		}
+		return nil, err
+	}
+	if game.Meta == nil {
+		game.Meta = map[string]string{}
+	}
+	return game.Meta, nil
+}
+
+// CreateTurn creates a new turn.
+func (s *JSONStore) CreateTurn(ctx context.Context, gameID string, turnNum int, phase string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkGame(gameID); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.turnPath(gameID, turnNum)); err == nil {
+		return fmt.Errorf("game %q: turn %d: %w", gameID, turnNum, cerrs.ErrExists)
+	}
+	if err := os.MkdirAll(s.turnDir(gameID, turnNum), 0o755); err != nil {
+		return err
+	}
+	return s.writeJSON(s.turnPath(gameID, turnNum), jsonTurn{GameID: gameID, Num: turnNum, Phase: phase, StartedAt: now()})
+}
+
+// DeleteTurn deletes the directory of a turn. turn.json is removed first,
+// so an interrupted delete leaves a directory that GetCurrentTurn skips.
+func (s *JSONStore) DeleteTurn(ctx context.Context, gameID string, turnNum int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return err
+	}
+	if err := os.Remove(s.turnPath(gameID, turnNum)); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.turnDir(gameID, turnNum))
+}
+
+// GetCurrentTurn finds the latest turn. A turn directory without turn.json
+// is skipped; CreateTurn can leave one behind if it is interrupted.
+func (s *JSONStore) GetCurrentTurn(ctx context.Context, gameID string) (*Turn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkGame(gameID); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.gameDir(gameID), "turns"))
+	if os.IsNotExist(err) {
+		return nil, &cerrs.ErrTurnNotFound{GameID: gameID, Turn: -1}
+	} else if err != nil {
+		return nil, err
+	}
+
+	latest, found := 0, false
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		num, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(s.turnPath(gameID, num)); err != nil {
+			continue
+		}
+		if !found || num > latest {
+			latest, found = num, true
+		}
+	}
+	if !found {
+		return nil, &cerrs.ErrTurnNotFound{GameID: gameID, Turn: -1}
+	}
+
+	var turn jsonTurn
+	if err := s.readJSON(s.turnPath(gameID, latest), &turn); err != nil {
+		return nil, err
+	}
+	return &Turn{GameID: turn.GameID, Num: turn.Num, Phase: turn.Phase, StartedAt: turn.StartedAt, EndedAt: turn.EndedAt}, nil
+}
+
+// SaveSnapshot saves entities, replacing any existing snapshot for the turn.
+func (s *JSONStore) SaveSnapshot(ctx context.Context, gameID string, turnNum int, entities []Entity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return err
+	}
+
+	records := make([]jsonEntity, 0, len(entities))
+	for _, entity := range entities {
+		record := jsonEntity{ID: entity.ID, Kind: entity.Kind}
+		if isCompactJSON(entity.Data) {
+			record.Data = json.RawMessage(entity.Data)
+		} else {
+			record.Blob = entity.Data
+		}
+		records = append(records, record)
+	}
+	return s.writeJSON(s.snapshotPath(gameID, turnNum), records)
+}
+
+// LoadSnapshot loads entities.
+func (s *JSONStore) LoadSnapshot(ctx context.Context, gameID string, turnNum int) ([]Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return nil, err
+	}
+
+	var records []jsonEntity
+	if err := s.readJSON(s.snapshotPath(gameID, turnNum), &records); err != nil {
+		if errors.Is(err, cerrs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entities []Entity
+	for _, record := range records {
+		entity := Entity{ID: record.ID, Kind: record.Kind}
+		if record.Data != nil {
+			entity.Data = compactJSON(record.Data)
+		} else {
+			entity.Data = record.Blob
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// SaveOrders saves orders, replacing any existing orders for the actor.
+func (s *JSONStore) SaveOrders(ctx context.Context, gameID string, turnNum int, actor string, orders []Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID, actor); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return err
+	}
+
+	records := make([]jsonOrder, 0, len(orders))
+	for _, order := range orders {
+		records = append(records, jsonOrder(order))
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+	if err := os.MkdirAll(filepath.Join(s.turnDir(gameID, turnNum), "orders"), 0o755); err != nil {
+		return err
+	}
+	return s.writeJSON(s.ordersPath(gameID, turnNum, actor), records)
+}
+
+// GetOrders retrieves orders.
+func (s *JSONStore) GetOrders(ctx context.Context, gameID string, turnNum int, actor string) ([]Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID, actor); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return nil, err
+	}
+
+	var records []jsonOrder
+	if err := s.readJSON(s.ordersPath(gameID, turnNum, actor), &records); err != nil {
+		if errors.Is(err, cerrs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var orders []Order
+	for _, record := range records {
+		orders = append(orders, Order(record))
+	}
+	return orders, nil
+}
+
+// SaveRNGState saves an RNG checkpoint, replacing any existing checkpoint for the turn.
+// The states are base64 encoded.
+func (s *JSONStore) SaveRNGState(ctx context.Context, gameID string, turnNum int, states map[string][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return err
+	}
+	if states == nil {
+		states = map[string][]byte{}
+	}
+	return s.writeJSON(s.rngStatePath(gameID, turnNum), states)
+}
+
+// LoadRNGState loads the RNG checkpoint for the turn.
+func (s *JSONStore) LoadRNGState(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return nil, err
+	}
+	states := map[string][]byte{}
+	if err := s.readJSON(s.rngStatePath(gameID, turnNum), &states); err != nil && !errors.Is(err, cerrs.ErrNotExist) {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SaveRNGTrace saves the RNG trace for a phase, replacing any existing trace.
+func (s *JSONStore) SaveRNGTrace(ctx context.Context, gameID string, turnNum int, phase string, trace []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID, phase); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return err
+	}
+
+	path := s.rngTracePath(gameID, turnNum, phase)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, trace)
+}
+
+// LoadRNGTraces loads the RNG traces for the turn, by phase.
+func (s *JSONStore) LoadRNGTraces(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return nil, err
+	}
+
+	traces := map[string][]byte{}
+	dir := s.rngTraceDir(gameID, turnNum)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return traces, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue // not a trace, such as a temporary file
+		}
+		phase, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		traces[phase] = data
+	}
+	return traces, nil
+}
+
+// SaveReport saves a report, replacing any existing report for the actor and mime type.
+func (s *JSONStore) SaveReport(ctx context.Context, gameID string, turnNum int, actor string, mime string, body io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID, actor, mime); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTurn(gameID, turnNum); err != nil {
+		return err
+	}
+
+	path := s.reportPath(gameID, turnNum, actor, mime)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// GetReport retrieves a report.
+func (s *JSONStore) GetReport(ctx context.Context, gameID string, turnNum int, actor string, mime string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID, actor, mime); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := os.ReadFile(s.reportPath(gameID, turnNum, actor, mime))
+	if os.IsNotExist(err) {
+		if err := s.checkTurn(gameID, turnNum); err != nil {
+			return nil, err
+		}
+		return nil, &cerrs.ErrReportNotFound{GameID: gameID, Turn: turnNum, Actor: actor, Mime: mime}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(NewByteReader(data)), nil
+}
+
+// GetSchemaVersion returns the current schema version.
+func (s *JSONStore) GetSchemaVersion(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var schema jsonSchema
+	if err := s.readJSON(s.schemaPath(), &schema); err != nil {
+		if errors.Is(err, cerrs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return schema.Version, nil
+}
+
+// UpgradeSchema applies pending schema upgrades.
+// There is only one layout version, so this writes schema.json if it is
+// missing and rejects versions it does not know.
+func (s *JSONStore) UpgradeSchema(ctx context.Context) error {
+	version, err := s.GetSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch version {
+	case jsonSchemaVersion:
+		return nil
+	case "":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := os.MkdirAll(filepath.Join(s.root, "games"), 0o755); err != nil {
+			return errors.Join(cerrs.ErrSchemaUpgradeFailed, err)
+		}
+		if err := s.writeJSON(s.schemaPath(), jsonSchema{Version: jsonSchemaVersion}); err != nil {
+			return errors.Join(cerrs.ErrSchemaUpgradeFailed, err)
+		}
+		return nil
+	}
+	return cerrs.ErrSchemaTooNew
+}
+
+// Close is a no-op; every write is flushed before it returns.
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+// checkGame returns cerrs.ErrGameNotFound if the game does not exist.
+func (s *JSONStore) checkGame(gameID string) error {
+	if _, err := os.Stat(s.gamePath(gameID)); os.IsNotExist(err) {
+		return &cerrs.ErrGameNotFound{GameID: gameID}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkTurn returns cerrs.ErrGameNotFound or cerrs.ErrTurnNotFound if the turn does not exist.
+func (s *JSONStore) checkTurn(gameID string, turnNum int) error {
+	if _, err := os.Stat(s.turnPath(gameID, turnNum)); os.IsNotExist(err) {
+		if err := s.checkGame(gameID); err != nil {
+			return err
+		}
+		return &cerrs.ErrTurnNotFound{GameID: gameID, Turn: turnNum}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *JSONStore) schemaPath() string {
+	return filepath.Join(s.root, "schema.json")
+}
+
+func (s *JSONStore) gameDir(gameID string) string {
+	return filepath.Join(s.root, "games", url.PathEscape(gameID))
+}
+
+func (s *JSONStore) gamePath(gameID string) string {
+	return filepath.Join(s.gameDir(gameID), "game.json")
+}
+
+func (s *JSONStore) turnDir(gameID string, turnNum int) string {
+	return filepath.Join(s.gameDir(gameID), "turns", strconv.Itoa(turnNum))
+}
+
+func (s *JSONStore) turnPath(gameID string, turnNum int) string {
+	return filepath.Join(s.turnDir(gameID, turnNum), "turn.json")
+}
+
+func (s *JSONStore) snapshotPath(gameID string, turnNum int) string {
+	return filepath.Join(s.turnDir(gameID, turnNum), "snapshot.json")
+}
+
+func (s *JSONStore) ordersPath(gameID string, turnNum int, actor string) string {
+	return filepath.Join(s.turnDir(gameID, turnNum), "orders", url.PathEscape(actor)+".json")
+}
+
+func (s *JSONStore) rngStatePath(gameID string, turnNum int) string {
+	return filepath.Join(s.turnDir(gameID, turnNum), "rng_state.json")
+}
+
+func (s *JSONStore) rngTraceDir(gameID string, turnNum int) string {
+	return filepath.Join(s.turnDir(gameID, turnNum), "rng_trace")
+}
+
+func (s *JSONStore) rngTracePath(gameID string, turnNum int, phase string) string {
+	return filepath.Join(s.rngTraceDir(gameID, turnNum), url.PathEscape(phase))
+}
+
+func (s *JSONStore) reportPath(gameID string, turnNum int, actor, mime string) string {
+	return filepath.Join(s.turnDir(gameID, turnNum), "reports", url.PathEscape(actor), url.PathEscape(mime))
+}
+
+// readJSON decodes the file at path into v.
+// It returns cerrs.ErrNotExist if the file does not exist.
+func (s *JSONStore) readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cerrs.ErrNotExist
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSON atomically writes v to path as indented JSON.
+// HTML characters are not escaped, so inline entity data is written as is.
+func (s *JSONStore) writeJSON(path string, v any) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b.Bytes())
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory,
+// syncs it, then renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	fd, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := fd.Name()
+	defer os.Remove(tmpName) // no-op after a successful rename
+
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// isCompactJSON reports whether data is valid JSON with no insignificant whitespace.
+// Only compact JSON can be stored inline and still round-trip byte for byte.
+func isCompactJSON(data []byte) bool {
+	if len(data) == 0 || !json.Valid(data) {
+		return false
+	}
+	return bytes.Equal(compactJSON(data), data)
+}
+
+// compactJSON returns data with insignificant whitespace removed.
+func compactJSON(data []byte) []byte {
+	var b bytes.Buffer
+	if err := json.Compact(&b, data); err != nil {
+		return data
+	}
+	return b.Bytes()
+}
+
+// now returns the current UTC time in the same format as SQLite's datetime('now').
+func now() string {
+	return time.Now().UTC().Format("2006-01-02 15:04:05")
+}
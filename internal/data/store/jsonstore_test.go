@@ -0,0 +1,205 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/cerrs"
+)
+
+var (
+	_ Store         = (*JSONStore)(nil)
+	_ MetaStore     = (*JSONStore)(nil)
+	_ RNGStateStore = (*JSONStore)(nil)
+	_ RNGTraceStore = (*JSONStore)(nil)
+)
+
+func TestJSONStoreSaveLoadSnapshot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "game")
+
+	st, err := NewJSONStore(root, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+
+	if err := st.CreateTurn(ctx, "game1", 1, "production"); err != nil {
+		t.Fatalf("failed to create turn: %v", err)
+	}
+
+	testData := []Entity{
+		{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Earth","population":1000}`)},
+		{ID: "ship-1", Kind: "ship", Data: []byte(`{ "name": "Enterprise", "tonnage": 5000 }`)},
+		{ID: "species-1", Kind: "species", Data: []byte{0x82, 0xa4, 0x6e, 0x61}},
+	}
+
+	if err := st.SaveSnapshot(ctx, "game1", 1, testData); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	loaded, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+
+	if len(loaded) != len(testData) {
+		t.Fatalf("expected %d entities, got %d", len(testData), len(loaded))
+	}
+
+	for i, entity := range loaded {
+		if entity.ID != testData[i].ID {
+			t.Errorf("entity %d: expected ID %q, got %q", i, testData[i].ID, entity.ID)
+		}
+		if entity.Kind != testData[i].Kind {
+			t.Errorf("entity %d: expected Kind %q, got %q", i, testData[i].Kind, entity.Kind)
+		}
+		if !bytes.Equal(entity.Data, testData[i].Data) {
+			t.Errorf("entity %d: expected Data %q, got %q", i, string(testData[i].Data), string(entity.Data))
+		}
+	}
+
+	// compact JSON data must be stored inline so it can be diffed
+	raw, err := os.ReadFile(filepath.Join(root, "games", "game1", "turns", "1", "snapshot.json"))
+	if err != nil {
+		t.Fatalf("failed to read snapshot file: %v", err)
+	}
+	if !strings.Contains(string(raw), `"name": "Earth"`) {
+		t.Errorf("expected inline JSON data in snapshot file, got\n%s", raw)
+	}
+}
+
+func TestJSONStoreTurnsOrdersReports(t *testing.T) {
+	st, err := NewJSONStore(filepath.Join(t.TempDir(), "game"), false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	if err := st.CreateGame(ctx, "game1", "Test Game"); !errors.Is(err, cerrs.ErrExists) {
+		t.Errorf("expected ErrExists for duplicate game, got %v", err)
+	}
+	if err := st.CreateTurn(ctx, "nogame", 1, "production"); err == nil {
+		t.Error("expected error creating turn for missing game")
+	}
+	for _, num := range []int{2, 10, 9} {
+		if err := st.CreateTurn(ctx, "game1", num, "production"); err != nil {
+			t.Fatalf("failed to create turn %d: %v", num, err)
+		}
+	}
+
+	turn, err := st.GetCurrentTurn(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get current turn: %v", err)
+	}
+	if turn.Num != 10 {
+		t.Errorf("expected current turn 10, got %d", turn.Num)
+	}
+
+	// a turn directory left without turn.json by an interrupted CreateTurn is skipped
+	if err := os.MkdirAll(st.turnDir("game1", 11), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if turn, err := st.GetCurrentTurn(ctx, "game1"); err != nil || turn.Num != 10 {
+		t.Errorf("expected current turn 10 with an empty turn directory, got %+v, %v", turn, err)
+	}
+	if err := st.CreateTurn(ctx, "game1", 11, "production"); err != nil {
+		t.Errorf("failed to create turn in an empty turn directory: %v", err)
+	}
+	if err := os.RemoveAll(st.turnDir("game1", 11)); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := []Order{{Seq: 1, Raw: "JUMP TR1 Alpha, 1 2 3", Status: "pending"}, {Seq: 2, Raw: "BUILD 10 IU", Status: "pending"}}
+	if err := st.SaveOrders(ctx, "game1", 10, "SP01", orders); err != nil {
+		t.Fatalf("failed to save orders: %v", err)
+	}
+	if err := st.SaveOrders(ctx, "game1", 10, "SP01", orders[1:]); err != nil {
+		t.Fatalf("failed to replace orders: %v", err)
+	}
+	got, err := st.GetOrders(ctx, "game1", 10, "SP01")
+	if err != nil {
+		t.Fatalf("failed to get orders: %v", err)
+	}
+	if len(got) != 1 || got[0] != orders[1] {
+		t.Errorf("expected replaced orders %+v, got %+v", orders[1:], got)
+	}
+
+	if err := st.SaveReport(ctx, "game1", 10, "SP01", "text/plain", strings.NewReader("first")); err != nil {
+		t.Fatalf("failed to save report: %v", err)
+	}
+	if err := st.SaveReport(ctx, "game1", 10, "SP01", "text/plain", strings.NewReader("second")); err != nil {
+		t.Fatalf("failed to overwrite report: %v", err)
+	}
+	rc, err := st.GetReport(ctx, "game1", 10, "SP01", "text/plain")
+	if err != nil {
+		t.Fatalf("failed to get report: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "second" {
+		t.Errorf("expected report %q, got %q", "second", body)
+	}
+	if _, err := st.GetReport(ctx, "game1", 10, "SP01", "text/html"); !errors.Is(err, cerrs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist for missing report, got %v", err)
+	}
+}
+
+func TestJSONStoreReopen(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "game")
+
+	st, err := NewJSONStore(root, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if err := st.CreateGame(context.Background(), "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	st.Close()
+
+	if _, err := NewJSONStore(root, false); !errors.Is(err, cerrs.ErrExists) {
+		t.Errorf("expected ErrExists without force flag, got %v", err)
+	}
+
+	st2, err := OpenJSONStore(root)
+	if err != nil {
+		t.Fatalf("failed to open existing store: %v", err)
+	}
+	defer st2.Close()
+
+	version, err := st2.GetSchemaVersion(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if version != jsonSchemaVersion {
+		t.Errorf("expected version %q, got %q", jsonSchemaVersion, version)
+	}
+
+	game, err := st2.GetGame(context.Background(), "game1")
+	if err != nil {
+		t.Fatalf("failed to get game: %v", err)
+	}
+	if game.Name != "Test Game" {
+		t.Errorf("expected name %q, got %q", "Test Game", game.Name)
+	}
+
+	if _, err := OpenJSONStore(filepath.Join(root, "missing")); !errors.Is(err, cerrs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist opening missing store, got %v", err)
+	}
+}
@@ -0,0 +1,455 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"sync"
+
+	"github.com/playbymail/fh/internal/cerrs"
+)
+
+// memorySchemaVersion is the schema version reported by MemoryStore.
+const memorySchemaVersion = "0001_initial"
+
+// MemoryStore implements Store with in-memory maps.
+// It never touches disk and is intended for tests and dry runs.
+// Data is copied on the way in and out, so callers may reuse their buffers.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	games map[string]*memoryGame
+}
+
+// memoryGame holds a game and its turns.
+type memoryGame struct {
+	game  Game
+	meta  map[string]string
+	turns map[int]*memoryTurn
+}
+
+// memoryTurn holds a turn and everything saved against it.
+type memoryTurn struct {
+	turn     Turn
+	entities []Entity
+	orders   map[string][]Order
+	reports  map[memoryReportKey][]byte
+	rngState map[string][]byte
+	rngTrace map[string][]byte
+}
+
+// memoryReportKey identifies a report within a turn.
+type memoryReportKey struct {
+	actor string
+	mime  string
+}
+
+// NewMemoryStore creates a new, empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{games: make(map[string]*memoryGame)}
+}
+
+// CreateGame creates a new game.
+func (s *MemoryStore) CreateGame(ctx context.Context, id, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.games[id]; ok {
+		return fmt.Errorf("game %q: %w", id, cerrs.ErrExists)
+	}
+	s.games[id] = &memoryGame{
+		game:  Game{ID: id, Name: name, CreatedAt: now()},
+		meta:  make(map[string]string),
+		turns: make(map[int]*memoryTurn),
+	}
+	return nil
+}
+
+// GetGame retrieves game metadata.
+func (s *MemoryStore) GetGame(ctx context.Context, id string) (*Game, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.games[id]
+	if !ok {
+		return nil, &cerrs.ErrGameNotFound{GameID: id}
+	}
+	game := g.game
+	return &game, nil
+}
+
+// SetGameMeta sets a game metadata value, replacing any existing value.
+func (s *MemoryStore) SetGameMeta(ctx context.Context, gameID, key, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.games[gameID]
+	if !ok {
+		return &cerrs.ErrGameNotFound{GameID: gameID}
+	}
+	g.meta[key] = value
+	return nil
+}
+
+// GetGameMeta retrieves the game metadata.
+func (s *MemoryStore) GetGameMeta(ctx context.Context, gameID string) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.games[gameID]
+	if !ok {
+		return nil, &cerrs.ErrGameNotFound{GameID: gameID}
+	}
+	return maps.Clone(g.meta), nil
+}
+
+// CreateTurn creates a new turn.
+func (s *MemoryStore) CreateTurn(ctx context.Context, gameID string, turnNum int, phase string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.games[gameID]
+	if !ok {
+		return &cerrs.ErrGameNotFound{GameID: gameID}
+	}
+	if _, ok := g.turns[turnNum]; ok {
+		return fmt.Errorf("game %q: turn %d: %w", gameID, turnNum, cerrs.ErrExists)
+	}
+	g.turns[turnNum] = &memoryTurn{
+		turn:     Turn{GameID: gameID, Num: turnNum, Phase: phase, StartedAt: now()},
+		orders:   make(map[string][]Order),
+		reports:  make(map[memoryReportKey][]byte),
+		rngTrace: make(map[string][]byte),
+	}
+	return nil
+}
+
+// DeleteTurn deletes a turn and everything saved with it.
+func (s *MemoryStore) DeleteTurn(ctx context.Context, gameID string, turnNum int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.turn(gameID, turnNum); err != nil {
+		return err
+	}
+	delete(s.games[gameID].turns, turnNum)
+	return nil
+}
+
+// GetCurrentTurn finds the latest turn.
+func (s *MemoryStore) GetCurrentTurn(ctx context.Context, gameID string) (*Turn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.games[gameID]
+	if !ok {
+		return nil, &cerrs.ErrGameNotFound{GameID: gameID}
+	}
+	var latest *memoryTurn
+	for _, t := range g.turns {
+		if latest == nil || t.turn.Num > latest.turn.Num {
+			latest = t
+		}
+	}
+	if latest == nil {
+		return nil, &cerrs.ErrTurnNotFound{GameID: gameID, Turn: -1}
+	}
+	turn := latest.turn
+	return &turn, nil
+}
+
+// SaveSnapshot saves entities, replacing any existing snapshot for the turn.
+func (s *MemoryStore) SaveSnapshot(ctx context.Context, gameID string, turnNum int, entities []Entity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	t.entities = copyEntities(entities)
+	return nil
+}
+
+// LoadSnapshot loads entities.
+func (s *MemoryStore) LoadSnapshot(ctx context.Context, gameID string, turnNum int) ([]Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	return copyEntities(t.entities), nil
+}
+
+// SaveOrders saves orders, replacing any existing orders for the actor.
+func (s *MemoryStore) SaveOrders(ctx context.Context, gameID string, turnNum int, actor string, orders []Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID, actor); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	saved := append([]Order(nil), orders...)
+	sort.SliceStable(saved, func(i, j int) bool { return saved[i].Seq < saved[j].Seq })
+	t.orders[actor] = saved
+	return nil
+}
+
+// GetOrders retrieves orders.
+func (s *MemoryStore) GetOrders(ctx context.Context, gameID string, turnNum int, actor string) ([]Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID, actor); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Order(nil), t.orders[actor]...), nil
+}
+
+// SaveRNGState saves an RNG checkpoint, replacing any existing checkpoint for the turn.
+func (s *MemoryStore) SaveRNGState(ctx context.Context, gameID string, turnNum int, states map[string][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	t.rngState = copyStates(states)
+	return nil
+}
+
+// LoadRNGState loads the RNG checkpoint for the turn.
+func (s *MemoryStore) LoadRNGState(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	return copyStates(t.rngState), nil
+}
+
+// SaveRNGTrace saves the RNG trace for a phase, replacing any existing trace.
+func (s *MemoryStore) SaveRNGTrace(ctx context.Context, gameID string, turnNum int, phase string, trace []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID, phase); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	t.rngTrace[phase] = bytes.Clone(trace)
+	return nil
+}
+
+// LoadRNGTraces loads the RNG traces for the turn, by phase.
+func (s *MemoryStore) LoadRNGTraces(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	return copyStates(t.rngTrace), nil
+}
+
+// SaveReport saves a report, replacing any existing report for the actor and mime type.
+func (s *MemoryStore) SaveReport(ctx context.Context, gameID string, turnNum int, actor string, mime string, body io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := checkNames(gameID, actor, mime); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return err
+	}
+	t.reports[memoryReportKey{actor: actor, mime: mime}] = data
+	return nil
+}
+
+// GetReport retrieves a report.
+func (s *MemoryStore) GetReport(ctx context.Context, gameID string, turnNum int, actor string, mime string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkNames(gameID, actor, mime); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.turn(gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := t.reports[memoryReportKey{actor: actor, mime: mime}]
+	if !ok {
+		return nil, &cerrs.ErrReportNotFound{GameID: gameID, Turn: turnNum, Actor: actor, Mime: mime}
+	}
+	return io.NopCloser(NewByteReader(bytes.Clone(data))), nil
+}
+
+// GetSchemaVersion returns the current schema version.
+func (s *MemoryStore) GetSchemaVersion(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return memorySchemaVersion, nil
+}
+
+// UpgradeSchema is a no-op; an in-memory store is always current.
+func (s *MemoryStore) UpgradeSchema(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close releases all data held by the store.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games = make(map[string]*memoryGame)
+	return nil
+}
+
+// turn returns the turn, or cerrs.ErrGameNotFound or cerrs.ErrTurnNotFound
+// if it has not been created. The caller must hold the lock.
+func (s *MemoryStore) turn(gameID string, turnNum int) (*memoryTurn, error) {
+	g, ok := s.games[gameID]
+	if !ok {
+		return nil, &cerrs.ErrGameNotFound{GameID: gameID}
+	}
+	t, ok := g.turns[turnNum]
+	if !ok {
+		return nil, &cerrs.ErrTurnNotFound{GameID: gameID, Turn: turnNum}
+	}
+	return t, nil
+}
+
+// copyStates returns a deep copy of RNG states or traces. It never returns nil.
+func copyStates(states map[string][]byte) map[string][]byte {
+	clone := make(map[string][]byte, len(states))
+	for name, state := range states {
+		clone[name] = bytes.Clone(state)
+	}
+	return clone
+}
+
+// copyEntities returns a deep copy of entities.
+func copyEntities(entities []Entity) []Entity {
+	if entities == nil {
+		return nil
+	}
+	clone := make([]Entity, len(entities))
+	for i, entity := range entities {
+		clone[i] = Entity{ID: entity.ID, Kind: entity.Kind, Data: bytes.Clone(entity.Data)}
+	}
+	return clone
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/cerrs"
+)
+
+var (
+	_ Store         = (*MemoryStore)(nil)
+	_ MetaStore     = (*MemoryStore)(nil)
+	_ RNGStateStore = (*MemoryStore)(nil)
+	_ RNGTraceStore = (*MemoryStore)(nil)
+)
+
+func TestMemoryStoreSaveLoadSnapshot(t *testing.T) {
+	st := NewMemoryStore()
+	defer st.Close()
+
+	ctx := context.Background()
+
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+
+	if err := st.CreateTurn(ctx, "game1", 1, "production"); err != nil {
+		t.Fatalf("failed to create turn: %v", err)
+	}
+
+	testData := []Entity{
+		{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Earth","population":1000}`)},
+		{ID: "ship-1", Kind: "ship", Data: []byte(`{"name":"Enterprise","tonnage":5000}`)},
+	}
+
+	if err := st.SaveSnapshot(ctx, "game1", 1, testData); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	// the store must not alias the caller's buffers
+	testData[0].Data[2] = 'X'
+
+	loaded, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if len(loaded) != len(testData) {
+		t.Fatalf("expected %d entities, got %d", len(testData), len(loaded))
+	}
+	if got := string(loaded[0].Data); got != `{"name":"Earth","population":1000}` {
+		t.Errorf("entity 0: expected original data, got %q", got)
+	}
+
+	if err := st.SaveSnapshot(ctx, "game1", 2, testData); err == nil {
+		t.Error("expected error saving snapshot for missing turn")
+	}
+}
+
+func TestMemoryStoreReports(t *testing.T) {
+	st := NewMemoryStore()
+	defer st.Close()
+
+	ctx := context.Background()
+
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	if err := st.CreateTurn(ctx, "game1", 1, "production"); err != nil {
+		t.Fatalf("failed to create turn: %v", err)
+	}
+
+	if err := st.SaveReport(ctx, "game1", 1, "SP01", "text/plain", strings.NewReader("turn 1")); err != nil {
+		t.Fatalf("failed to save report: %v", err)
+	}
+	rc, err := st.GetReport(ctx, "game1", 1, "SP01", "text/plain")
+	if err != nil {
+		t.Fatalf("failed to get report: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "turn 1" {
+		t.Errorf("expected report %q, got %q", "turn 1", body)
+	}
+
+	if _, err := st.GetReport(ctx, "game1", 1, "SP02", "text/plain"); !errors.Is(err, cerrs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist for missing report, got %v", err)
+	}
+}
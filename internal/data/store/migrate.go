@@ -0,0 +1,333 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/playbymail/fh/internal/cerrs"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migration represents a database schema migration.
+// The checksum is the SHA-256 of the up script; it is recorded when the
+// migration is applied and verified every time the store is opened.
+type migration struct {
+	name     string
+	up       string
+	down     string
+	checksum string
+}
+
+// migrations is the ordered list of all schema migrations.
+var migrations = mustLoadMigrations(migrationFS)
+
+// loadMigrations reads NNNN_name.up.sql and NNNN_name.down.sql pairs from fsys.
+// Every migration must have an up script; the down script is optional.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		var name, direction string
+		if n, ok := strings.CutSuffix(base, ".up.sql"); ok {
+			name, direction = n, "up"
+		} else if n, ok := strings.CutSuffix(base, ".down.sql"); ok {
+			name, direction = n, "down"
+		} else {
+			return nil, fmt.Errorf("%s: expected NNNN_name.up.sql or NNNN_name.down.sql", file)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byName[name]
+		if !ok {
+			m = &migration{name: name}
+			byName[name] = m
+		}
+		if direction == "up" {
+			m.up = string(data)
+			sum := sha256.Sum256(data)
+			m.checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	var list []migration
+	for _, m := range byName {
+		if m.up == "" {
+			return nil, fmt.Errorf("%s: missing up migration", m.name)
+		}
+		list = append(list, *m)
+	}
+	if len(list) == 0 {
+		return nil, cerrs.ErrNoMigrations
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list, nil
+}
+
+func mustLoadMigrations(fsys fs.FS) []migration {
+	list, err := loadMigrations(fsys)
+	if err != nil {
+		panic(fmt.Sprintf("store: migrations: %v", err))
+	}
+	return list
+}
+
+// LatestSchemaVersion returns the name of the newest registered migration.
+// This is the version that OpenSQLiteStore upgrades to.
+func LatestSchemaVersion() string {
+	return migrations[len(migrations)-1].name
+}
+
+// MigrationStatus reports the state of a single migration.
+type MigrationStatus struct {
+	Name      string
+	Applied   bool
+	AppliedAt string // empty if not applied
+	Checksum  string // checksum of the registered up script
+	Modified  bool   // applied with a different checksum
+	Unknown   bool   // applied but not in this binary's registry
+	CanRevert bool   // has a down script
+}
+
+// appliedMigration is a row from the migrations table.
+type appliedMigration struct {
+	name      string
+	appliedAt string
+	checksum  string
+}
+
+// ensureMigrationsTable creates the migrations table, adding the checksum
+// column to tables created before checksums were recorded.
+func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS migrations (
+		  id INTEGER PRIMARY KEY AUTOINCREMENT,
+		  name TEXT NOT NULL UNIQUE,
+		  applied_at TEXT NOT NULL,
+		  checksum TEXT NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info('migrations')`)
+	if err != nil {
+		return err
+	}
+	hasChecksum := false
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			rows.Close()
+			return err
+		}
+		hasChecksum = hasChecksum || column == "checksum"
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !hasChecksum {
+		_, err = db.ExecContext(ctx, `ALTER TABLE migrations ADD COLUMN checksum TEXT NOT NULL DEFAULT ''`)
+	}
+	return err
+}
+
+// appliedMigrations returns the applied migrations in the order they were applied.
+func appliedMigrations(ctx context.Context, db *sql.DB) ([]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, applied_at, checksum FROM migrations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.name, &a.appliedAt, &a.checksum); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// verifyMigrations checks applied migrations against the registry.
+// Rows recorded before checksums existed are stamped with the registered
+// checksum the first time they are seen.
+func verifyMigrations(ctx context.Context, db *sql.DB, applied []appliedMigration) error {
+	for i, a := range applied {
+		if i >= len(migrations) || migrations[i].name != a.name {
+			if findMigration(a.name) < 0 {
+				return fmt.Errorf("%s: %w", a.name, cerrs.ErrSchemaTooNew)
+			}
+			return fmt.Errorf("%s: applied out of order: %w", a.name, cerrs.ErrSchemaUpgradeFailed)
+		}
+		m := migrations[i]
+		if a.checksum == "" {
+			if _, err := db.ExecContext(ctx, `UPDATE migrations SET checksum = ? WHERE name = ?`, m.checksum, m.name); err != nil {
+				return err
+			}
+			continue
+		}
+		if a.checksum != m.checksum {
+			return fmt.Errorf("%s: %w", a.name, cerrs.ErrMigrationChecksum)
+		}
+	}
+	return nil
+}
+
+// findMigration returns the index of the named migration or -1.
+func findMigration(name string) int {
+	for i, m := range migrations {
+		if m.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrateUp applies every pending migration, each in its own transaction.
+func migrateUp(ctx context.Context, db *sql.DB) error {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err := verifyMigrations(ctx, db, applied); err != nil {
+		return err
+	}
+
+	for _, m := range migrations[len(applied):] {
+		if err := runMigration(ctx, db, m.up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO migrations (name, applied_at, checksum) VALUES (?, datetime('now'), ?)
+			`, m.name, m.checksum)
+			return err
+		}); err != nil {
+			return fmt.Errorf("%s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// migrateDown reverts the most recently applied migrations, newest first.
+func migrateDown(ctx context.Context, db *sql.DB, steps int) error {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err := verifyMigrations(ctx, db, applied); err != nil {
+		return err
+	}
+	if steps > len(applied) {
+		steps = len(applied)
+	}
+
+	for i := len(applied) - 1; i >= len(applied)-steps; i-- {
+		m := migrations[i]
+		if m.down == "" {
+			return fmt.Errorf("%s: %w", m.name, cerrs.ErrNoDownMigration)
+		}
+		if err := runMigration(ctx, db, m.down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM migrations WHERE name = ?`, m.name)
+			return err
+		}); err != nil {
+			return fmt.Errorf("%s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// runMigration executes script and record in a single transaction.
+func runMigration(ctx context.Context, db *sql.DB, script string, record func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpgradeSchema applies pending schema upgrades.
+func (s *SQLiteStore) UpgradeSchema(ctx context.Context) error {
+	if err := migrateUp(ctx, s.db); err != nil {
+		return errors.Join(cerrs.ErrSchemaUpgradeFailed, err)
+	}
+	return nil
+}
+
+// DowngradeSchema reverts the given number of applied migrations, newest first.
+func (s *SQLiteStore) DowngradeSchema(ctx context.Context, steps int) error {
+	if err := migrateDown(ctx, s.db, steps); err != nil {
+		return errors.Join(cerrs.ErrSchemaDowngradeFailed, err)
+	}
+	return nil
+}
+
+// MigrationStatus reports every registered migration, followed by any applied
+// migrations that this binary does not know about.
+func (s *SQLiteStore) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(ctx, s.db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]appliedMigration{}
+	for _, a := range applied {
+		byName[a.name] = a
+	}
+
+	var list []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Name: m.name, Checksum: m.checksum, CanRevert: m.down != ""}
+		if a, ok := byName[m.name]; ok {
+			status.Applied = true
+			status.AppliedAt = a.appliedAt
+			status.Modified = a.checksum != "" && a.checksum != m.checksum
+		}
+		list = append(list, status)
+	}
+	for _, a := range applied {
+		if findMigration(a.name) < 0 {
+			list = append(list, MigrationStatus{Name: a.name, Applied: true, AppliedAt: a.appliedAt, Unknown: true})
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/playbymail/fh/internal/cerrs"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_second.up.sql":  {Data: []byte("CREATE TABLE b (id TEXT);")},
+		"migrations/0001_first.up.sql":   {Data: []byte("CREATE TABLE a (id TEXT);")},
+		"migrations/0001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+	list, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(list) != 2 || list[0].name != "0001_first" || list[1].name != "0002_second" {
+		t.Fatalf("expected 0001_first, 0002_second, got %+v", list)
+	}
+	if list[0].down == "" || list[1].down != "" {
+		t.Errorf("expected down script only for 0001_first")
+	}
+	if list[0].checksum == "" || list[0].checksum == list[1].checksum {
+		t.Errorf("expected distinct checksums, got %q and %q", list[0].checksum, list[1].checksum)
+	}
+
+	if _, err := loadMigrations(fstest.MapFS{"migrations/0001_first.down.sql": {Data: []byte("DROP TABLE a;")}}); err == nil {
+		t.Error("expected error for migration without up script")
+	}
+	if _, err := loadMigrations(fstest.MapFS{}); !errors.Is(err, cerrs.ErrNoMigrations) {
+		t.Errorf("expected ErrNoMigrations, got %v", err)
+	}
+}
+
+func TestMigrationChecksumBackfill(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	st, err := NewSQLiteStore(dbPath, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	// simulate a database created before checksums were recorded
+	if _, err := st.db.Exec(`UPDATE migrations SET checksum = ''`); err != nil {
+		t.Fatalf("failed to clear checksums: %v", err)
+	}
+	st.Close()
+
+	st2, err := OpenSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st2.Close()
+
+	var checksum string
+	if err := st2.db.QueryRow(`SELECT checksum FROM migrations WHERE name = ?`, migrations[0].name).Scan(&checksum); err != nil {
+		t.Fatalf("failed to read checksum: %v", err)
+	}
+	if checksum != migrations[0].checksum {
+		t.Errorf("expected checksum %q, got %q", migrations[0].checksum, checksum)
+	}
+}
+
+func TestMigrationChecksumMismatch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	st, err := NewSQLiteStore(dbPath, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if _, err := st.db.Exec(`UPDATE migrations SET checksum = 'edited'`); err != nil {
+		t.Fatalf("failed to edit checksum: %v", err)
+	}
+
+	statuses, err := st.MigrationStatus(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if !statuses[0].Modified {
+		t.Errorf("expected %s to be reported as modified", statuses[0].Name)
+	}
+	st.Close()
+
+	if _, err := OpenSQLiteStore(dbPath); !errors.Is(err, cerrs.ErrMigrationChecksum) {
+		t.Errorf("expected ErrMigrationChecksum, got %v", err)
+	}
+}
+
+func TestMigrateDownUp(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	st, err := NewSQLiteStore(dbPath, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	if err := st.DowngradeSchema(ctx, len(migrations)); err != nil {
+		t.Fatalf("failed to downgrade: %v", err)
+	}
+	version, err := st.GetSchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if version != "" {
+		t.Errorf("expected empty version after full downgrade, got %q", version)
+	}
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err == nil {
+		t.Error("expected error creating game after game table was dropped")
+	}
+
+	if err := st.UpgradeSchema(ctx); err != nil {
+		t.Fatalf("failed to upgrade: %v", err)
+	}
+	version, err = st.GetSchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("expected version %q, got %q", LatestSchemaVersion(), version)
+	}
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Errorf("failed to create game after upgrade: %v", err)
+	}
+}
+
+// createTable matches the tables created by a migration script.
+var createTable = regexp.MustCompile(`(?i)CREATE TABLE IF NOT EXISTS (\w+)`)
+
+func TestMigrations(t *testing.T) {
+	for i, m := range migrations {
+		t.Run(m.name, func(t *testing.T) {
+			st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), false)
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			defer st.Close()
+
+			// revert this migration and every later one
+			ctx := context.Background()
+			if err := st.DowngradeSchema(ctx, len(migrations)-i); err != nil {
+				t.Fatalf("failed to downgrade: %v", err)
+			}
+			want := ""
+			if i > 0 {
+				want = migrations[i-1].name
+			}
+			if version, err := st.GetSchemaVersion(ctx); err != nil || version != want {
+				t.Fatalf("after downgrade: expected version %q, got %q, %v", want, version, err)
+			}
+
+			if err := st.UpgradeSchema(ctx); err != nil {
+				t.Fatalf("failed to upgrade: %v", err)
+			}
+			if version, err := st.GetSchemaVersion(ctx); err != nil || version != LatestSchemaVersion() {
+				t.Errorf("after upgrade: expected version %q, got %q, %v", LatestSchemaVersion(), version, err)
+			}
+			for _, match := range createTable.FindAllStringSubmatch(m.up, -1) {
+				var exists int
+				err := st.db.QueryRow(`
+					SELECT 1 FROM sqlite_master
+					WHERE type='table' AND name=?
+				`, match[1]).Scan(&exists)
+				if err != nil || exists != 1 {
+					t.Errorf("%s table not created", match[1])
+				}
+			}
+		})
+	}
+}
+
+// TestMigrationsKeepData re-runs every up script on a store with data.
+// Up scripts only create what is missing, so no rows may be lost.
+func TestMigrationsKeepData(t *testing.T) {
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"), false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	if err := st.CreateTurn(ctx, "game1", 0, "setup"); err != nil {
+		t.Fatalf("failed to create turn: %v", err)
+	}
+	if err := st.SetGameMeta(ctx, "game1", "rng", "keyed"); err != nil {
+		t.Fatalf("failed to set game meta: %v", err)
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 0, []Entity{{ID: "S001", Kind: "star", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	if err := st.SaveRNGState(ctx, "game1", 0, map[string][]byte{"legacy": {'M', 1}}); err != nil {
+		t.Fatalf("failed to save rng state: %v", err)
+	}
+	if err := st.SaveRNGTrace(ctx, "game1", 0, "galaxy", []byte(`{}`)); err != nil {
+		t.Fatalf("failed to save rng trace: %v", err)
+	}
+
+	for _, m := range migrations {
+		if _, err := st.db.ExecContext(ctx, m.up); err != nil {
+			t.Fatalf("%s: failed to re-run up script: %v", m.name, err)
+		}
+	}
+
+	if meta, err := st.GetGameMeta(ctx, "game1"); err != nil || len(meta) != 1 {
+		t.Errorf("game meta: expected 1 value, got %v, %v", meta, err)
+	}
+	if entities, err := st.LoadSnapshot(ctx, "game1", 0); err != nil || len(entities) != 1 {
+		t.Errorf("snapshot: expected 1 entity, got %d, %v", len(entities), err)
+	}
+	if states, err := st.LoadRNGState(ctx, "game1", 0); err != nil || len(states) != 1 {
+		t.Errorf("rng state: expected 1 generator, got %d, %v", len(states), err)
+	}
+	if traces, err := st.LoadRNGTraces(ctx, "game1", 0); err != nil || len(traces) != 1 {
+		t.Errorf("rng traces: expected 1 phase, got %d, %v", len(traces), err)
+	}
+}
+
+func TestMigrationUnknownVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	st, err := NewSQLiteStore(dbPath, false)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if _, err := st.db.Exec(`INSERT INTO migrations (name, applied_at, checksum) VALUES ('9999_future', datetime('now'), 'x')`); err != nil {
+		t.Fatalf("failed to insert future migration: %v", err)
+	}
+	st.Close()
+
+	if _, err := OpenSQLiteStore(dbPath); !errors.Is(err, cerrs.ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
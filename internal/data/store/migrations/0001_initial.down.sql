@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_turn_game_started;
+DROP TABLE IF EXISTS report;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS entity;
+DROP TABLE IF EXISTS turn;
+DROP TABLE IF EXISTS game;
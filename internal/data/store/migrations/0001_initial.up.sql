@@ -0,0 +1,54 @@
+-- games & turns
+CREATE TABLE IF NOT EXISTS game (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS turn (
+  game_id TEXT NOT NULL,
+  num INTEGER NOT NULL,
+  phase TEXT NOT NULL,
+  started_at TEXT NOT NULL,
+  ended_at TEXT,
+  PRIMARY KEY (game_id, num),
+  FOREIGN KEY (game_id) REFERENCES game(id) ON DELETE CASCADE
+);
+
+-- world snapshots
+CREATE TABLE IF NOT EXISTS entity (
+  game_id TEXT NOT NULL,
+  turn_num INTEGER NOT NULL,
+  id TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  data BLOB NOT NULL,
+  PRIMARY KEY (game_id, turn_num, id),
+  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
+);
+
+-- orders
+CREATE TABLE IF NOT EXISTS orders (
+  game_id TEXT NOT NULL,
+  turn_num INTEGER NOT NULL,
+  actor TEXT NOT NULL,
+  seq INTEGER NOT NULL,
+  raw TEXT NOT NULL,
+  normalized TEXT,
+  status TEXT NOT NULL,
+  error TEXT,
+  PRIMARY KEY (game_id, turn_num, actor, seq),
+  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
+);
+
+-- reports
+CREATE TABLE IF NOT EXISTS report (
+  game_id TEXT NOT NULL,
+  turn_num INTEGER NOT NULL,
+  actor TEXT NOT NULL,
+  mime TEXT NOT NULL,
+  body BLOB NOT NULL,
+  PRIMARY KEY (game_id, turn_num, actor, mime),
+  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_turn_game_started ON turn(game_id, started_at);
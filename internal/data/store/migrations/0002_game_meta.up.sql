@@ -0,0 +1,8 @@
+-- per-game settings, such as the RNG mode
+CREATE TABLE IF NOT EXISTS game_meta (
+  game_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (game_id, key),
+  FOREIGN KEY (game_id) REFERENCES game(id) ON DELETE CASCADE
+);
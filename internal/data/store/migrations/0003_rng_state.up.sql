@@ -0,0 +1,9 @@
+-- RNG checkpoints, so a crashed turn can resume with the same draws
+CREATE TABLE IF NOT EXISTS rng_state (
+  game_id TEXT NOT NULL,
+  turn_num INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  state BLOB NOT NULL,
+  PRIMARY KEY (game_id, turn_num, name),
+  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- RNG draw traces, one per phase of a turn
+CREATE TABLE IF NOT EXISTS rng_trace (
+  game_id TEXT NOT NULL,
+  turn_num INTEGER NOT NULL,
+  phase TEXT NOT NULL,
+  trace BLOB NOT NULL,
+  PRIMARY KEY (game_id, turn_num, phase),
+  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
+);
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 
@@ -17,7 +18,33 @@ type SQLiteStore struct {
 }
 
 // OpenSQLiteStore opens an existing SQLite store.
+// It verifies the checksums of applied migrations and applies any that are pending.
 func OpenSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	store, err := OpenSQLiteStoreNoUpgrade(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify applied migrations and upgrade schema if needed.
+	// UpgradeSchema is a no-op when the schema is current.
+	if err := store.UpgradeSchema(context.Background()); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	version, err := store.GetSchemaVersion(context.Background())
+	if err != nil || version != LatestSchemaVersion() {
+		store.Close()
+		return nil, cerrs.ErrSchemaTooNew
+	}
+
+	return store, nil
+}
+
+// OpenSQLiteStoreNoUpgrade opens an existing SQLite store without checking
+// or changing the schema. It is intended for migration tooling; everything
+// else should use OpenSQLiteStore.
+func OpenSQLiteStoreNoUpgrade(dbPath string) (*SQLiteStore, error) {
 	_, err := os.Stat(dbPath)
 	if os.IsNotExist(err) {
 		return nil, cerrs.ErrNotExist
@@ -36,30 +63,7 @@ func OpenSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, err
 	}
 
-	store := &SQLiteStore{db: db}
-
-	// Check and upgrade schema if needed
-	expected := "0001_initial"
-	version, err := store.GetSchemaVersion(context.Background())
-	if err != nil {
-		store.Close()
-		return nil, err
-	}
-
-	if version != expected {
-		if err := store.UpgradeSchema(context.Background()); err != nil {
-			store.Close()
-			return nil, errors.Join(cerrs.ErrSchemaUpgradeFailed, err)
-		}
-
-		version, err = store.GetSchemaVersion(context.Background())
-		if err != nil || version != expected {
-			store.Close()
-			return nil, cerrs.ErrSchemaTooNew
-		}
-	}
-
-	return store, nil
+	return &SQLiteStore{db: db}, nil
 }
 
 // NewSQLiteStore creates a new SQLite store.
@@ -85,7 +89,7 @@ func NewSQLiteStore(dbPath string, force bool) (*SQLiteStore, error) {
 		return nil, err
 	}
 
-	if err := setupSchema(db); err != nil {
+	if err := migrateUp(context.Background(), db); err != nil {
 		db.Close()
 		return nil, errors.Join(cerrs.ErrSchemaSetupFailed, err)
 	}
@@ -109,99 +113,25 @@ func enablePragmas(db *sql.DB) error {
 	return nil
 }
 
-// setupSchema creates the database tables.
-func setupSchema(db *sql.DB) error {
-	schema := `
--- migrations
-CREATE TABLE IF NOT EXISTS migrations (
-  id INTEGER PRIMARY KEY AUTOINCREMENT,
-  name TEXT NOT NULL UNIQUE,
-  applied_at TEXT NOT NULL
-);
-
--- games & turns
-CREATE TABLE IF NOT EXISTS game (
-  id TEXT PRIMARY KEY,
-  name TEXT NOT NULL,
-  created_at TEXT NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS turn (
-  game_id TEXT NOT NULL,
-  num INTEGER NOT NULL,
-  phase TEXT NOT NULL,
-  started_at TEXT NOT NULL,
-  ended_at TEXT,
-  PRIMARY KEY (game_id, num),
-  FOREIGN KEY (game_id) REFERENCES game(id) ON DELETE CASCADE
-);
-
--- world snapshots
-CREATE TABLE IF NOT EXISTS entity (
-  game_id TEXT NOT NULL,
-  turn_num INTEGER NOT NULL,
-  id TEXT NOT NULL,
-  kind TEXT NOT NULL,
-  data BLOB NOT NULL,
-  PRIMARY KEY (game_id, turn_num, id),
-  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
-);
-
--- orders
-CREATE TABLE IF NOT EXISTS orders (
-  game_id TEXT NOT NULL,
-  turn_num INTEGER NOT NULL,
-  actor TEXT NOT NULL,
-  seq INTEGER NOT NULL,
-  raw TEXT NOT NULL,
-  normalized TEXT,
-  status TEXT NOT NULL,
-  error TEXT,
-  PRIMARY KEY (game_id, turn_num, actor, seq),
-  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
-);
-
--- reports
-CREATE TABLE IF NOT EXISTS report (
-  game_id TEXT NOT NULL,
-  turn_num INTEGER NOT NULL,
-  actor TEXT NOT NULL,
-  mime TEXT NOT NULL,
-  body BLOB NOT NULL,
-  PRIMARY KEY (game_id, turn_num, actor, mime),
-  FOREIGN KEY (game_id, turn_num) REFERENCES turn(game_id, num) ON DELETE CASCADE
-);
-`
-	_, err := db.Exec(schema)
-	if err != nil {
-		return err
-	}
-
-	// Record the initial migration
-	_, err = db.Exec(`
-		INSERT OR IGNORE INTO migrations (name, applied_at) VALUES ('0001_initial', datetime('now'))
-	`)
-	if err != nil {
-		return err
-	}
-
-	// Add indexes
-	_, err = db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_turn_game_started ON turn(game_id, started_at);
-	`)
-	return err
-}
-
 // CreateGame inserts a new game.
 func (s *SQLiteStore) CreateGame(ctx context.Context, id, name string) error {
+	if err := checkNames(id); err != nil {
+		return err
+	}
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO game (id, name, created_at) VALUES (?, ?, datetime('now'))
 	`, id, name)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf("game %q: %w", id, cerrs.ErrExists)
+	}
 	return err
 }
 
 // GetGame retrieves game metadata.
 func (s *SQLiteStore) GetGame(ctx context.Context, id string) (*Game, error) {
+	if err := checkNames(id); err != nil {
+		return nil, err
+	}
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, name, created_at FROM game WHERE id = ?
 	`, id)
@@ -209,21 +139,92 @@ func (s *SQLiteStore) GetGame(ctx context.Context, id string) (*Game, error) {
 	var game Game
 	err := row.Scan(&game.ID, &game.Name, &game.CreatedAt)
 	if err == sql.ErrNoRows {
-		return nil, cerrs.ErrNotImplemented // TODO: proper not found error
+		return nil, &cerrs.ErrGameNotFound{GameID: id}
+	} else if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// SetGameMeta sets a game metadata value, replacing any existing value.
+func (s *SQLiteStore) SetGameMeta(ctx context.Context, gameID, key, value string) error {
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	if err := checkGame(ctx, s.db, gameID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO game_meta (game_id, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (game_id, key) DO UPDATE SET value = excluded.value
+	`, gameID, key, value)
+	return err
+}
+
+// GetGameMeta retrieves the game metadata.
+func (s *SQLiteStore) GetGameMeta(ctx context.Context, gameID string) (map[string]string, error) {
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	if err := checkGame(ctx, s.db, gameID); err != nil {
+		return nil, err
 	}
-	return &game, err
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT key, value FROM game_meta WHERE game_id = ?
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meta := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		meta[key] = value
+	}
+	return meta, rows.Err()
 }
 
 // CreateTurn inserts a new turn.
 func (s *SQLiteStore) CreateTurn(ctx context.Context, gameID string, turnNum int, phase string) error {
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	if err := checkGame(ctx, s.db, gameID); err != nil {
+		return err
+	}
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO turn (game_id, num, phase, started_at) VALUES (?, ?, ?, datetime('now'))
 	`, gameID, turnNum, phase)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf("game %q: turn %d: %w", gameID, turnNum, cerrs.ErrExists)
+	}
+	return err
+}
+
+// DeleteTurn deletes a turn. The snapshot, orders, reports and RNG data of
+// the turn are deleted with it.
+func (s *SQLiteStore) DeleteTurn(ctx context.Context, gameID string, turnNum int) error {
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM turn WHERE game_id = ? AND num = ?
+	`, gameID, turnNum)
 	return err
 }
 
 // GetCurrentTurn finds the latest turn.
 func (s *SQLiteStore) GetCurrentTurn(ctx context.Context, gameID string) (*Turn, error) {
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
 	row := s.db.QueryRowContext(ctx, `
 		SELECT game_id, num, phase, started_at, ended_at
 		FROM turn
@@ -233,21 +234,35 @@ func (s *SQLiteStore) GetCurrentTurn(ctx context.Context, gameID string) (*Turn,
 	`, gameID)
 
 	var turn Turn
-	err := row.Scan(&turn.GameID, &turn.Num, &turn.Phase, &turn.StartedAt, &turn.EndedAt)
+	var endedAt sql.NullString
+	err := row.Scan(&turn.GameID, &turn.Num, &turn.Phase, &turn.StartedAt, &endedAt)
 	if err == sql.ErrNoRows {
-		return nil, cerrs.ErrNotImplemented
+		if err := checkGame(ctx, s.db, gameID); err != nil {
+			return nil, err
+		}
+		return nil, &cerrs.ErrTurnNotFound{GameID: gameID, Turn: -1}
+	} else if err != nil {
+		return nil, err
 	}
-	return &turn, err
+	turn.EndedAt = endedAt.String
+	return &turn, nil
 }
 
 // SaveSnapshot saves entities.
 func (s *SQLiteStore) SaveSnapshot(ctx context.Context, gameID string, turnNum int, entities []Entity) error {
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := checkTurn(ctx, tx, gameID, turnNum); err != nil {
+		return err
+	}
+
 	// Delete existing entities for this turn
 	_, err = tx.ExecContext(ctx, `
 		DELETE FROM entity WHERE game_id = ? AND turn_num = ?
@@ -277,6 +292,13 @@ func (s *SQLiteStore) SaveSnapshot(ctx context.Context, gameID string, turnNum i
 
 // LoadSnapshot loads entities.
 func (s *SQLiteStore) LoadSnapshot(ctx context.Context, gameID string, turnNum int) ([]Entity, error) {
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return nil, err
+	}
+
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, kind, data FROM entity WHERE game_id = ? AND turn_num = ?
 	`, gameID, turnNum)
@@ -301,12 +323,19 @@ func (s *SQLiteStore) LoadSnapshot(ctx context.Context, gameID string, turnNum i
 
 // SaveOrders saves orders.
 func (s *SQLiteStore) SaveOrders(ctx context.Context, gameID string, turnNum int, actor string, orders []Order) error {
+	if err := checkNames(gameID, actor); err != nil {
+		return err
+	}
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := checkTurn(ctx, tx, gameID, turnNum); err != nil {
+		return err
+	}
+
 	// Delete existing orders
 	_, err = tx.ExecContext(ctx, `
 		DELETE FROM orders WHERE game_id = ? AND turn_num = ? AND actor = ?
@@ -337,6 +366,13 @@ func (s *SQLiteStore) SaveOrders(ctx context.Context, gameID string, turnNum int
 
 // GetOrders retrieves orders.
 func (s *SQLiteStore) GetOrders(ctx context.Context, gameID string, turnNum int, actor string) ([]Order, error) {
+	if err := checkNames(gameID, actor); err != nil {
+		return nil, err
+	}
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return nil, err
+	}
+
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT seq, raw, normalized, status, error FROM orders
 		WHERE game_id = ? AND turn_num = ? AND actor = ?
@@ -359,13 +395,130 @@ func (s *SQLiteStore) GetOrders(ctx context.Context, gameID string, turnNum int,
 	return orders, rows.Err()
 }
 
+// SaveRNGState saves an RNG checkpoint, replacing any existing checkpoint for the turn.
+func (s *SQLiteStore) SaveRNGState(ctx context.Context, gameID string, turnNum int, states map[string][]byte) error {
+	if err := checkNames(gameID); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkTurn(ctx, tx, gameID, turnNum); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM rng_state WHERE game_id = ? AND turn_num = ?
+	`, gameID, turnNum)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO rng_state (game_id, turn_num, name, state) VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for name, state := range states {
+		if _, err := stmt.ExecContext(ctx, gameID, turnNum, name, state); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadRNGState loads the RNG checkpoint for the turn.
+func (s *SQLiteStore) LoadRNGState(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error) {
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, state FROM rng_state WHERE game_id = ? AND turn_num = ?
+	`, gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := map[string][]byte{}
+	for rows.Next() {
+		var name string
+		var state []byte
+		if err := rows.Scan(&name, &state); err != nil {
+			return nil, err
+		}
+		states[name] = state
+	}
+	return states, rows.Err()
+}
+
+// SaveRNGTrace saves the RNG trace for a phase, replacing any existing trace.
+func (s *SQLiteStore) SaveRNGTrace(ctx context.Context, gameID string, turnNum int, phase string, trace []byte) error {
+	if err := checkNames(gameID, phase); err != nil {
+		return err
+	}
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rng_trace (game_id, turn_num, phase, trace) VALUES (?, ?, ?, ?)
+		ON CONFLICT (game_id, turn_num, phase) DO UPDATE SET trace = excluded.trace
+	`, gameID, turnNum, phase, trace)
+	return err
+}
+
+// LoadRNGTraces loads the RNG traces for the turn, by phase.
+func (s *SQLiteStore) LoadRNGTraces(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error) {
+	if err := checkNames(gameID); err != nil {
+		return nil, err
+	}
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT phase, trace FROM rng_trace WHERE game_id = ? AND turn_num = ?
+	`, gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	traces := map[string][]byte{}
+	for rows.Next() {
+		var phase string
+		var trace []byte
+		if err := rows.Scan(&phase, &trace); err != nil {
+			return nil, err
+		}
+		traces[phase] = trace
+	}
+	return traces, rows.Err()
+}
+
 // SaveReport saves a report.
 func (s *SQLiteStore) SaveReport(ctx context.Context, gameID string, turnNum int, actor string, mime string, body io.Reader) error {
+	if err := checkNames(gameID, actor, mime); err != nil {
+		return err
+	}
 	data, err := io.ReadAll(body)
 	if err != nil {
 		return err
 	}
 
+	if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+		return err
+	}
+
 	_, err = s.db.ExecContext(ctx, `
 		INSERT OR REPLACE INTO report (game_id, turn_num, actor, mime, body) VALUES (?, ?, ?, ?, ?)
 	`, gameID, turnNum, actor, mime, data)
@@ -374,13 +527,19 @@ func (s *SQLiteStore) SaveReport(ctx context.Context, gameID string, turnNum int
 
 // GetReport retrieves a report.
 func (s *SQLiteStore) GetReport(ctx context.Context, gameID string, turnNum int, actor string, mime string) (io.ReadCloser, error) {
+	if err := checkNames(gameID, actor, mime); err != nil {
+		return nil, err
+	}
 	var data []byte
 	err := s.db.QueryRowContext(ctx, `
 		SELECT body FROM report WHERE game_id = ? AND turn_num = ? AND actor = ? AND mime = ?
 	`, gameID, turnNum, actor, mime).Scan(&data)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, cerrs.ErrNotImplemented
+			if err := checkTurn(ctx, s.db, gameID, turnNum); err != nil {
+				return nil, err
+			}
+			return nil, &cerrs.ErrReportNotFound{GameID: gameID, Turn: turnNum, Actor: actor, Mime: mime}
 		}
 		return nil, err
 	}
@@ -388,6 +547,34 @@ func (s *SQLiteStore) GetReport(ctx context.Context, gameID string, turnNum int,
 	return io.NopCloser(NewByteReader(data)), nil
 }
 
+// queryRower is implemented by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// checkGame returns cerrs.ErrGameNotFound if the game does not exist.
+func checkGame(ctx context.Context, q queryRower, gameID string) error {
+	var found int
+	err := q.QueryRowContext(ctx, `SELECT 1 FROM game WHERE id = ?`, gameID).Scan(&found)
+	if err == sql.ErrNoRows {
+		return &cerrs.ErrGameNotFound{GameID: gameID}
+	}
+	return err
+}
+
+// checkTurn returns cerrs.ErrGameNotFound or cerrs.ErrTurnNotFound if the turn does not exist.
+func checkTurn(ctx context.Context, q queryRower, gameID string, turnNum int) error {
+	var found int
+	err := q.QueryRowContext(ctx, `SELECT 1 FROM turn WHERE game_id = ? AND num = ?`, gameID, turnNum).Scan(&found)
+	if err == sql.ErrNoRows {
+		if err := checkGame(ctx, q, gameID); err != nil {
+			return err
+		}
+		return &cerrs.ErrTurnNotFound{GameID: gameID, Turn: turnNum}
+	}
+	return err
+}
+
 // ByteReader implements io.Reader for byte slice.
 type ByteReader struct {
 	data []byte
@@ -440,6 +627,11 @@ func isTableNotExistError(err error) bool {
 	return contains(errMsg, "no such table") || contains(errMsg, "does not exist")
 }
 
+// isUniqueConstraintError checks if the error is due to a duplicate key.
+func isUniqueConstraintError(err error) bool {
+	return err != nil && contains(err.Error(), "UNIQUE constraint failed")
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && findSubstring(s, substr))
 }
@@ -453,49 +645,6 @@ func findSubstring(s, substr string) bool {
 	return false
 }
 
-// migration represents a database schema migration.
-type migration struct {
-	name string
-	up   func(*sql.DB) error
-}
-
-// migrations is the ordered list of all schema migrations.
-var migrations = []migration{
-	{
-		name: "0001_initial",
-		up:   setupSchema,
-	},
-}
-
-// UpgradeSchema applies pending schema upgrades.
-func (s *SQLiteStore) UpgradeSchema(ctx context.Context) error {
-	currentVersion, err := s.GetSchemaVersion(ctx)
-	if err != nil {
-		return err
-	}
-
-	// Find which migrations need to be applied
-	startIndex := 0
-	if currentVersion != "" {
-		for i, m := range migrations {
-			if m.name == currentVersion {
-				startIndex = i + 1
-				break
-			}
-		}
-	}
-
-	// Apply pending migrations
-	for i := startIndex; i < len(migrations); i++ {
-		m := migrations[i]
-		if err := m.up(s.db); err != nil {
-			return errors.Join(cerrs.ErrSchemaUpgradeFailed, err)
-		}
-	}
-
-	return nil
-}
-
 // Close closes the database.
 func (s *SQLiteStore) Close() error {
 	return s.db.Close()
@@ -2,9 +2,12 @@ package store
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/playbymail/fh/internal/cerrs"
 )
 
 func TestSaveLoadSnapshot(t *testing.T) {
@@ -92,13 +95,16 @@ func TestForeignKeyCascade(t *testing.T) {
 		t.Fatalf("failed to delete game: %v", err)
 	}
 
-	loaded, err := st.LoadSnapshot(ctx, "game1", 1)
-	if err != nil {
-		t.Fatalf("failed to load snapshot: %v", err)
+	if _, err := st.LoadSnapshot(ctx, "game1", 1); !errors.Is(err, cerrs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist loading snapshot for deleted game, got %v", err)
 	}
 
-	if len(loaded) != 0 {
-		t.Errorf("expected cascade delete to remove entities, but got %d entities", len(loaded))
+	var count int
+	if err := st.db.QueryRow("SELECT COUNT(*) FROM entity WHERE game_id = ?", "game1").Scan(&count); err != nil {
+		t.Fatalf("failed to count entities: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected cascade delete to remove entities, but got %d entities", count)
 	}
 }
 
@@ -123,8 +129,8 @@ func TestSchemaUpgrade(t *testing.T) {
 		t.Fatalf("failed to get schema version: %v", err)
 	}
 
-	if version != "0001_initial" {
-		t.Errorf("expected version 0001_initial, got %q", version)
+	if version != LatestSchemaVersion() {
+		t.Errorf("expected version %q, got %q", LatestSchemaVersion(), version)
 	}
 }
 
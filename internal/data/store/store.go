@@ -1,9 +1,16 @@
 // Package store implements persistence interfaces for Far Horizons.
+//
+// Every store implements Store. MetaStore, RNGStateStore and RNGTraceStore
+// are optional: a store implements the ones for the data it keeps, and
+// callers check for them with a type assertion.
 package store
 
 import (
 	"context"
+	"fmt"
 	"io"
+
+	"github.com/playbymail/fh/internal/cerrs"
 )
 
 // Store is the interface for game data persistence.
@@ -19,6 +26,7 @@ type Store interface {
 
 	// Turn management
 	CreateTurn(ctx context.Context, gameID string, turnNum int, phase string) error
+	DeleteTurn(ctx context.Context, gameID string, turnNum int) error // and everything saved with it
 	GetCurrentTurn(ctx context.Context, gameID string) (*Turn, error)
 
 	// World snapshots
@@ -37,6 +45,32 @@ type Store interface {
 	Close() error
 }
 
+// MetaStore is implemented by stores that keep metadata with a game, such
+// as the settings chosen when the game was created.
+type MetaStore interface {
+	// SetGameMeta sets a metadata value.
+	// GetGameMeta returns every value set for the game.
+	SetGameMeta(ctx context.Context, gameID, key, value string) error
+	GetGameMeta(ctx context.Context, gameID string) (map[string]string, error)
+}
+
+// RNGStateStore is implemented by stores that keep RNG checkpoints, the
+// state of named generators during a turn.
+type RNGStateStore interface {
+	// SaveRNGState replaces any existing checkpoint for the turn.
+	SaveRNGState(ctx context.Context, gameID string, turnNum int, states map[string][]byte) error
+	LoadRNGState(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error)
+}
+
+// RNGTraceStore is implemented by stores that keep RNG traces, the logs
+// of the draws made in a phase of a turn.
+type RNGTraceStore interface {
+	// SaveRNGTrace replaces any existing trace for the phase;
+	// LoadRNGTraces returns the traces of every phase of the turn.
+	SaveRNGTrace(ctx context.Context, gameID string, turnNum int, phase string, trace []byte) error
+	LoadRNGTraces(ctx context.Context, gameID string, turnNum int) (map[string][]byte, error)
+}
+
 // Game represents a game instance.
 type Game struct {
 	ID        string
@@ -68,3 +102,16 @@ type Order struct {
 	Status     string
 	Error      string
 }
+
+// checkNames returns cerrs.ErrInvalidName if a game ID, actor, mime type or
+// phase is empty, "." or "..". JSONStore uses names as path components, where
+// those would refer to another directory, so every method of every store
+// rejects them.
+func checkNames(names ...string) error {
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." {
+			return fmt.Errorf("%q: %w", name, cerrs.ErrInvalidName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,815 @@
+// Package storetest implements a behavioral test suite for store.Store.
+//
+// Any implementation of store.Store can prove that it behaves like
+// SQLiteStore by calling Run from a test:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store {
+//			return mystore.New(t.TempDir())
+//		})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/cerrs"
+	"github.com/playbymail/fh/internal/data/store"
+)
+
+// Constructor returns a new, empty store for a single test.
+// The suite closes the store when the test ends.
+type Constructor func(t *testing.T) store.Store
+
+// Run runs the full conformance suite against stores built by newStore.
+func Run(t *testing.T, newStore Constructor) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, st store.Store)
+	}{
+		{"CreateGetGame", testCreateGetGame},
+		{"DuplicateGame", testDuplicateGame},
+		{"GameMeta", testGameMeta},
+		{"CurrentTurnOrdering", testCurrentTurnOrdering},
+		{"TurnRequiresGame", testTurnRequiresGame},
+		{"DeleteTurn", testDeleteTurn},
+		{"SnapshotRoundTrip", testSnapshotRoundTrip},
+		{"SnapshotReplace", testSnapshotReplace},
+		{"SnapshotHTMLCharacters", testSnapshotHTMLCharacters},
+		{"SnapshotPerTurn", testSnapshotPerTurn},
+		{"OrdersReplacePerActor", testOrdersReplacePerActor},
+		{"ReportOverwriteByMime", testReportOverwriteByMime},
+		{"RNGStateReplace", testRNGStateReplace},
+		{"RNGTracePerPhase", testRNGTracePerPhase},
+		{"NotFound", testNotFound},
+		{"InvalidNames", testInvalidNames},
+		{"ContextCanceled", testContextCanceled},
+		{"SchemaVersion", testSchemaVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newStore(t)
+			defer st.Close()
+			tt.fn(t, st)
+		})
+	}
+}
+
+// setupTurns creates game1 and the given turns, failing the test on error.
+func setupTurns(t *testing.T, st store.Store, turns ...int) {
+	t.Helper()
+	ctx := context.Background()
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	for _, num := range turns {
+		if err := st.CreateTurn(ctx, "game1", num, "production"); err != nil {
+			t.Fatalf("failed to create turn %d: %v", num, err)
+		}
+	}
+}
+
+func testCreateGetGame(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	game, err := st.GetGame(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get game: %v", err)
+	}
+	if game.ID != "game1" {
+		t.Errorf("expected ID %q, got %q", "game1", game.ID)
+	}
+	if game.Name != "Test Game" {
+		t.Errorf("expected Name %q, got %q", "Test Game", game.Name)
+	}
+	if game.CreatedAt == "" {
+		t.Error("expected CreatedAt to be set")
+	}
+}
+
+func testDuplicateGame(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	if err := st.CreateGame(ctx, "game1", "Test Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	if err := st.CreateGame(ctx, "game1", "Other Game"); !errors.Is(err, cerrs.ErrExists) {
+		t.Errorf("expected ErrExists creating duplicate game, got %v", err)
+	}
+	game, err := st.GetGame(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get game: %v", err)
+	}
+	if game.Name != "Test Game" {
+		t.Errorf("duplicate create changed name to %q", game.Name)
+	}
+}
+
+// metaStore returns the store as a store.MetaStore, or skips the test if
+// the store does not keep game metadata.
+func metaStore(t *testing.T, st store.Store) store.MetaStore {
+	t.Helper()
+	ms, ok := st.(store.MetaStore)
+	if !ok {
+		t.Skip("store does not implement store.MetaStore")
+	}
+	return ms
+}
+
+func testGameMeta(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	ms := metaStore(t, st)
+	setupTurns(t, st)
+	meta, err := ms.GetGameMeta(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get game meta: %v", err)
+	}
+	if len(meta) != 0 {
+		t.Errorf("expected no meta on a new game, got %v", meta)
+	}
+
+	if err := ms.SetGameMeta(ctx, "game1", "rng", "keyed"); err != nil {
+		t.Fatalf("failed to set game meta: %v", err)
+	}
+	if err := ms.SetGameMeta(ctx, "game1", "seed", "42"); err != nil {
+		t.Fatalf("failed to set game meta: %v", err)
+	}
+	if err := ms.SetGameMeta(ctx, "game1", "rng", "legacy"); err != nil {
+		t.Fatalf("failed to replace game meta: %v", err)
+	}
+	meta, err = ms.GetGameMeta(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get game meta: %v", err)
+	}
+	if len(meta) != 2 || meta["rng"] != "legacy" || meta["seed"] != "42" {
+		t.Errorf("expected rng=legacy seed=42, got %v", meta)
+	}
+
+	// changing the returned map does not change the store
+	meta["rng"] = "changed"
+	if again, _ := ms.GetGameMeta(ctx, "game1"); again["rng"] != "legacy" {
+		t.Errorf("GetGameMeta returned the stored map")
+	}
+}
+
+func testCurrentTurnOrdering(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 2, 10, 9, 0)
+	if err := st.CreateGame(ctx, "game2", "Other Game"); err != nil {
+		t.Fatalf("failed to create game: %v", err)
+	}
+	if err := st.CreateTurn(ctx, "game2", 99, "combat"); err != nil {
+		t.Fatalf("failed to create turn: %v", err)
+	}
+
+	turn, err := st.GetCurrentTurn(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get current turn: %v", err)
+	}
+	if turn.GameID != "game1" || turn.Num != 10 || turn.Phase != "production" {
+		t.Errorf("expected game1 turn 10 production, got %+v", *turn)
+	}
+	if turn.StartedAt == "" {
+		t.Error("expected StartedAt to be set")
+	}
+
+	if err := st.CreateTurn(ctx, "game1", 10, "combat"); !errors.Is(err, cerrs.ErrExists) {
+		t.Errorf("expected ErrExists creating duplicate turn, got %v", err)
+	}
+}
+
+func testTurnRequiresGame(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	if err := st.CreateTurn(ctx, "nogame", 1, "production"); err == nil {
+		t.Error("expected error creating turn for missing game")
+	}
+	if err := st.SaveSnapshot(ctx, "nogame", 1, []store.Entity{{ID: "a", Kind: "star", Data: []byte(`{}`)}}); err == nil {
+		t.Error("expected error saving snapshot for missing turn")
+	}
+	if err := st.SaveOrders(ctx, "nogame", 1, "SP01", []store.Order{{Seq: 1, Raw: "x", Status: "pending"}}); err == nil {
+		t.Error("expected error saving orders for missing turn")
+	}
+	if err := st.SaveReport(ctx, "nogame", 1, "SP01", "text/plain", strings.NewReader("x")); err == nil {
+		t.Error("expected error saving report for missing turn")
+	}
+}
+
+func testDeleteTurn(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1, 2)
+
+	one := []store.Entity{{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Earth"}`)}}
+	two := []store.Entity{{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Mars"}`)}}
+	if err := st.SaveSnapshot(ctx, "game1", 1, one); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 2, two); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	if err := st.SaveOrders(ctx, "game1", 2, "SP01", []store.Order{{Seq: 1, Raw: "x", Status: "pending"}}); err != nil {
+		t.Fatalf("failed to save orders: %v", err)
+	}
+	if err := st.DeleteTurn(ctx, "game1", 2); err != nil {
+		t.Fatalf("failed to delete turn: %v", err)
+	}
+
+	turn, err := st.GetCurrentTurn(ctx, "game1")
+	if err != nil {
+		t.Fatalf("failed to get current turn: %v", err)
+	}
+	if turn.Num != 1 {
+		t.Errorf("expected current turn 1, got %d", turn.Num)
+	}
+	if _, err := st.LoadSnapshot(ctx, "game1", 2); !errors.Is(err, cerrs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist loading the snapshot of a deleted turn, got %v", err)
+	}
+	got, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	compareEntities(t, got, one)
+
+	// the turn can be created again, without the data of the deleted one
+	if err := st.CreateTurn(ctx, "game1", 2, "production"); err != nil {
+		t.Fatalf("failed to create the deleted turn again: %v", err)
+	}
+	if got, err := st.LoadSnapshot(ctx, "game1", 2); err != nil || len(got) != 0 {
+		t.Errorf("expected an empty snapshot, got %v, %v", got, err)
+	}
+	if got, err := st.GetOrders(ctx, "game1", 2, "SP01"); err != nil || len(got) != 0 {
+		t.Errorf("expected no orders, got %v, %v", got, err)
+	}
+}
+
+func testSnapshotRoundTrip(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1)
+
+	want := []store.Entity{
+		{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Earth","population":1000}`)},
+		{ID: "ship-1", Kind: "ship", Data: []byte(`{"name":"Enterprise","tonnage":5000}`)},
+		{ID: "species-1", Kind: "species", Data: []byte(`{"name":"Humans","tech_level":10}`)},
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 1, want); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	got, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	compareEntities(t, got, want)
+}
+
+func testSnapshotHTMLCharacters(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1)
+
+	want := []store.Entity{
+		{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"A<B&C>D"}`)},
+		{ID: "planet-2", Kind: "planet", Data: []byte(`{"name":"\u003c\u0026\u003e"}`)},
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 1, want); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	got, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	compareEntities(t, got, want)
+}
+
+func testSnapshotReplace(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1)
+
+	first := []store.Entity{
+		{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Earth"}`)},
+		{ID: "planet-2", Kind: "planet", Data: []byte(`{"name":"Mars"}`)},
+	}
+	second := []store.Entity{
+		{ID: "planet-2", Kind: "planet", Data: []byte(`{"name":"Ares"}`)},
+		{ID: "ship-1", Kind: "ship", Data: []byte(`{"name":"Enterprise"}`)},
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 1, first); err != nil {
+		t.Fatalf("failed to save first snapshot: %v", err)
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 1, second); err != nil {
+		t.Fatalf("failed to save second snapshot: %v", err)
+	}
+	got, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	compareEntities(t, got, second)
+
+	if err := st.SaveSnapshot(ctx, "game1", 1, nil); err != nil {
+		t.Fatalf("failed to save empty snapshot: %v", err)
+	}
+	got, err = st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load empty snapshot: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected empty snapshot, got %d entities", len(got))
+	}
+}
+
+func testSnapshotPerTurn(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1, 2)
+
+	turn1 := []store.Entity{{ID: "planet-1", Kind: "planet", Data: []byte(`{"turn":1}`)}}
+	turn2 := []store.Entity{{ID: "planet-1", Kind: "planet", Data: []byte(`{"turn":2}`)}}
+	if err := st.SaveSnapshot(ctx, "game1", 1, turn1); err != nil {
+		t.Fatalf("failed to save turn 1 snapshot: %v", err)
+	}
+	if err := st.SaveSnapshot(ctx, "game1", 2, turn2); err != nil {
+		t.Fatalf("failed to save turn 2 snapshot: %v", err)
+	}
+	got, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load turn 1 snapshot: %v", err)
+	}
+	compareEntities(t, got, turn1)
+}
+
+func testOrdersReplacePerActor(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1)
+
+	sp01 := []store.Order{
+		{Seq: 2, Raw: "BUILD 10 IU", Status: "pending"},
+		{Seq: 1, Raw: "JUMP TR1 Alpha, 1 2 3", Normalized: `{"cmd":"jump"}`, Status: "valid"},
+	}
+	sp02 := []store.Order{
+		{Seq: 1, Raw: "SCAN", Status: "error", Error: "no ship"},
+	}
+	if err := st.SaveOrders(ctx, "game1", 1, "SP01", sp01); err != nil {
+		t.Fatalf("failed to save SP01 orders: %v", err)
+	}
+	if err := st.SaveOrders(ctx, "game1", 1, "SP02", sp02); err != nil {
+		t.Fatalf("failed to save SP02 orders: %v", err)
+	}
+
+	got, err := st.GetOrders(ctx, "game1", 1, "SP01")
+	if err != nil {
+		t.Fatalf("failed to get SP01 orders: %v", err)
+	}
+	compareOrders(t, got, []store.Order{sp01[1], sp01[0]})
+
+	replacement := []store.Order{{Seq: 1, Raw: "PRODUCTION PL Earth", Status: "pending"}}
+	if err := st.SaveOrders(ctx, "game1", 1, "SP01", replacement); err != nil {
+		t.Fatalf("failed to replace SP01 orders: %v", err)
+	}
+	got, err = st.GetOrders(ctx, "game1", 1, "SP01")
+	if err != nil {
+		t.Fatalf("failed to get SP01 orders: %v", err)
+	}
+	compareOrders(t, got, replacement)
+
+	got, err = st.GetOrders(ctx, "game1", 1, "SP02")
+	if err != nil {
+		t.Fatalf("failed to get SP02 orders: %v", err)
+	}
+	compareOrders(t, got, sp02)
+}
+
+func testReportOverwriteByMime(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1)
+
+	save := func(actor, mime, body string) {
+		t.Helper()
+		if err := st.SaveReport(ctx, "game1", 1, actor, mime, strings.NewReader(body)); err != nil {
+			t.Fatalf("failed to save %s %s report: %v", actor, mime, err)
+		}
+	}
+	save("SP01", "text/plain", "first")
+	save("SP01", "text/html", "<p>html</p>")
+	save("SP02", "text/plain", "other")
+	save("SP01", "text/plain", "second")
+
+	for _, tc := range []struct{ actor, mime, want string }{
+		{"SP01", "text/plain", "second"},
+		{"SP01", "text/html", "<p>html</p>"},
+		{"SP02", "text/plain", "other"},
+	} {
+		rc, err := st.GetReport(ctx, "game1", 1, tc.actor, tc.mime)
+		if err != nil {
+			t.Fatalf("failed to get %s %s report: %v", tc.actor, tc.mime, err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s %s report: %v", tc.actor, tc.mime, err)
+		}
+		if string(body) != tc.want {
+			t.Errorf("%s %s: expected %q, got %q", tc.actor, tc.mime, tc.want, body)
+		}
+	}
+}
+
+// rngStateStore returns the store as a store.RNGStateStore, or skips the
+// test if the store does not keep RNG checkpoints.
+func rngStateStore(t *testing.T, st store.Store) store.RNGStateStore {
+	t.Helper()
+	rs, ok := st.(store.RNGStateStore)
+	if !ok {
+		t.Skip("store does not implement store.RNGStateStore")
+	}
+	return rs
+}
+
+func testRNGStateReplace(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	rs := rngStateStore(t, st)
+	setupTurns(t, st, 1, 2)
+
+	states, err := rs.LoadRNGState(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load rng state: %v", err)
+	}
+	if len(states) != 0 {
+		t.Errorf("expected no rng state on a new turn, got %v", states)
+	}
+
+	first := map[string][]byte{"combat": {'X', 1, 2, 3}, "jump": {'X', 4, 5, 6}}
+	if err := rs.SaveRNGState(ctx, "game1", 1, first); err != nil {
+		t.Fatalf("failed to save rng state: %v", err)
+	}
+	if err := rs.SaveRNGState(ctx, "game1", 2, map[string][]byte{"combat": {'M', 0}}); err != nil {
+		t.Fatalf("failed to save rng state: %v", err)
+	}
+	states, err = rs.LoadRNGState(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load rng state: %v", err)
+	}
+	compareStates(t, states, first)
+
+	// changing the saved or returned maps does not change the store
+	first["combat"][1] = 9
+	states["jump"][1] = 9
+	states, _ = rs.LoadRNGState(ctx, "game1", 1)
+	compareStates(t, states, map[string][]byte{"combat": {'X', 1, 2, 3}, "jump": {'X', 4, 5, 6}})
+
+	second := map[string][]byte{"jump": {'X', 7}}
+	if err := rs.SaveRNGState(ctx, "game1", 1, second); err != nil {
+		t.Fatalf("failed to replace rng state: %v", err)
+	}
+	states, err = rs.LoadRNGState(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load rng state: %v", err)
+	}
+	compareStates(t, states, second)
+
+	if err := rs.SaveRNGState(ctx, "game1", 1, nil); err != nil {
+		t.Fatalf("failed to clear rng state: %v", err)
+	}
+	if states, err := rs.LoadRNGState(ctx, "game1", 1); err != nil || len(states) != 0 {
+		t.Errorf("expected no rng state after clearing, got %v, %v", states, err)
+	}
+	states, _ = rs.LoadRNGState(ctx, "game1", 2)
+	compareStates(t, states, map[string][]byte{"combat": {'M', 0}})
+}
+
+// rngTraceStore returns the store as a store.RNGTraceStore, or skips the
+// test if the store does not keep RNG traces.
+func rngTraceStore(t *testing.T, st store.Store) store.RNGTraceStore {
+	t.Helper()
+	ts, ok := st.(store.RNGTraceStore)
+	if !ok {
+		t.Skip("store does not implement store.RNGTraceStore")
+	}
+	return ts
+}
+
+func testRNGTracePerPhase(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	ts := rngTraceStore(t, st)
+	setupTurns(t, st, 1, 2)
+
+	traces, err := ts.LoadRNGTraces(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load rng traces: %v", err)
+	}
+	if len(traces) != 0 {
+		t.Errorf("expected no rng traces on a new turn, got %v", traces)
+	}
+
+	saves := []struct {
+		turn  int
+		phase string
+		trace string
+	}{
+		{1, "combat", `{"draws":[1]}`},
+		{1, "jump", `{"draws":[2]}`},
+		{1, "combat", `{"draws":[3]}`},
+		{1, "post-arrival/strike", `{"draws":[4]}`},
+		{2, "combat", `{"draws":[5]}`},
+	}
+	for _, sv := range saves {
+		if err := ts.SaveRNGTrace(ctx, "game1", sv.turn, sv.phase, []byte(sv.trace)); err != nil {
+			t.Fatalf("failed to save rng trace %d %s: %v", sv.turn, sv.phase, err)
+		}
+	}
+
+	traces, err = ts.LoadRNGTraces(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load rng traces: %v", err)
+	}
+	compareStates(t, traces, map[string][]byte{
+		"combat":              []byte(`{"draws":[3]}`),
+		"jump":                []byte(`{"draws":[2]}`),
+		"post-arrival/strike": []byte(`{"draws":[4]}`),
+	})
+	traces, _ = ts.LoadRNGTraces(ctx, "game1", 2)
+	compareStates(t, traces, map[string][]byte{"combat": []byte(`{"draws":[5]}`)})
+}
+
+func testNotFound(t *testing.T, st store.Store) {
+	ctx := context.Background()
+
+	// wantGame checks for cerrs.ErrGameNotFound with the given key.
+	wantGame := func(method string, err error, gameID string) {
+		t.Helper()
+		var e *cerrs.ErrGameNotFound
+		if !errors.As(err, &e) || !errors.Is(err, cerrs.ErrNotExist) {
+			t.Errorf("%s: expected ErrGameNotFound, got %v", method, err)
+		} else if e.GameID != gameID {
+			t.Errorf("%s: expected GameID %q, got %q", method, gameID, e.GameID)
+		}
+	}
+	// wantTurn checks for cerrs.ErrTurnNotFound with the given key.
+	wantTurn := func(method string, err error, gameID string, turnNum int) {
+		t.Helper()
+		var e *cerrs.ErrTurnNotFound
+		if !errors.As(err, &e) || !errors.Is(err, cerrs.ErrNotExist) {
+			t.Errorf("%s: expected ErrTurnNotFound, got %v", method, err)
+		} else if e.GameID != gameID || e.Turn != turnNum {
+			t.Errorf("%s: expected game %q turn %d, got game %q turn %d", method, gameID, turnNum, e.GameID, e.Turn)
+		}
+	}
+
+	_, err := st.GetGame(ctx, "nogame")
+	wantGame("GetGame", err, "nogame")
+	if ms, ok := st.(store.MetaStore); ok {
+		_, err = ms.GetGameMeta(ctx, "nogame")
+		wantGame("GetGameMeta", err, "nogame")
+		wantGame("SetGameMeta", ms.SetGameMeta(ctx, "nogame", "rng", "keyed"), "nogame")
+	}
+	_, err = st.GetCurrentTurn(ctx, "nogame")
+	wantGame("GetCurrentTurn", err, "nogame")
+	wantGame("CreateTurn", st.CreateTurn(ctx, "nogame", 1, "production"), "nogame")
+	wantGame("DeleteTurn", st.DeleteTurn(ctx, "nogame", 1), "nogame")
+	_, err = st.LoadSnapshot(ctx, "nogame", 1)
+	wantGame("LoadSnapshot", err, "nogame")
+	_, err = st.GetOrders(ctx, "nogame", 1, "SP01")
+	wantGame("GetOrders", err, "nogame")
+	_, err = st.GetReport(ctx, "nogame", 1, "SP01", "text/plain")
+	wantGame("GetReport", err, "nogame")
+	if rs, ok := st.(store.RNGStateStore); ok {
+		_, err = rs.LoadRNGState(ctx, "nogame", 1)
+		wantGame("LoadRNGState", err, "nogame")
+	}
+	if ts, ok := st.(store.RNGTraceStore); ok {
+		_, err = ts.LoadRNGTraces(ctx, "nogame", 1)
+		wantGame("LoadRNGTraces", err, "nogame")
+	}
+
+	setupTurns(t, st)
+	_, err = st.GetCurrentTurn(ctx, "game1")
+	wantTurn("GetCurrentTurn", err, "game1", -1)
+
+	if err := st.CreateTurn(ctx, "game1", 1, "production"); err != nil {
+		t.Fatalf("failed to create turn: %v", err)
+	}
+	_, err = st.LoadSnapshot(ctx, "game1", 2)
+	wantTurn("LoadSnapshot", err, "game1", 2)
+	_, err = st.GetOrders(ctx, "game1", 2, "SP01")
+	wantTurn("GetOrders", err, "game1", 2)
+	_, err = st.GetReport(ctx, "game1", 2, "SP01", "text/plain")
+	wantTurn("GetReport", err, "game1", 2)
+	wantTurn("SaveSnapshot", st.SaveSnapshot(ctx, "game1", 2, nil), "game1", 2)
+	wantTurn("DeleteTurn", st.DeleteTurn(ctx, "game1", 2), "game1", 2)
+	wantTurn("SaveOrders", st.SaveOrders(ctx, "game1", 2, "SP01", nil), "game1", 2)
+	wantTurn("SaveReport", st.SaveReport(ctx, "game1", 2, "SP01", "text/plain", strings.NewReader("x")), "game1", 2)
+	if rs, ok := st.(store.RNGStateStore); ok {
+		_, err = rs.LoadRNGState(ctx, "game1", 2)
+		wantTurn("LoadRNGState", err, "game1", 2)
+		wantTurn("SaveRNGState", rs.SaveRNGState(ctx, "game1", 2, nil), "game1", 2)
+	}
+	if ts, ok := st.(store.RNGTraceStore); ok {
+		_, err = ts.LoadRNGTraces(ctx, "game1", 2)
+		wantTurn("LoadRNGTraces", err, "game1", 2)
+		wantTurn("SaveRNGTrace", ts.SaveRNGTrace(ctx, "game1", 2, "combat", []byte("{}")), "game1", 2)
+	}
+
+	// an existing turn with nothing saved is empty, not missing
+	if entities, err := st.LoadSnapshot(ctx, "game1", 1); err != nil || len(entities) != 0 {
+		t.Errorf("LoadSnapshot: expected empty snapshot, got %d entities, %v", len(entities), err)
+	}
+	if orders, err := st.GetOrders(ctx, "game1", 1, "SP01"); err != nil || len(orders) != 0 {
+		t.Errorf("GetOrders: expected no orders, got %d orders, %v", len(orders), err)
+	}
+
+	_, err = st.GetReport(ctx, "game1", 1, "SP01", "text/plain")
+	var e *cerrs.ErrReportNotFound
+	if !errors.As(err, &e) || !errors.Is(err, cerrs.ErrNotExist) {
+		t.Errorf("GetReport: expected ErrReportNotFound, got %v", err)
+	} else if e.GameID != "game1" || e.Turn != 1 || e.Actor != "SP01" || e.Mime != "text/plain" {
+		t.Errorf("GetReport: unexpected key %+v", *e)
+	}
+}
+
+func testInvalidNames(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	setupTurns(t, st, 1)
+
+	want := []store.Entity{{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Earth"}`)}}
+	if err := st.SaveSnapshot(ctx, "game1", 1, want); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	// wantInvalid checks for cerrs.ErrInvalidName.
+	wantInvalid := func(method string, err error) {
+		t.Helper()
+		if !errors.Is(err, cerrs.ErrInvalidName) {
+			t.Errorf("%s: expected ErrInvalidName, got %v", method, err)
+		}
+	}
+	entities := []store.Entity{{ID: "planet-1", Kind: "planet", Data: []byte(`{"name":"Mars"}`)}}
+	for _, name := range []string{"", ".", ".."} {
+		wantInvalid("CreateGame", st.CreateGame(ctx, name, "Bad Game"))
+		_, err := st.GetGame(ctx, name)
+		wantInvalid("GetGame", err)
+		wantInvalid("CreateTurn", st.CreateTurn(ctx, name, 2, "production"))
+		wantInvalid("DeleteTurn", st.DeleteTurn(ctx, name, 1))
+		_, err = st.GetCurrentTurn(ctx, name)
+		wantInvalid("GetCurrentTurn", err)
+		wantInvalid("SaveSnapshot", st.SaveSnapshot(ctx, name, 1, entities))
+		_, err = st.LoadSnapshot(ctx, name, 1)
+		wantInvalid("LoadSnapshot", err)
+		wantInvalid("SaveOrders", st.SaveOrders(ctx, "game1", 1, name, []store.Order{{Seq: 1, Raw: "x", Status: "pending"}}))
+		_, err = st.GetOrders(ctx, "game1", 1, name)
+		wantInvalid("GetOrders", err)
+		wantInvalid("SaveReport actor", st.SaveReport(ctx, "game1", 1, name, "text/plain", strings.NewReader("x")))
+		wantInvalid("SaveReport mime", st.SaveReport(ctx, "game1", 1, "SP01", name, strings.NewReader("x")))
+		_, err = st.GetReport(ctx, "game1", 1, name, "text/plain")
+		wantInvalid("GetReport actor", err)
+		_, err = st.GetReport(ctx, "game1", 1, "SP01", name)
+		wantInvalid("GetReport mime", err)
+		if ms, ok := st.(store.MetaStore); ok {
+			wantInvalid("SetGameMeta", ms.SetGameMeta(ctx, name, "rng", "keyed"))
+			_, err = ms.GetGameMeta(ctx, name)
+			wantInvalid("GetGameMeta", err)
+		}
+		if rs, ok := st.(store.RNGStateStore); ok {
+			wantInvalid("SaveRNGState", rs.SaveRNGState(ctx, name, 1, map[string][]byte{"a": {1}}))
+			_, err = rs.LoadRNGState(ctx, name, 1)
+			wantInvalid("LoadRNGState", err)
+		}
+		if ts, ok := st.(store.RNGTraceStore); ok {
+			wantInvalid("SaveRNGTrace", ts.SaveRNGTrace(ctx, "game1", 1, name, []byte(`{}`)))
+			_, err = ts.LoadRNGTraces(ctx, name, 1)
+			wantInvalid("LoadRNGTraces", err)
+		}
+	}
+
+	got, err := st.LoadSnapshot(ctx, "game1", 1)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	compareEntities(t, got, want)
+}
+
+func testContextCanceled(t *testing.T, st store.Store) {
+	setupTurns(t, st, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	check := func(method string, err error) {
+		t.Helper()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", method, err)
+		}
+	}
+	check("CreateGame", st.CreateGame(ctx, "game2", "Other Game"))
+	_, err := st.GetGame(ctx, "game1")
+	check("GetGame", err)
+	if ms, ok := st.(store.MetaStore); ok {
+		check("SetGameMeta", ms.SetGameMeta(ctx, "game1", "rng", "keyed"))
+		_, err = ms.GetGameMeta(ctx, "game1")
+		check("GetGameMeta", err)
+	}
+	check("CreateTurn", st.CreateTurn(ctx, "game1", 2, "production"))
+	check("DeleteTurn", st.DeleteTurn(ctx, "game1", 1))
+	_, err = st.GetCurrentTurn(ctx, "game1")
+	check("GetCurrentTurn", err)
+	check("SaveSnapshot", st.SaveSnapshot(ctx, "game1", 1, []store.Entity{{ID: "a", Kind: "star", Data: []byte(`{}`)}}))
+	_, err = st.LoadSnapshot(ctx, "game1", 1)
+	check("LoadSnapshot", err)
+	check("SaveOrders", st.SaveOrders(ctx, "game1", 1, "SP01", []store.Order{{Seq: 1, Raw: "x", Status: "pending"}}))
+	_, err = st.GetOrders(ctx, "game1", 1, "SP01")
+	check("GetOrders", err)
+	check("SaveReport", st.SaveReport(ctx, "game1", 1, "SP01", "text/plain", strings.NewReader("x")))
+	_, err = st.GetReport(ctx, "game1", 1, "SP01", "text/plain")
+	check("GetReport", err)
+	if rs, ok := st.(store.RNGStateStore); ok {
+		check("SaveRNGState", rs.SaveRNGState(ctx, "game1", 1, map[string][]byte{"a": {1}}))
+		_, err = rs.LoadRNGState(ctx, "game1", 1)
+		check("LoadRNGState", err)
+	}
+	if ts, ok := st.(store.RNGTraceStore); ok {
+		check("SaveRNGTrace", ts.SaveRNGTrace(ctx, "game1", 1, "combat", []byte("{}")))
+		_, err = ts.LoadRNGTraces(ctx, "game1", 1)
+		check("LoadRNGTraces", err)
+	}
+
+	// nothing may have been written with the canceled context
+	bg := context.Background()
+	if _, err := st.GetGame(bg, "game2"); err == nil {
+		t.Error("CreateGame wrote a game with a canceled context")
+	}
+	if entities, _ := st.LoadSnapshot(bg, "game1", 1); len(entities) != 0 {
+		t.Error("SaveSnapshot wrote entities with a canceled context")
+	}
+}
+
+func testSchemaVersion(t *testing.T, st store.Store) {
+	ctx := context.Background()
+	version, err := st.GetSchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if version == "" {
+		t.Error("expected a schema version on a new store")
+	}
+	if err := st.UpgradeSchema(ctx); err != nil {
+		t.Fatalf("failed to upgrade current schema: %v", err)
+	}
+	again, err := st.GetSchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if again != version {
+		t.Errorf("upgrade of current schema changed version from %q to %q", version, again)
+	}
+}
+
+// compareStates compares RNG states or traces.
+func compareStates(t *testing.T, got, want map[string][]byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d rng states, got %d", len(want), len(got))
+	}
+	for name, state := range want {
+		if !bytes.Equal(got[name], state) {
+			t.Errorf("rng state %q: expected %v, got %v", name, state, got[name])
+		}
+	}
+}
+
+// compareEntities compares snapshots without regard to order.
+func compareEntities(t *testing.T, got, want []store.Entity) {
+	t.Helper()
+	got = append([]store.Entity(nil), got...)
+	want = append([]store.Entity(nil), want...)
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	sort.Slice(want, func(i, j int) bool { return want[i].ID < want[j].ID })
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entities, got %d", len(want), len(got))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID {
+			t.Errorf("entity %d: expected ID %q, got %q", i, want[i].ID, got[i].ID)
+		}
+		if got[i].Kind != want[i].Kind {
+			t.Errorf("entity %d: expected Kind %q, got %q", i, want[i].Kind, got[i].Kind)
+		}
+		if !bytes.Equal(got[i].Data, want[i].Data) {
+			t.Errorf("entity %d: expected Data %q, got %q", i, want[i].Data, got[i].Data)
+		}
+	}
+}
+
+// compareOrders compares orders, which must be returned in Seq order.
+func compareOrders(t *testing.T, got, want []store.Order) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d orders, got %d", len(want), len(got))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("order %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
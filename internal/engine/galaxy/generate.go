@@ -0,0 +1,152 @@
+package galaxy
+
+import (
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/rng/dice"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Natural wormholes.
+const (
+	wormholeChance    = 8  // percent of stars with a wormhole
+	minWormholeLength = 20 // parsecs, limited by the galaxy diameter
+)
+
+// Galaxy is a generated galaxy.
+type Galaxy struct {
+	Galaxy  *world.Galaxy
+	Stars   []*world.Star   // in the order the C game numbers them
+	Planets []*world.Planet // grouped by star, in orbit order
+}
+
+// Entities returns the galaxy, stars and planets as world entities.
+func (g *Galaxy) Entities() []world.Entity {
+	list := make([]world.Entity, 0, 1+len(g.Stars)+len(g.Planets))
+	list = append(list, g.Galaxy)
+	for _, s := range g.Stars {
+		list = append(list, s)
+	}
+	for _, p := range g.Planets {
+		list = append(list, p)
+	}
+	return list
+}
+
+// NumWormholes returns the number of natural wormholes.
+// Each wormhole connects two stars.
+func (g *Galaxy) NumWormholes() int {
+	n := 0
+	for _, s := range g.Stars {
+		if s.Wormhole {
+			n++
+		}
+	}
+	return n / 2
+}
+
+// Generate creates a galaxy.
+// All random draws come from the factory, so the same parameters and seed
+// always produce the same galaxy. Placement and wormholes each have their
+// own stream and every star draws from a stream keyed by its ID.
+func Generate(p Params, f rng.Factory) (*Galaxy, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	g := &Galaxy{
+		Galaxy: &world.Galaxy{
+			EntityID:    ids.Galaxy(),
+			DNumSpecies: p.Species,
+			Radius:      p.Radius,
+		},
+	}
+
+	// Place the stars. There is at most one star in each x, y column.
+	diameter := 2 * p.Radius
+	starHere := make([][]int, diameter)
+	for x := range starHere {
+		starHere[x] = make([]int, diameter)
+		for y := range starHere[x] {
+			starHere[x][y] = -1
+		}
+	}
+	r := f.For("galaxy", "stars")
+	for numStars := 0; numStars < p.Stars; {
+		x, y, z := dice.Rnd(r, diameter)-1, dice.Rnd(r, diameter)-1, dice.Rnd(r, diameter)-1
+		rx, ry, rz := x-p.Radius, y-p.Radius, z-p.Radius
+		if rx*rx+ry*ry+rz*rz >= p.Radius*p.Radius {
+			continue
+		}
+		if starHere[x][y] == -1 {
+			starHere[x][y] = z
+			numStars++
+		}
+	}
+
+	for x := 0; x < diameter; x++ {
+		for y := 0; y < diameter; y++ {
+			if starHere[x][y] == -1 {
+				continue
+			}
+			star, planets := generateStar(f, world.Coords{X: x, Y: y, Z: starHere[x][y]})
+			g.Stars = append(g.Stars, star)
+			g.Planets = append(g.Planets, planets...)
+		}
+	}
+
+	addWormholes(g.Stars, min(minWormholeLength, diameter/2), f.For("galaxy", "wormholes"))
+	return g, nil
+}
+
+// generateStar rolls the type, color and size of a star and creates its planets.
+func generateStar(f rng.Factory, c world.Coords) (*world.Star, []*world.Planet) {
+	star := &world.Star{EntityID: ids.Star(c.X, c.Y, c.Z), Coords: c}
+	r := f.For("galaxy", string(star.EntityID))
+
+	// Main sequence is the most common type.
+	star.Type = world.StarType(dice.Rnd(r, int(world.GiantStar)+6))
+	if star.Type > world.GiantStar {
+		star.Type = world.MainSequenceStar
+	}
+	star.Color = world.StarColor(dice.Rnd(r, int(world.RedStar)))
+	star.Size = dice.Rnd(r, 10) - 1
+
+	// Larger types tend to have more planets.
+	numPlanets := -2
+	for i := 0; i < 3; i++ {
+		numPlanets += dice.Rnd(r, int(star.Type)+1)
+	}
+	numPlanets = min(max(numPlanets, 1), 9)
+
+	planets := generatePlanets(r, star, numPlanets, false)
+	return star, planets
+}
+
+// addWormholes connects pairs of stars with natural wormholes.
+// Home systems never get a wormhole.
+func addWormholes(stars []*world.Star, minLength int, r rng.Scoped) {
+	var candidates []*world.Star
+	for _, s := range stars {
+		if !s.HomeSystem {
+			candidates = append(candidates, s)
+		}
+	}
+	for _, s := range candidates {
+		if s.Wormhole || dice.Rnd(r, 100) > wormholeChance {
+			continue
+		}
+		// Pick the other end from the stars that are far enough away.
+		var ends []*world.Star
+		for _, t := range candidates {
+			if t != s && !t.Wormhole && t.DistanceSquared(s.Coords) >= minLength*minLength {
+				ends = append(ends, t)
+			}
+		}
+		if len(ends) == 0 {
+			continue
+		}
+		t := ends[dice.Rnd(r, len(ends))-1]
+		s.Wormhole, s.WormholeExit = true, t.Coords
+		t.Wormhole, t.WormholeExit = true, s.Coords
+	}
+}
@@ -0,0 +1,179 @@
+package galaxy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/world"
+)
+
+func TestNewParams(t *testing.T) {
+	tests := []struct {
+		name                 string
+		species, stars, rad  int
+		lessCrowded, wantErr bool
+		want                 Params
+	}{
+		{name: "standard", species: 15, want: Params{Species: 15, Stars: 90, Radius: 20}},
+		{name: "less crowded", species: 18, lessCrowded: true, want: Params{Species: 18, Stars: 162, Radius: 25}},
+		{name: "minimum stars", species: 1, want: Params{Species: 1, Stars: MinStars, Radius: 11}},
+		{name: "given values", species: 10, stars: 100, rad: 25, want: Params{Species: 10, Stars: 100, Radius: 25}},
+		{name: "too many species", species: 101, wantErr: true},
+		{name: "stars and less crowded", species: 10, stars: 100, lessCrowded: true, wantErr: true},
+		{name: "too crowded", species: 10, stars: 500, rad: 10, wantErr: true},
+		{name: "too sparse", species: 10, stars: 12, rad: 50, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParams(tt.species, tt.stars, tt.rad, tt.lessCrowded)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewParams() expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewParams() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NewParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateReproducible(t *testing.T) {
+	p := Params{Species: 15, Stars: 90, Radius: 20}
+	a, err := Generate(p, rng.NewFactory([]byte("seed")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Generate(p, rng.NewFactory([]byte("seed")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed produced different galaxies")
+	}
+	c, err := Generate(p, rng.NewFactory([]byte("other seed")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(a.Stars, c.Stars) {
+		t.Errorf("different seeds produced the same galaxy")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	p := Params{Species: 30, Stars: 270, Radius: 29}
+	g, err := Generate(p, rng.NewFactory([]byte("test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Stars) != p.Stars {
+		t.Fatalf("generated %d stars, want %d", len(g.Stars), p.Stars)
+	}
+	if g.Galaxy.DNumSpecies != 30 || g.Galaxy.Radius != 29 {
+		t.Errorf("galaxy = %+v", g.Galaxy)
+	}
+
+	columns := map[[2]int]bool{}
+	planets := map[world.ID]*world.Planet{}
+	for _, pl := range g.Planets {
+		planets[pl.EntityID] = pl
+	}
+	for _, s := range g.Stars {
+		rx, ry, rz := s.X-p.Radius, s.Y-p.Radius, s.Z-p.Radius
+		if rx*rx+ry*ry+rz*rz >= p.Radius*p.Radius {
+			t.Errorf("%s is outside the galaxy", s.EntityID)
+		}
+		if columns[[2]int{s.X, s.Y}] {
+			t.Errorf("%s shares an x, y column", s.EntityID)
+		}
+		columns[[2]int{s.X, s.Y}] = true
+		if s.Type < world.DwarfStar || s.Type > world.GiantStar || s.Color < world.BlueStar || s.Color > world.RedStar || s.Size < 0 || s.Size > 9 {
+			t.Errorf("%s has type %d color %d size %d", s.EntityID, s.Type, s.Color, s.Size)
+		}
+		if len(s.Planets) < 1 || len(s.Planets) > 9 {
+			t.Errorf("%s has %d planets", s.EntityID, len(s.Planets))
+		}
+		for i, id := range s.Planets {
+			pl, ok := planets[id]
+			if !ok || pl.Orbit != i+1 || pl.Coords != s.Coords || pl.StarID != s.EntityID {
+				t.Errorf("%s: bad planet %s", s.EntityID, id)
+			}
+		}
+		if s.Wormhole {
+			exit := findStar(g, s.WormholeExit)
+			if exit == nil || !exit.Wormhole || exit.WormholeExit != s.Coords {
+				t.Errorf("%s: wormhole to %v is not paired", s.EntityID, s.WormholeExit)
+			}
+		}
+	}
+
+	for _, pl := range g.Planets {
+		if pl.Diameter < 3 || pl.TemperatureClass < 1 || pl.TemperatureClass > 30 || pl.PressureClass < 0 || pl.PressureClass > 29 {
+			t.Errorf("%s: diameter %d temperature %d pressure %d", pl.EntityID, pl.Diameter, pl.TemperatureClass, pl.PressureClass)
+		}
+		if pl.MiningDifficulty < 88 || pl.MiningDifficulty > 1100 {
+			t.Errorf("%s: mining difficulty %d", pl.EntityID, pl.MiningDifficulty)
+		}
+		if pl.PressureClass == 0 && len(pl.Gases) != 0 {
+			t.Errorf("%s: gases without an atmosphere", pl.EntityID)
+		}
+		if len(pl.Gases) > 4 {
+			t.Errorf("%s: %d gases", pl.EntityID, len(pl.Gases))
+		}
+		if len(pl.Gases) != 0 {
+			total := 0
+			for _, gp := range pl.Gases {
+				total += gp.Percent
+			}
+			if total != 100 {
+				t.Errorf("%s: gases total %d%%", pl.EntityID, total)
+			}
+		}
+	}
+
+	// every entity has a canonical id and round trips through the codec
+	list, err := world.EncodeAll(g.Entities())
+	if err != nil {
+		t.Fatalf("EncodeAll() error = %v", err)
+	}
+	if _, err := world.DecodeAll(list); err != nil {
+		t.Fatalf("DecodeAll() error = %v", err)
+	}
+	if _, err := world.NewState(g.Entities()); err != nil {
+		t.Fatalf("NewState() error = %v", err)
+	}
+}
+
+func TestEarthLike(t *testing.T) {
+	f := rng.NewFactory([]byte("test"))
+	for i := 0; i < 50; i++ {
+		star := &world.Star{EntityID: "STAR:1,2,3", Coords: world.Coords{X: 1, Y: 2, Z: 3}}
+		planets := generatePlanets(f.For("earth", string(rune('a'+i))), star, 1+i%9, true)
+		homes := 0
+		for _, p := range planets {
+			if p.Special == world.IdealHomePlanet {
+				homes++
+				if p.GasPercent(world.O2) < 11 || p.GasPercent(world.O2) > 30 || p.PressureClass == 0 {
+					t.Errorf("earth-like planet has a bad atmosphere: %+v", p)
+				}
+			}
+		}
+		if homes != 1 {
+			t.Errorf("%d planets: got %d earth-like planets, want 1", len(planets), homes)
+		}
+	}
+}
+
+func findStar(g *Galaxy, c world.Coords) *world.Star {
+	for _, s := range g.Stars {
+		if s.Coords == c {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package galaxy
+
+import (
+	"strconv"
+
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Home systems have between MinHomePlanets and MaxHomePlanets planets.
+// Systems with fewer planets can't be home systems.
+const (
+	MinHomePlanets = 3
+	MaxHomePlanets = 9
+)
+
+// The potential of a balanced home system is in this range.
+const (
+	minHomePotential = 20
+	maxHomePotential = 25
+)
+
+// GenerateHomeSystemTemplates creates one balanced template for each number
+// of planets a home system can have. Each template draws from its own
+// stream, keyed by its ID.
+func GenerateHomeSystemTemplates(f rng.Factory) []*world.HomeSystemTemplate {
+	var templates []*world.HomeSystemTemplate
+	for n := MinHomePlanets; n <= MaxHomePlanets; n++ {
+		templates = append(templates, generateHomeSystemTemplate(f.For("home-system", strconv.Itoa(n)), n))
+	}
+	return templates
+}
+
+// generateHomeSystemTemplate generates systems with an earth-like planet
+// until one has a potential in the balanced range.
+func generateHomeSystemTemplate(r rng.Scoped, numPlanets int) *world.HomeSystemTemplate {
+	t := &world.HomeSystemTemplate{EntityID: ids.HomeSystem(numPlanets), NumPlanets: numPlanets}
+	for {
+		planets := generatePlanets(r, &world.Star{}, numPlanets, true)
+		t.Planets = make([]world.Planet, len(planets))
+		for i, p := range planets {
+			t.Planets[i] = *p
+			t.Planets[i].EntityID, t.Planets[i].StarID = "", ""
+		}
+		t.Potential = HomeSystemPotential(t)
+		if minHomePotential <= t.Potential && t.Potential <= maxHomePotential {
+			return t
+		}
+	}
+}
+
+// HomeSystemPotential rates how easily a species can colonize the other
+// planets of its home system. Each planet contributes more when it needs
+// less life support and is easier to mine.
+func HomeSystemPotential(t *world.HomeSystemTemplate) int {
+	home := t.HomePlanet()
+	if home == nil {
+		return 0
+	}
+	potential := 0
+	for i := range t.Planets {
+		p := &t.Planets[i]
+		if p == home {
+			continue
+		}
+		potential += 20000 / ((LifeSupportNeeded(home, p) + 3) * (50 + p.MiningDifficulty))
+	}
+	return potential
+}
+
+// LifeSupportNeeded returns the life support a species from the home planet
+// needs to live on the planet, as a tech level. It is 3 for each step of
+// temperature and pressure class, plus 3 if the planet has no oxygen.
+// Poison gases are not counted because they depend on the species.
+func LifeSupportNeeded(home, p *world.Planet) int {
+	lsn := 3*abs(p.TemperatureClass-home.TemperatureClass) + 3*abs(p.PressureClass-home.PressureClass)
+	if p.GasPercent(world.O2) == 0 {
+		lsn += 3
+	}
+	return lsn
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
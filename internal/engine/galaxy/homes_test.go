@@ -0,0 +1,79 @@
+package galaxy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+func TestGenerateHomeSystemTemplates(t *testing.T) {
+	templates := GenerateHomeSystemTemplates(rng.NewFactory([]byte("test")))
+	if len(templates) != MaxHomePlanets-MinHomePlanets+1 {
+		t.Fatalf("got %d templates", len(templates))
+	}
+	for i, tmpl := range templates {
+		n := MinHomePlanets + i
+		if tmpl.NumPlanets != n || len(tmpl.Planets) != n || tmpl.EntityID != ids.HomeSystem(n) {
+			t.Errorf("template %d: id %s with %d planets", i, tmpl.EntityID, len(tmpl.Planets))
+			continue
+		}
+		homes := 0
+		for j, p := range tmpl.Planets {
+			if p.Orbit != j+1 || p.EntityID != "" || p.StarID != "" {
+				t.Errorf("%s: planet %d has orbit %d, id %q", tmpl.EntityID, j, p.Orbit, p.EntityID)
+			}
+			if p.Special == world.IdealHomePlanet {
+				homes++
+			}
+		}
+		if homes != 1 {
+			t.Errorf("%s: %d home planets", tmpl.EntityID, homes)
+		}
+		if tmpl.Potential < minHomePotential || tmpl.Potential > maxHomePotential || tmpl.Potential != HomeSystemPotential(tmpl) {
+			t.Errorf("%s: potential %d", tmpl.EntityID, tmpl.Potential)
+		}
+	}
+
+	again := GenerateHomeSystemTemplates(rng.NewFactory([]byte("test")))
+	if !reflect.DeepEqual(templates, again) {
+		t.Errorf("same seed produced different templates")
+	}
+
+	// templates round trip through the codec
+	entities := make([]world.Entity, len(templates))
+	for i, tmpl := range templates {
+		entities[i] = tmpl
+	}
+	list, err := world.EncodeAll(entities)
+	if err != nil {
+		t.Fatalf("EncodeAll() error = %v", err)
+	}
+	decoded, err := world.DecodeAll(list)
+	if err != nil {
+		t.Fatalf("DecodeAll() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, entities) {
+		t.Errorf("templates did not round trip")
+	}
+}
+
+func TestLifeSupportNeeded(t *testing.T) {
+	home := &world.Planet{TemperatureClass: 10, PressureClass: 9, Gases: []world.GasPercent{{Gas: world.N2, Percent: 80}, {Gas: world.O2, Percent: 20}}}
+	tests := []struct {
+		p    world.Planet
+		want int
+	}{
+		{*home, 0},
+		{world.Planet{TemperatureClass: 12, PressureClass: 8, Gases: home.Gases}, 9},
+		{world.Planet{TemperatureClass: 10, PressureClass: 9}, 3},
+		{world.Planet{TemperatureClass: 30, PressureClass: 0}, 60 + 27 + 3},
+	}
+	for _, tt := range tests {
+		if got := LifeSupportNeeded(home, &tt.p); got != tt.want {
+			t.Errorf("LifeSupportNeeded(tc %d, pc %d) = %d, want %d", tt.p.TemperatureClass, tt.p.PressureClass, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package galaxy implements galaxy generation.
+package galaxy
+
+import "fmt"
+
+// Limits and reference values from the C game.
+// A standard game has 15 species in a galaxy of 90 stars with a radius of 20 parsecs.
+const (
+	MinSpecies = 1
+	MaxSpecies = 100
+	MinStars   = 12
+	MaxStars   = 1000
+	MinRadius  = 6
+	MaxRadius  = 50
+
+	StandardNumSpecies = 15
+	StandardNumStars   = 90
+	StandardRadius     = 20
+)
+
+// A star may be placed at one of every chanceOfStar cubic parsecs.
+// Galaxies outside this range are too crowded or too sparse to play.
+const (
+	minChanceOfStar = 50
+	maxChanceOfStar = 3200
+)
+
+// Params are the sizing parameters for a galaxy.
+type Params struct {
+	Species int // number of species the galaxy is designed for
+	Stars   int
+	Radius  int // in parsecs
+}
+
+// NumStars returns the number of stars for a galaxy designed for the
+// given number of species. A less crowded galaxy has about 50% more stars.
+func NumStars(species int, lessCrowded bool) int {
+	n := (species * StandardNumStars) / StandardNumSpecies
+	if lessCrowded {
+		n = (3 * n) / 2
+	}
+	return n
+}
+
+// RadiusFor returns the smallest radius that gives the stars the same
+// density as the standard galaxy.
+func RadiusFor(stars int) int {
+	volume := stars * StandardRadius * StandardRadius * StandardRadius / StandardNumStars
+	radius := MinRadius
+	for radius*radius*radius < volume {
+		radius++
+	}
+	return radius
+}
+
+// NewParams returns the parameters for a galaxy designed for the number of
+// species. Stars and radius are derived from the species count when zero;
+// a derived star count is never less than MinStars.
+func NewParams(species, stars, radius int, lessCrowded bool) (Params, error) {
+	if stars != 0 && lessCrowded {
+		return Params{}, fmt.Errorf("a less crowded galaxy can not have a fixed number of stars")
+	}
+	p := Params{Species: species, Stars: stars, Radius: radius}
+	if p.Stars == 0 {
+		p.Stars = max(NumStars(species, lessCrowded), MinStars)
+	}
+	if p.Radius == 0 {
+		p.Radius = RadiusFor(p.Stars)
+	}
+	return p, p.Validate()
+}
+
+// Volume returns the volume of the galaxy in cubic parsecs,
+// using the integer approximation of the C game.
+func (p Params) Volume() int {
+	return (4 * 314 * p.Radius * p.Radius * p.Radius) / 300
+}
+
+// ChanceOfStar returns the number of cubic parsecs per star.
+func (p Params) ChanceOfStar() int {
+	if p.Stars == 0 {
+		return 0
+	}
+	return p.Volume() / p.Stars
+}
+
+// Validate returns an error if the parameters are out of range
+// or if the stars would be too crowded or too sparse.
+func (p Params) Validate() error {
+	if p.Species < MinSpecies || p.Species > MaxSpecies {
+		return fmt.Errorf("species must be between %d and %d, got %d", MinSpecies, MaxSpecies, p.Species)
+	}
+	if p.Stars < MinStars || p.Stars > MaxStars {
+		return fmt.Errorf("stars must be between %d and %d, got %d", MinStars, MaxStars, p.Stars)
+	}
+	if p.Radius < MinRadius || p.Radius > MaxRadius {
+		return fmt.Errorf("radius must be between %d and %d, got %d", MinRadius, MaxRadius, p.Radius)
+	}
+	if chance := p.ChanceOfStar(); chance < minChanceOfStar {
+		return fmt.Errorf("too many stars (%d) for a radius of %d", p.Stars, p.Radius)
+	} else if chance > maxChanceOfStar {
+		return fmt.Errorf("too few stars (%d) for a radius of %d", p.Stars, p.Radius)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package galaxy
+
+import (
+	"sort"
+
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/rng/dice"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Planets start with the diameters (thousands of km) and temperature
+// classes of our own solar system, indexed by orbit.
+var (
+	startDiameter  = [10]int{0, 5, 12, 13, 7, 20, 143, 121, 51, 49}
+	startTempClass = [10]int{0, 29, 27, 11, 9, 8, 6, 5, 5, 3}
+)
+
+// generatePlanets creates the planets orbiting a star and sets star.Planets.
+// If earthLike is set, one planet is replaced with an ideal home planet:
+// the first with a temperature class of 11 or less, or else the last one.
+func generatePlanets(r rng.Scoped, star *world.Star, numPlanets int, earthLike bool) []*world.Planet {
+	planets := make([]*world.Planet, 0, numPlanets)
+	star.Planets = make([]world.ID, 0, numPlanets)
+	for orbit := 1; orbit <= numPlanets; orbit++ {
+		p := &world.Planet{
+			EntityID:       ids.Planet(star.X, star.Y, star.Z, orbit),
+			StarID:         star.EntityID,
+			Location:       world.Location{Coords: star.Coords, Orbit: orbit},
+			EconEfficiency: 100,
+		}
+
+		// Small systems are modeled on the odd orbits of the solar system.
+		n := orbit
+		if numPlanets <= 3 {
+			n = 2*orbit + 1
+		}
+		dia := randomize(r, startDiameter[n])
+		for dia < 3 {
+			dia += dice.Rnd(r, 4)
+		}
+
+		// Planets bigger than 40,000 km are gas giants.
+		gasGiant := dia > 40
+
+		// Density is times 100, from 60 to 170 for gas giants and from
+		// 370 to 570 for rocky planets. The factor 72 gives Earth
+		// (density 550, diameter 13) a gravity of 100.
+		var density int
+		if gasGiant {
+			density = 58 + dice.Rnd(r, 56) + dice.Rnd(r, 56)
+		} else {
+			density = 368 + dice.Rnd(r, 101) + dice.Rnd(r, 101)
+		}
+		grav := density * dia / 72
+
+		tc := randomize(r, startTempClass[n])
+		if gasGiant {
+			for tc < 3 {
+				tc += dice.Rnd(r, 2)
+			}
+			for tc > 7 {
+				tc -= dice.Rnd(r, 2)
+			}
+		} else {
+			for tc < 1 {
+				tc += dice.Rnd(r, 3)
+			}
+			for tc > 30 {
+				tc -= dice.Rnd(r, 3)
+			}
+		}
+
+		if earthLike && (tc <= 11 || orbit == numPlanets) {
+			earthLike = false
+			makeEarthLike(r, p)
+			planets = append(planets, p)
+			star.Planets = append(star.Planets, p.EntityID)
+			continue
+		}
+
+		// Pressure depends mostly on gravity.
+		pc := randomize(r, grav/10)
+		if gasGiant {
+			for pc < 11 {
+				pc += dice.Rnd(r, 3)
+			}
+			for pc > 29 {
+				pc -= dice.Rnd(r, 3)
+			}
+		} else {
+			for pc < 0 {
+				pc += dice.Rnd(r, 3)
+			}
+			for pc > 12 {
+				pc -= dice.Rnd(r, 3)
+			}
+		}
+		// Low gravity planets and very hot or cold ones have no atmosphere.
+		if grav < 10 || tc < 2 || tc > 27 {
+			pc = 0
+		}
+
+		p.Diameter, p.Gravity = dia, grav
+		p.TemperatureClass, p.PressureClass = tc, pc
+		if pc != 0 {
+			p.Gases = generateGases(r, tc)
+		}
+		p.MiningDifficulty = miningDifficulty(r, dia)
+
+		planets = append(planets, p)
+		star.Planets = append(star.Planets, p.EntityID)
+	}
+	return planets
+}
+
+// randomize adds or subtracts four rolls of a die a quarter the size of n.
+func randomize(r rng.Scoped, n int) int {
+	dieSize := max(n/4, 2)
+	for i := 0; i < 4; i++ {
+		roll := dice.Rnd(r, dieSize)
+		if dice.Rnd(r, 100) > 50 {
+			n += roll
+		} else {
+			n -= roll
+		}
+	}
+	return n
+}
+
+// generateGases creates an atmosphere for a planet with the temperature class.
+// Hotter planets have heavier gases.
+func generateGases(r rng.Scoped, tc int) []world.GasPercent {
+	firstGas := min(max(100*tc/225, 1), 9)
+	wanted := (dice.Rnd(r, 4) + dice.Rnd(r, 4)) / 2
+
+	var gases []world.GasPercent
+	quantity := 0
+	for len(gases) == 0 {
+		for g := firstGas; g <= firstGas+4 && len(gases) < wanted; g++ {
+			gas := world.Gas(g)
+			if gas == world.He {
+				// Helium is rare, and only found on cold planets.
+				if tc > 5 || dice.Rnd(r, 20) != 1 {
+					continue
+				}
+			} else if dice.Rnd(r, 4) != 1 {
+				continue
+			}
+			pct := dice.Rnd(r, 100)
+			if gas == world.He {
+				pct = dice.Rnd(r, 3)
+			}
+			gases = append(gases, world.GasPercent{Gas: gas, Percent: pct})
+			quantity += pct
+		}
+	}
+
+	// Convert quantities to percentages, giving any rounding to the first gas.
+	total := 0
+	for i := range gases {
+		gases[i].Percent = 100 * gases[i].Percent / quantity
+		total += gases[i].Percent
+	}
+	gases[0].Percent += 100 - total
+	return gases
+}
+
+// miningDifficulty returns the mining difficulty times 100, from 88 to 1100.
+// It grows with the diameter, with an occasional big surprise.
+func miningDifficulty(r rng.Scoped, dia int) int {
+	md := 0
+	for md < 40 || md > 500 {
+		md = (dice.Rnd(r, 3)+dice.Rnd(r, 3)+dice.Rnd(r, 3)-dice.Rnd(r, 4))*dice.Rnd(r, dia) + dice.Rnd(r, 30) + dice.Rnd(r, 30)
+	}
+	return md * 11 / 5
+}
+
+// makeEarthLike turns a planet into an ideal home planet.
+func makeEarthLike(r rng.Scoped, p *world.Planet) {
+	p.Special = world.IdealHomePlanet
+	p.Diameter = 11 + dice.Rnd(r, 3)
+	p.Gravity = 93 + dice.Rnd(r, 11) + dice.Rnd(r, 11) + dice.Rnd(r, 5)
+	p.TemperatureClass = 9 + dice.Rnd(r, 3)
+	p.PressureClass = 8 + dice.Rnd(r, 3)
+	p.MiningDifficulty = 208 + dice.Rnd(r, 11) + dice.Rnd(r, 11)
+
+	// Mostly nitrogen, with 11% to 30% oxygen and sometimes traces of
+	// ammonia or carbon dioxide.
+	o2 := 10 + dice.Rnd(r, 20)
+	n2 := 100 - o2
+	p.Gases = nil
+	if dice.Rnd(r, 3) == 1 {
+		nh3 := dice.Rnd(r, 5)
+		p.Gases = append(p.Gases, world.GasPercent{Gas: world.NH3, Percent: nh3})
+		n2 -= nh3
+	}
+	if dice.Rnd(r, 3) == 1 {
+		co2 := dice.Rnd(r, 5)
+		p.Gases = append(p.Gases, world.GasPercent{Gas: world.CO2, Percent: co2})
+		n2 -= co2
+	}
+	p.Gases = append(p.Gases,
+		world.GasPercent{Gas: world.N2, Percent: n2},
+		world.GasPercent{Gas: world.O2, Percent: o2},
+	)
+	sort.Slice(p.Gases, func(i, j int) bool { return p.Gases[i].Gas < p.Gases[j].Gas })
+}
@@ -0,0 +1,106 @@
+package galaxy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/playbymail/fh/internal/engine/world"
+)
+
+// Suggestion is a recommended galaxy size, with what to expect from it.
+type Suggestion struct {
+	Params
+	Recommended       Params // the values derived from the species count
+	ExpectedPlanets   int
+	ExpectedWormholes int
+	// Warnings explain why the stars or radius may not make a playable galaxy.
+	Warnings []string
+}
+
+// Suggest returns the recommended galaxy for the number of species.
+// Stars and radius override the recommendations when they are not zero;
+// the result then warns about densities the C game would reject or that
+// are far from the standard galaxy.
+func Suggest(species, stars, radius int, lessCrowded bool) (Suggestion, error) {
+	if species < MinSpecies || species > MaxSpecies {
+		return Suggestion{}, fmt.Errorf("species must be between %d and %d, got %d", MinSpecies, MaxSpecies, species)
+	}
+	var s Suggestion
+	s.Recommended.Species = species
+	s.Recommended.Stars = max(NumStars(species, lessCrowded), MinStars)
+	s.Recommended.Radius = RadiusFor(s.Recommended.Stars)
+
+	s.Params = s.Recommended
+	if stars != 0 {
+		s.Stars = stars
+		if radius == 0 {
+			s.Radius = RadiusFor(stars)
+		}
+		if lessCrowded {
+			s.warn("--less-crowded is not allowed with --stars")
+		}
+	}
+	if radius != 0 {
+		s.Radius = radius
+	}
+
+	s.ExpectedPlanets = int(math.Round(float64(s.Stars) * expectedPlanetsPerStar()))
+	// A wormhole joins two stars, and a star can only have one.
+	s.ExpectedWormholes = int(math.Round(float64(s.Stars) * wormholeChance / (100 + wormholeChance)))
+
+	if err := s.Params.Validate(); err != nil {
+		s.warn("%v", err)
+		return s, nil
+	}
+	standard := Params{Species: StandardNumSpecies, Stars: StandardNumStars, Radius: StandardRadius}.ChanceOfStar()
+	switch chance := s.ChanceOfStar(); {
+	case chance < standard/2:
+		s.warn("one star per %d cubic parsecs is more than twice as crowded as the standard galaxy (one per %d)", chance, standard)
+	case chance > standard*2:
+		s.warn("one star per %d cubic parsecs is less than half as crowded as the standard galaxy (one per %d)", chance, standard)
+	}
+	perSpecies := float64(s.Stars) / float64(species)
+	standardPerSpecies := float64(StandardNumStars) / StandardNumSpecies
+	switch {
+	case perSpecies < standardPerSpecies/2:
+		s.warn("%.1f stars per species is less than half the standard %.0f; species will meet very early", perSpecies, standardPerSpecies)
+	case perSpecies > standardPerSpecies*2:
+		s.warn("%.1f stars per species is more than twice the standard %.0f; the game will be slow", perSpecies, standardPerSpecies)
+	}
+	return s, nil
+}
+
+func (s *Suggestion) warn(format string, args ...any) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// expectedPlanetsPerStar returns the mean number of planets generateStar
+// creates, by enumerating every roll.
+func expectedPlanetsPerStar() float64 {
+	// Types 1 through 4 each have a one in ten chance; the other six
+	// rolls are main sequence stars.
+	chance := []struct {
+		t world.StarType
+		p float64
+	}{
+		{world.DwarfStar, 0.1},
+		{world.DegenerateStar, 0.1},
+		{world.MainSequenceStar, 0.7},
+		{world.GiantStar, 0.1},
+	}
+	mean := 0.0
+	for _, c := range chance {
+		die := int(c.t) + 1
+		sum, n := 0, 0
+		for a := 1; a <= die; a++ {
+			for b := 1; b <= die; b++ {
+				for d := 1; d <= die; d++ {
+					sum += min(max(a+b+d-2, 1), 9)
+					n++
+				}
+			}
+		}
+		mean += c.p * float64(sum) / float64(n)
+	}
+	return mean
+}
@@ -0,0 +1,82 @@
+package galaxy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/rng"
+)
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name                string
+		species, stars, rad int
+		lessCrowded         bool
+		want                Params
+		warnings            []string
+	}{
+		{name: "standard", species: 15, want: Params{Species: 15, Stars: 90, Radius: 20}},
+		{name: "less crowded", species: 15, lessCrowded: true, want: Params{Species: 15, Stars: 135, Radius: 23}},
+		{name: "given stars", species: 15, stars: 120, want: Params{Species: 15, Stars: 120, Radius: 23}},
+		{name: "stars and less crowded", species: 15, stars: 120, lessCrowded: true,
+			want: Params{Species: 15, Stars: 120, Radius: 23}, warnings: []string{"not allowed"}},
+		{name: "too crowded", species: 15, stars: 300, rad: 15,
+			want: Params{Species: 15, Stars: 300, Radius: 15}, warnings: []string{"too many stars"}},
+		{name: "crowded", species: 15, stars: 90, rad: 14,
+			want: Params{Species: 15, Stars: 90, Radius: 14}, warnings: []string{"more than twice as crowded"}},
+		{name: "sparse", species: 15, stars: 100, rad: 40,
+			want: Params{Species: 15, Stars: 100, Radius: 40}, warnings: []string{"less than half as crowded"}},
+		{name: "few stars per species", species: 40, stars: 100,
+			want: Params{Species: 40, Stars: 100, Radius: 21}, warnings: []string{"meet very early"}},
+		{name: "many stars per species", species: 5, stars: 90,
+			want: Params{Species: 5, Stars: 90, Radius: 20}, warnings: []string{"slow"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Suggest(tt.species, tt.stars, tt.rad, tt.lessCrowded)
+			if err != nil {
+				t.Fatalf("Suggest() error = %v", err)
+			}
+			if got.Params != tt.want {
+				t.Errorf("Suggest() = %+v, want %+v", got.Params, tt.want)
+			}
+			if len(got.Warnings) != len(tt.warnings) {
+				t.Fatalf("Suggest() warnings = %q, want %d", got.Warnings, len(tt.warnings))
+			}
+			for i, w := range tt.warnings {
+				if !strings.Contains(got.Warnings[i], w) {
+					t.Errorf("warning %d = %q, want it to mention %q", i, got.Warnings[i], w)
+				}
+			}
+		})
+	}
+
+	if _, err := Suggest(0, 0, 0, false); err == nil {
+		t.Errorf("Suggest(0) expected error")
+	}
+}
+
+func TestSuggestExpectations(t *testing.T) {
+	s, err := Suggest(15, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const runs = 50
+	planets, wormholes := 0, 0
+	for i := 0; i < runs; i++ {
+		g, err := Generate(s.Params, rng.NewFactory([]byte(fmt.Sprint(i))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		planets += len(g.Planets)
+		wormholes += g.NumWormholes()
+	}
+	if avg := float64(planets) / runs; math.Abs(avg-float64(s.ExpectedPlanets)) > 0.05*avg {
+		t.Errorf("expected %d planets, generated %.1f on average", s.ExpectedPlanets, avg)
+	}
+	if avg := float64(wormholes) / runs; math.Abs(avg-float64(s.ExpectedWormholes)) > 1.5 {
+		t.Errorf("expected %d wormholes, generated %.1f on average", s.ExpectedWormholes, avg)
+	}
+}
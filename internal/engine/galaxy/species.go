@@ -0,0 +1,260 @@
+package galaxy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/playbymail/fh/internal/config"
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/rng/dice"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Starting values for a new species.
+const (
+	startMI, startMA = 10, 10 // tech levels that are not chosen by the player
+	homePopUnits     = 1500   // HP_AVAILABLE_POP in the C source
+	homeShipyards    = 1
+	// homeBase is the sum of the mining and manufacturing bases of the home
+	// planet, in tenths. It is split so that the raw material mined each
+	// turn matches the manufacturing capacity.
+	homeBase = 2500
+	// numNeutralGases is the number of gases a species can breathe without
+	// harm, counting the gases of its home planet.
+	numNeutralGases = 6
+)
+
+// startingShips are the ships each species starts with, in orbit around its home planet.
+var startingShips = []struct {
+	name  string
+	class world.ShipClass
+	typ   world.ShipType
+}{
+	{"Pioneer", world.TR, world.FTL},
+}
+
+// AddSpecies creates the species in the configuration and adds them to the
+// galaxy. Each species gets a home system at least minDistance parsecs from
+// every other home system. The planets of the home system are replaced with
+// the home system template for its number of planets, so the templates must
+// already exist. Species are numbered after any that already exist.
+//
+// Each species draws from its own stream, keyed by its ID.
+func AddSpecies(o *world.Overlay, configs []*config.SpeciesConfig, minDistance int, f rng.Factory) ([]*world.Species, error) {
+	gal, ok := o.Edit(ids.Galaxy())
+	if !ok {
+		return nil, fmt.Errorf("the galaxy has not been created")
+	}
+	g := gal.(*world.Galaxy)
+	templates := map[int]*world.HomeSystemTemplate{}
+	for _, e := range o.ByKind(world.KindHomeSystem) {
+		t := e.(*world.HomeSystemTemplate)
+		templates[t.NumPlanets] = t
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("the home system templates have not been created")
+	}
+
+	var created []*world.Species
+	for i, cfg := range configs {
+		number := g.NumSpecies + 1
+		if number > g.DNumSpecies {
+			return nil, fmt.Errorf("species %d: %q: the galaxy was designed for %d species", i, cfg.Name, g.DNumSpecies)
+		}
+		if len(o.ByName(world.KindSpecies, cfg.Name)) != 0 {
+			return nil, fmt.Errorf("species %d: %q: a species with that name already exists", i, cfg.Name)
+		}
+		if len(o.ByName(world.KindColony, cfg.Homeworld)) != 0 {
+			return nil, fmt.Errorf("species %d: %q: a planet named %q already exists", i, cfg.Name, cfg.Homeworld)
+		}
+		r := f.For("species", string(ids.Species(number)))
+
+		star, err := pickHomeSystem(o, templates, minDistance, r)
+		if err != nil {
+			return nil, fmt.Errorf("species %d: %q: %w", i, cfg.Name, err)
+		}
+		home, err := applyTemplate(o, star, templates[len(star.Planets)])
+		if err != nil {
+			return nil, fmt.Errorf("species %d: %q: %w", i, cfg.Name, err)
+		}
+
+		sp := newSpecies(number, cfg, home, r)
+		o.Upsert(sp)
+		o.Upsert(newHomeColony(sp, cfg, home))
+		for _, s := range startingShips {
+			o.Upsert(&world.Ship{
+				EntityID: ids.Ship(number, s.name),
+				Name:     s.name,
+				Species:  number,
+				Location: home.Location,
+				Status:   world.InOrbit,
+				Type:     s.typ,
+				Class:    s.class,
+				Tonnage:  s.class.Tonnage(),
+			})
+		}
+		g.NumSpecies = number
+		created = append(created, sp)
+	}
+	return created, nil
+}
+
+// pickHomeSystem chooses a random star that can be a home system: it has a
+// template for its number of planets, no wormhole, and no home system
+// closer than minDistance.
+func pickHomeSystem(o *world.Overlay, templates map[int]*world.HomeSystemTemplate, minDistance int, r rng.Scoped) (*world.Star, error) {
+	var homes, candidates []*world.Star
+	for _, e := range o.ByKind(world.KindStar) {
+		s := e.(*world.Star)
+		switch {
+		case s.HomeSystem:
+			homes = append(homes, s)
+		case !s.Wormhole && templates[len(s.Planets)] != nil:
+			candidates = append(candidates, s)
+		}
+	}
+	var ok []*world.Star
+	for _, s := range candidates {
+		far := true
+		for _, h := range homes {
+			if s.DistanceSquared(h.Coords) < minDistance*minDistance {
+				far = false
+				break
+			}
+		}
+		if far {
+			ok = append(ok, s)
+		}
+	}
+	if len(ok) == 0 {
+		return nil, fmt.Errorf("no star is at least %d parsecs from every home system", minDistance)
+	}
+	star, _ := o.Edit(ok[dice.Rnd(r, len(ok))-1].EntityID)
+	return star.(*world.Star), nil
+}
+
+// applyTemplate replaces the planets of the star with those of the template
+// and returns the home planet. The template must have a planet for each
+// planet of the star, and an ideal home planet.
+func applyTemplate(o *world.Overlay, star *world.Star, t *world.HomeSystemTemplate) (*world.Planet, error) {
+	if len(t.Planets) != len(star.Planets) {
+		return nil, fmt.Errorf("home system template %s has %d planets, star %s has %d", t.EntityID, len(t.Planets), star.EntityID, len(star.Planets))
+	}
+	if t.HomePlanet() == nil {
+		return nil, fmt.Errorf("home system template %s has no ideal home planet", t.EntityID)
+	}
+	star.HomeSystem = true
+	var home *world.Planet
+	for i, id := range star.Planets {
+		e, ok := o.Edit(id)
+		if !ok {
+			return nil, fmt.Errorf("star %s: planet %s does not exist", star.EntityID, id)
+		}
+		p := e.(*world.Planet)
+		tp := t.Planets[i].Clone().(*world.Planet)
+		tp.EntityID, tp.StarID, tp.Location, tp.Message = p.EntityID, p.StarID, p.Location, p.Message
+		*p = *tp
+		if p.Special == world.IdealHomePlanet {
+			home = p
+		}
+	}
+	return home, nil
+}
+
+// newSpecies creates a species living on the home planet.
+func newSpecies(number int, cfg *config.SpeciesConfig, home *world.Planet, r rng.Scoped) *world.Species {
+	sp := &world.Species{
+		EntityID: ids.Species(number),
+		Number:   number,
+		Name:     cfg.Name,
+		Email:    cfg.Email,
+		GovtName: cfg.GovtName,
+		GovtType: cfg.GovtType,
+		Home:     home.Location,
+	}
+
+	// Every home planet has oxygen, which the species needs at about the
+	// same level. The gases it evolved with are harmless, as are some
+	// others. All remaining gases are poisonous.
+	o2 := home.GasPercent(world.O2)
+	sp.RequiredGas = world.O2
+	sp.RequiredGasMin = max(o2/2, 1)
+	sp.RequiredGasMax = min(2*o2, 100)
+	neutral := map[world.Gas]bool{}
+	for _, g := range home.Gases {
+		if g.Gas != world.O2 {
+			neutral[g.Gas] = true
+		}
+	}
+	var others []world.Gas
+	for g := world.Gas(1); g <= world.NumGases; g++ {
+		if g != world.O2 && !neutral[g] {
+			others = append(others, g)
+		}
+	}
+	for len(neutral) < numNeutralGases && len(others) > 0 {
+		i := dice.Rnd(r, len(others)) - 1
+		neutral[others[i]] = true
+		others = append(others[:i], others[i+1:]...)
+	}
+	for g := range neutral {
+		sp.NeutralGases = append(sp.NeutralGases, g)
+	}
+	sort.Slice(sp.NeutralGases, func(i, j int) bool { return sp.NeutralGases[i] < sp.NeutralGases[j] })
+	sp.PoisonGases = others
+
+	sp.TechLevel = world.TechLevels{MI: startMI, MA: startMA, ML: cfg.ML, GV: cfg.GV, LS: cfg.LS, BI: cfg.BI}
+	if x := cfg.Experimental; x != nil {
+		for _, v := range []struct {
+			tech  world.Tech
+			level int
+		}{
+			{world.MI, x.TechMI}, {world.MA, x.TechMA}, {world.ML, x.TechML},
+			{world.GV, x.TechGV}, {world.LS, x.TechLS}, {world.BI, x.TechBI},
+		} {
+			if v.level != 0 {
+				sp.TechLevel.Set(v.tech, v.level)
+			}
+		}
+		sp.EconUnits = x.EconUnits
+	}
+	sp.InitTechLevel = sp.TechLevel
+	sp.TechKnowledge = sp.TechLevel
+	return sp
+}
+
+// newHomeColony creates the home colony of the species.
+func newHomeColony(sp *world.Species, cfg *config.SpeciesConfig, home *world.Planet) *world.Colony {
+	c := &world.Colony{
+		EntityID:  ids.Colony(sp.Number, home.EntityID),
+		Name:      cfg.Homeworld,
+		Species:   sp.Number,
+		PlanetID:  home.EntityID,
+		Location:  home.Location,
+		Status:    world.HomePlanet | world.Populated,
+		Shipyards: homeShipyards,
+		PopUnits:  homePopUnits,
+	}
+
+	// Raw material is 10 * MI * MIBase / MiningDifficulty and manufacturing
+	// capacity is MA * MABase / 10, so equal output needs
+	// MIBase / MABase = MA * MiningDifficulty / (100 * MI).
+	mi, ma := sp.TechLevel.MI, sp.TechLevel.MA
+	ratio := ma * home.MiningDifficulty
+	c.MIBase = homeBase * ratio / (ratio + 100*mi)
+	c.MABase = homeBase - c.MIBase
+	if x := cfg.Experimental; x != nil {
+		if x.MIBase != 0 {
+			c.MIBase = x.MIBase
+		}
+		if x.MABase != 0 {
+			c.MABase = x.MABase
+		}
+		if x.ShipYards != 0 {
+			c.Shipyards = x.ShipYards
+		}
+	}
+	sp.HPOriginalBase = c.MIBase + c.MABase
+	return c
+}
@@ -0,0 +1,192 @@
+package galaxy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playbymail/fh/internal/config"
+	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// setupState returns a galaxy for the species with home system templates.
+func setupState(t *testing.T, species int, templates bool) *world.State {
+	t.Helper()
+	params, err := NewParams(species, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := rng.NewFactory([]byte("test"))
+	g, err := Generate(params, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities := g.Entities()
+	if templates {
+		for _, tmpl := range GenerateHomeSystemTemplates(f) {
+			entities = append(entities, tmpl)
+		}
+	}
+	state, err := world.NewState(entities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func speciesConfig(name, homeworld string) *config.SpeciesConfig {
+	return &config.SpeciesConfig{
+		Email: name + "@example.com", GovtName: "Council", GovtType: "Democracy",
+		Homeworld: homeworld, Name: name, ML: 4, GV: 4, LS: 4, BI: 3,
+	}
+}
+
+func TestAddSpecies(t *testing.T) {
+	state := setupState(t, 6, true)
+	configs := []*config.SpeciesConfig{
+		speciesConfig("Humans", "Terra"),
+		speciesConfig("Zorgons", "Zorg"),
+		speciesConfig("Vulcans", "Vulcan"),
+	}
+	configs[2].Experimental = &config.Experimental{EconUnits: 500, MABase: 700, ShipYards: 3, TechMA: 20, TechML: 9}
+
+	o := state.Mutate()
+	created, err := AddSpecies(o, configs, 10, rng.NewFactory([]byte("species")))
+	if err != nil {
+		t.Fatalf("AddSpecies() error = %v", err)
+	}
+	after := o.Commit()
+	if len(created) != 3 {
+		t.Fatalf("created %d species", len(created))
+	}
+	if g, _ := after.GetEntity(ids.Galaxy()); g.(*world.Galaxy).NumSpecies != 3 {
+		t.Errorf("galaxy has %d species", g.(*world.Galaxy).NumSpecies)
+	}
+
+	for i, sp := range created {
+		if sp.Number != i+1 || sp.Name != configs[i].Name || sp.Email != configs[i].Email {
+			t.Errorf("species %d = SP%02d %s <%s>", i, sp.Number, sp.Name, sp.Email)
+		}
+		star, ok := after.StarAt(sp.Home.Coords)
+		if !ok || !star.HomeSystem || star.Wormhole {
+			t.Fatalf("%s: bad home system %v", sp.Name, star)
+		}
+		for _, other := range created[:i] {
+			if d := sp.Home.Distance(other.Home.Coords); d < 10 {
+				t.Errorf("%s is %.1f parsecs from %s", sp.Name, d, other.Name)
+			}
+		}
+
+		// the planets of the home system are those of the template
+		e, _ := after.GetEntity(ids.HomeSystem(len(star.Planets)))
+		tmpl := e.(*world.HomeSystemTemplate)
+		var home *world.Planet
+		for j, id := range star.Planets {
+			e, _ := after.GetEntity(id)
+			p := e.(*world.Planet)
+			want := tmpl.Planets[j]
+			want.EntityID, want.StarID, want.Location = p.EntityID, p.StarID, p.Location
+			if !reflect.DeepEqual(*p, want) {
+				t.Errorf("%s: planet %d = %+v, want %+v", sp.Name, j+1, *p, want)
+			}
+			if p.Special == world.IdealHomePlanet {
+				home = p
+			}
+		}
+		if home == nil || home.Location != sp.Home {
+			t.Fatalf("%s: home planet is not at %s", sp.Name, sp.Home)
+		}
+
+		// every gas is required, neutral or poisonous, and the home
+		// atmosphere is breathable
+		o2 := home.GasPercent(world.O2)
+		if sp.RequiredGas != world.O2 || o2 < sp.RequiredGasMin || o2 > sp.RequiredGasMax {
+			t.Errorf("%s: requires %s %d-%d%%, home has %d%%", sp.Name, sp.RequiredGas, sp.RequiredGasMin, sp.RequiredGasMax, o2)
+		}
+		if len(sp.NeutralGases) != numNeutralGases || 1+len(sp.NeutralGases)+len(sp.PoisonGases) != world.NumGases {
+			t.Errorf("%s: neutral %v, poison %v", sp.Name, sp.NeutralGases, sp.PoisonGases)
+		}
+		for _, g := range home.Gases {
+			if sp.IsPoison(g.Gas) {
+				t.Errorf("%s: home planet has poison %s", sp.Name, g.Gas)
+			}
+		}
+
+		colonies := after.ColoniesOnPlanet(home.EntityID)
+		if len(colonies) != 1 || colonies[0].Name != configs[i].Homeworld || !colonies[0].Status.Has(world.HomePlanet) || colonies[0].PopUnits == 0 {
+			t.Errorf("%s: bad home colony %v", sp.Name, colonies)
+		}
+		if ships := after.ShipsAt(sp.Number, sp.Home.Coords); len(ships) != len(startingShips) {
+			t.Errorf("%s: %d starting ships", sp.Name, len(ships))
+		}
+	}
+
+	want := world.TechLevels{MI: 10, MA: 10, ML: 4, GV: 4, LS: 4, BI: 3}
+	if created[0].TechLevel != want || created[0].InitTechLevel != want || created[0].EconUnits != 0 {
+		t.Errorf("tech levels = %+v", created[0].TechLevel)
+	}
+	want.MA, want.ML = 20, 9
+	if created[2].TechLevel != want || created[2].EconUnits != 500 {
+		t.Errorf("experimental tech levels = %+v, econ units %d", created[2].TechLevel, created[2].EconUnits)
+	}
+	home := after.ColoniesOf(3)[0]
+	if home.MABase != 700 || home.Shipyards != 3 || created[2].HPOriginalBase != home.MIBase+home.MABase {
+		t.Errorf("experimental colony = %+v", home)
+	}
+}
+
+func TestAddSpeciesErrors(t *testing.T) {
+	f := rng.NewFactory([]byte("species"))
+	tests := []struct {
+		name      string
+		templates bool
+		configs   []*config.SpeciesConfig
+		radius    int
+	}{
+		{"no templates", false, []*config.SpeciesConfig{speciesConfig("Humans", "Terra")}, 10},
+		{"too many species", true, []*config.SpeciesConfig{
+			speciesConfig("A", "A"), speciesConfig("B", "B"), speciesConfig("C", "C"),
+		}, 1},
+		{"radius too large", true, []*config.SpeciesConfig{speciesConfig("A", "A"), speciesConfig("B", "B")}, 100},
+		{"same name", true, []*config.SpeciesConfig{speciesConfig("A", "A"), speciesConfig("a", "B")}, 1},
+		{"same homeworld", true, []*config.SpeciesConfig{speciesConfig("A", "Terra"), speciesConfig("B", "TERRA")}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := setupState(t, 2, tt.templates)
+			if _, err := AddSpecies(state.Mutate(), tt.configs, tt.radius, f); err == nil {
+				t.Errorf("AddSpecies() expected error")
+			}
+		})
+	}
+}
+
+func TestAddSpeciesBadTemplates(t *testing.T) {
+	f := rng.NewFactory([]byte("species"))
+	tests := []struct {
+		name   string
+		tamper func(tmpl *world.HomeSystemTemplate)
+	}{
+		{"no ideal home planet", func(tmpl *world.HomeSystemTemplate) {
+			for i := range tmpl.Planets {
+				tmpl.Planets[i].Special = world.NotSpecial
+			}
+		}},
+		{"missing planets", func(tmpl *world.HomeSystemTemplate) {
+			tmpl.Planets = tmpl.Planets[:1]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := setupState(t, 2, true).Mutate()
+			for _, e := range o.ByKind(world.KindHomeSystem) {
+				edited, _ := o.Edit(e.ID())
+				tt.tamper(edited.(*world.HomeSystemTemplate))
+			}
+			if _, err := AddSpecies(o, []*config.SpeciesConfig{speciesConfig("Humans", "Terra")}, 1, f); err == nil {
+				t.Errorf("AddSpecies() expected error")
+			}
+		})
+	}
+}
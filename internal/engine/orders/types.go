@@ -3,6 +3,7 @@ package orders
 
 import (
 	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/world"
 )
 
 // Context holds execution context for orders.
@@ -24,9 +25,10 @@ type Order interface {
 }
 
 // ReadOnly is a read-only world view for validation.
+// The indexed queries let validators avoid scanning the whole world.
 type ReadOnly interface {
 	GetEntity(id string) (interface{}, bool)
-	// TODO: Add query methods
+	world.Queries
 }
 
 // ReadWrite is a mutable world view for execution.
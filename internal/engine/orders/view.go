@@ -0,0 +1,46 @@
+package orders
+
+import (
+	"fmt"
+
+	"github.com/playbymail/fh/internal/engine/world"
+)
+
+// View returns a ReadOnly view of a world snapshot.
+func View(s world.Snapshot) ReadOnly {
+	return snapshotView{s}
+}
+
+// Edit returns a ReadWrite view of a mutable world.
+// Upsert panics if the entity is not a world.Entity with a matching ID.
+func Edit(m world.Mutable) ReadWrite {
+	return mutableView{snapshotView{m}, m}
+}
+
+type snapshotView struct {
+	world.Snapshot
+}
+
+func (v snapshotView) GetEntity(id string) (interface{}, bool) {
+	return v.Snapshot.GetEntity(world.ID(id))
+}
+
+type mutableView struct {
+	snapshotView
+	m world.Mutable
+}
+
+func (v mutableView) Upsert(id string, entity interface{}) {
+	e, ok := entity.(world.Entity)
+	if !ok {
+		panic(fmt.Sprintf("orders: upsert %s: %T is not a world entity", id, entity))
+	}
+	if e.ID() != world.ID(id) {
+		panic(fmt.Sprintf("orders: upsert %s: entity has id %s", id, e.ID()))
+	}
+	v.m.Upsert(e)
+}
+
+func (v mutableView) Delete(id string) {
+	v.m.Delete(world.ID(id))
+}
@@ -0,0 +1,99 @@
+// Package dice implements the random draws used by the game rules on top
+// of rng.Scoped.
+//
+// The rules of the C engine are written in terms of rnd(max), which returns
+// a number from 1 to max. Rnd makes the same call on the Scoped, so a rule
+// ported from C draws the same numbers from an AlgorithmM stream.
+package dice
+
+import (
+	"github.com/playbymail/fh/internal/engine/rng"
+)
+
+// Rnd returns a random number from 1 to n, like rnd in the C engine.
+// It panics if n <= 0.
+func Rnd(r rng.Scoped, n int) int {
+	return r.Intn(n) + 1
+}
+
+// Percent returns a random number from 1 to 100.
+// A roll of Percent(r) <= chance succeeds chance percent of the time.
+func Percent(r rng.Scoped) int {
+	return Rnd(r, 100)
+}
+
+// RollSum returns the sum of count rolls of a die with the given number of
+// sides, so RollSum(r, 3, 6) rolls 3d6. The dice are rolled in order.
+// It panics if sides <= 0; a count <= 0 rolls nothing and returns 0.
+func RollSum(r rng.Scoped, count, sides int) int {
+	sum := 0
+	for i := 0; i < count; i++ {
+		sum += Rnd(r, sides)
+	}
+	return sum
+}
+
+// Choose returns the index of a weight, chosen with a probability
+// proportional to the weight, using a single draw. Weights of 0 are never
+// chosen. It panics if a weight is negative or no weight is positive.
+func Choose(r rng.Scoped, weights []int) int {
+	total := 0
+	for _, w := range weights {
+		if w < 0 {
+			panic("dice: negative weight")
+		}
+		total += w
+	}
+	if total <= 0 {
+		panic("dice: no positive weight")
+	}
+	roll := Rnd(r, total)
+	for i, w := range weights {
+		if roll <= w {
+			return i
+		}
+		roll -= w
+	}
+	panic("unreachable")
+}
+
+// Shuffle shuffles n elements with the Fisher-Yates algorithm, working down
+// from the last element, as math/rand does. swap swaps the elements with
+// indexes i and j. It panics if n < 0.
+func Shuffle(r rng.Scoped, n int, swap func(i, j int)) {
+	if n < 0 {
+		panic("dice: invalid argument to Shuffle")
+	}
+	for i := n - 1; i > 0; i-- {
+		swap(i, r.Intn(i+1))
+	}
+}
+
+// goldenWeights are the weights drawn from for the Choose golden file.
+var goldenWeights = []int{1, 0, 3, 6}
+
+// GenerateGolden returns the draws for the golden files in testdata, by
+// file name. Each file is drawn from a new AlgorithmM with the seed.
+func GenerateGolden(seed uint64) map[string][]int {
+	draw := func(count int, fn func(r rng.Scoped) int) []int {
+		r := rng.NewAlgorithmM(seed)
+		numbers := make([]int, count)
+		for i := range numbers {
+			numbers[i] = fn(r)
+		}
+		return numbers
+	}
+	deck := make([]int, 52)
+	for i := range deck {
+		deck[i] = i
+	}
+	Shuffle(rng.NewAlgorithmM(seed), len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+
+	return map[string][]int{
+		"rnd1to6.golden":    draw(1024, func(r rng.Scoped) int { return Rnd(r, 6) }),
+		"percent.golden":    draw(1024, Percent),
+		"rollsum3d6.golden": draw(1024, func(r rng.Scoped) int { return RollSum(r, 3, 6) }),
+		"choose.golden":     draw(1024, func(r rng.Scoped) int { return Choose(r, goldenWeights) }),
+		"shuffle52.golden":  deck,
+	}
+}
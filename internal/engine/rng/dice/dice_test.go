@@ -0,0 +1,143 @@
+package dice
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/playbymail/fh/internal/engine/rng"
+)
+
+// readGolden reads a golden file of one number per line.
+func readGolden(t *testing.T, name string) []int {
+	t.Helper()
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("failed to open golden file: %v", err)
+	}
+	defer file.Close()
+
+	var expected []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		num, err := strconv.Atoi(scanner.Text())
+		if err != nil {
+			t.Fatalf("failed to parse number: %v", err)
+		}
+		expected = append(expected, num)
+	}
+	return expected
+}
+
+// compareGolden compares the numbers with a golden file in testdata.
+func compareGolden(t *testing.T, name string, numbers []int) {
+	t.Helper()
+	expected := readGolden(t, filepath.Join("testdata", name))
+	if len(numbers) != len(expected) {
+		t.Fatalf("length mismatch: got %d, expected %d", len(numbers), len(expected))
+	}
+	for i, num := range numbers {
+		if num != expected[i] {
+			t.Errorf("mismatch at %d: got %d, expected %d", i, num, expected[i])
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r rng.Scoped) int
+	}{
+		{"rnd1to6.golden", func(r rng.Scoped) int { return Rnd(r, 6) }},
+		{"percent.golden", Percent},
+		{"rollsum3d6.golden", func(r rng.Scoped) int { return RollSum(r, 3, 6) }},
+		{"choose.golden", func(r rng.Scoped) int { return Choose(r, goldenWeights) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rng.NewAlgorithmM(0xDEADBEEF)
+			var numbers []int
+			for i := 0; i < 1024; i++ {
+				numbers = append(numbers, tt.fn(r))
+			}
+			compareGolden(t, tt.name, numbers)
+		})
+	}
+}
+
+func TestShuffleGolden(t *testing.T) {
+	deck := make([]int, 52)
+	for i := range deck {
+		deck[i] = i
+	}
+	Shuffle(rng.NewAlgorithmM(0xDEADBEEF), len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	compareGolden(t, "shuffle52.golden", deck)
+
+	seen := make([]bool, len(deck))
+	for _, card := range deck {
+		if seen[card] {
+			t.Fatalf("card %d appears twice", card)
+		}
+		seen[card] = true
+	}
+}
+
+// TestRndMatchesC checks Rnd against the AlgorithmM golden file,
+// since rnd(max) in the C engine is one more than the draw.
+func TestRndMatchesC(t *testing.T) {
+	expected := readGolden(t, filepath.Join("..", "testdata", "algorithmm_range0to7.golden"))
+	r := rng.NewAlgorithmM(0xDEADBEEF)
+	for i, n := range expected {
+		if got := Rnd(r, 8); got != n+1 {
+			t.Fatalf("mismatch at %d: got %d, expected %d", i, got, n+1)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	r := rng.NewFactory([]byte("test")).For("dice")
+	for i := 0; i < 1000; i++ {
+		if n := Rnd(r, 6); n < 1 || n > 6 {
+			t.Fatalf("Rnd(6) returned %d, out of range", n)
+		}
+		if n := Percent(r); n < 1 || n > 100 {
+			t.Fatalf("Percent() returned %d, out of range", n)
+		}
+		if n := RollSum(r, 3, 6); n < 3 || n > 18 {
+			t.Fatalf("RollSum(3, 6) returned %d, out of range", n)
+		}
+		if n := Choose(r, goldenWeights); n == 1 || n < 0 || n >= len(goldenWeights) {
+			t.Fatalf("Choose() returned %d, a weight of 0 or out of range", n)
+		}
+	}
+	if n := RollSum(r, 0, 6); n != 0 {
+		t.Errorf("RollSum(0, 6) = %d, expected 0", n)
+	}
+	if n := Choose(r, []int{0, 0, 5}); n != 2 {
+		t.Errorf("Choose() = %d, expected the only positive weight", n)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	r := rng.NewAlgorithmM(1)
+	tests := map[string]func(){
+		"Rnd(0)":           func() { Rnd(r, 0) },
+		"RollSum(1, 0)":    func() { RollSum(r, 1, 0) },
+		"Choose(nil)":      func() { Choose(r, nil) },
+		"Choose(zeros)":    func() { Choose(r, []int{0, 0}) },
+		"Choose(negative)": func() { Choose(r, []int{2, -1}) },
+		"Shuffle(-1)":      func() { Shuffle(r, -1, func(i, j int) {}) },
+	}
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package rng
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Mode selects how a game derives its random draws.
+type Mode string
+
+const (
+	// ModeKeyed derives an independent stream for every set of keys,
+	// so draws do not depend on the order of the calls. It is the default.
+	ModeKeyed Mode = "keyed"
+	// ModeLegacy draws every number from a single AlgorithmM stream, as the
+	// C engine does, so the results depend on the order of the draws. The
+	// generators do not make the same draws as C, so a game does not follow
+	// a C game with the same seed.
+	ModeLegacy Mode = "legacy"
+)
+
+// ParseMode returns the mode with the given name.
+// An empty name is ModeKeyed.
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", ModeKeyed:
+		return ModeKeyed, nil
+	case ModeLegacy:
+		return ModeLegacy, nil
+	}
+	return "", fmt.Errorf("invalid rng mode %q (want %s or %s)", name, ModeKeyed, ModeLegacy)
+}
+
+// DefaultLegacySeed is the seed the C engine starts with when it is not
+// given one (last_random in prng.c).
+const DefaultLegacySeed uint64 = 1924085713
+
+// NewLegacyFactory creates a factory that reproduces the single global
+// seed stream of the C engine. For ignores its keys and always returns the
+// same AlgorithmM, so every draw advances the one stream and the results
+// depend only on the order of the draws.
+func NewLegacyFactory(seed uint64) Factory {
+	return &legacyFactory{rng: &AlgorithmM{seed: seed}}
+}
+
+type legacyFactory struct {
+	rng *AlgorithmM
+}
+
+func (f *legacyFactory) For(keys ...string) Scoped {
+	return f.rng
+}
+
+// NewFactoryForMode creates the factory for the mode from a seed.
+// Keyed factories use the seed as the master key, with the derivation and
+// salt. Legacy factories ignore both, and need a decimal seed; an empty
+// seed is DefaultLegacySeed.
+func NewFactoryForMode(mode Mode, d Derivation, seed, salt string) (Factory, error) {
+	switch mode {
+	case ModeKeyed:
+		return NewKeyedFactory(d, []byte(seed), []byte(salt))
+	case ModeLegacy:
+		if seed == "" {
+			return NewLegacyFactory(DefaultLegacySeed), nil
+		}
+		n, err := strconv.ParseUint(seed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("legacy rng seed %q: must be a decimal number", seed)
+		}
+		return NewLegacyFactory(n), nil
+	}
+	return nil, fmt.Errorf("invalid rng mode %q", mode)
+}
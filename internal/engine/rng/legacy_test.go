@@ -0,0 +1,59 @@
+package rng
+
+import (
+	"testing"
+)
+
+func TestLegacyFactory_SingleStream(t *testing.T) {
+	factory := NewLegacyFactory(0xDEADBEEF)
+	want := NewAlgorithmM(0xDEADBEEF)
+
+	// draws through any key continue the same stream, in call order
+	keys := [][]string{{"galaxy"}, {"species", "SP01"}, {"galaxy"}, nil}
+	for i := 0; i < 100; i++ {
+		r := factory.For(keys[i%len(keys)]...)
+		if got, w := r.Intn(8), want.Intn(8); got != w {
+			t.Fatalf("draw %d: got %d, expected %d", i, got, w)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"": ModeKeyed, "keyed": ModeKeyed, "legacy": ModeLegacy} {
+		if got, err := ParseMode(name); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, expected %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseMode("C"); err == nil {
+		t.Error("ParseMode(\"C\") expected error")
+	}
+}
+
+func TestNewFactoryForMode(t *testing.T) {
+	f, err := NewFactoryForMode(ModeLegacy, DerivationV1, "", "")
+	if err != nil {
+		t.Fatalf("NewFactoryForMode() error = %v", err)
+	}
+	if got, want := f.For("x").Uint64(), NewAlgorithmM(DefaultLegacySeed).Uint64(); got != want {
+		t.Errorf("default legacy seed: got %d, expected %d", got, want)
+	}
+
+	f, err = NewFactoryForMode(ModeLegacy, DerivationV1, "42", "")
+	if err != nil {
+		t.Fatalf("NewFactoryForMode() error = %v", err)
+	}
+	if got, want := f.For("x").Uint64(), NewAlgorithmM(42).Uint64(); got != want {
+		t.Errorf("legacy seed 42: got %d, expected %d", got, want)
+	}
+	if _, err := NewFactoryForMode(ModeLegacy, DerivationV1, "not-a-number", ""); err == nil {
+		t.Error("expected error for a legacy seed that is not a number")
+	}
+
+	f, err = NewFactoryForMode(ModeKeyed, DerivationV1, "test", "game1")
+	if err != nil {
+		t.Fatalf("NewFactoryForMode() error = %v", err)
+	}
+	if got, want := f.For("x").Uint64(), NewFactory([]byte("test")).For("x").Uint64(); got != want {
+		t.Errorf("keyed: got %d, expected %d", got, want)
+	}
+}
@@ -1,6 +1,8 @@
 // Package rng implements deterministic RNG for the engine.
 package rng
 
+import "fmt"
+
 // Scoped provides deterministic random draws.
 type Scoped interface {
 	Uint64() uint64
@@ -13,8 +15,50 @@ type Factory interface {
 	For(keys ...string) Scoped
 }
 
+// Derivation is the version of the seed derivation of a keyed factory.
+// A game keeps the derivation it was created with, so its turns replay
+// bit for bit after a new version is added.
+type Derivation string
+
+const (
+	// DerivationV1 joins the keys with "|", so For("a|b") and For("a", "b")
+	// derive the same stream. It is kept for games created with it.
+	DerivationV1 Derivation = "v1"
+	// DerivationV2 length-prefixes each key and adds a version label and
+	// the salt of the game.
+	DerivationV2 Derivation = "v2"
+	// LatestDerivation is the derivation for new games.
+	LatestDerivation = DerivationV2
+)
+
+// ParseDerivation returns the derivation with the given name.
+// An empty name is DerivationV1, the derivation of games that predate
+// versioning.
+func ParseDerivation(name string) (Derivation, error) {
+	switch Derivation(name) {
+	case "", DerivationV1:
+		return DerivationV1, nil
+	case DerivationV2:
+		return DerivationV2, nil
+	}
+	return "", fmt.Errorf("invalid rng derivation %q (want %s or %s)", name, DerivationV1, DerivationV2)
+}
+
 // NewFactory creates a new RNG factory with the given master key.
 // Uses HMAC-SHA256 to derive seeds from keys, with xoroshiro128+ as the PRNG.
+// It uses DerivationV1; new games should use NewKeyedFactory.
 func NewFactory(masterKey []byte) Factory {
-	return newFactory(masterKey)
+	return &factory{masterKey: masterKey, derivation: DerivationV1}
+}
+
+// NewKeyedFactory creates a new RNG factory with the given master key and
+// derivation. The salt, usually the game ID, is only used by DerivationV2.
+func NewKeyedFactory(d Derivation, masterKey, salt []byte) (Factory, error) {
+	switch d {
+	case DerivationV1:
+		return NewFactory(masterKey), nil
+	case DerivationV2:
+		return &factory{masterKey: masterKey, derivation: d, salt: salt}, nil
+	}
+	return nil, fmt.Errorf("invalid rng derivation %q", d)
 }
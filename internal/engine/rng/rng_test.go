@@ -40,7 +40,76 @@ func TestXoroshiro128Plus_DifferentKeys(t *testing.T) {
 	}
 }
 
-// TestXoroshiro128Plus_KnownValues removed - need to compute actual sequence
+// TestDerivation_KnownValues pins the first draws of each derivation,
+// so a change to either one breaks the replay of old games here first.
+func TestDerivation_KnownValues(t *testing.T) {
+	tests := []struct {
+		d    Derivation
+		want [2]uint64
+	}{
+		{DerivationV1, [2]uint64{9489795168585200722, 8341589644758254215}},
+		{DerivationV2, [2]uint64{17037631572870958651, 5406801354365126987}},
+	}
+	for _, tt := range tests {
+		f, err := NewKeyedFactory(tt.d, []byte("test-master-key"), []byte("game1"))
+		if err != nil {
+			t.Fatalf("NewKeyedFactory(%s) error = %v", tt.d, err)
+		}
+		r := f.For("game1", "turn1")
+		if got := [2]uint64{r.Uint64(), r.Uint64()}; got != tt.want {
+			t.Errorf("%s: got %v, expected %v", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestDerivation_V2Separation(t *testing.T) {
+	masterKey := []byte("test-master-key")
+	v1 := NewFactory(masterKey)
+	v2, err := NewKeyedFactory(DerivationV2, masterKey, []byte("game1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewKeyedFactory(DerivationV2, masterKey, []byte("game2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// v1 keeps its collision, so old games replay unchanged
+	if v1.For("a|b").Uint64() != v1.For("a", "b").Uint64() {
+		t.Error("v1: expected For(\"a|b\") and For(\"a\", \"b\") to collide")
+	}
+	pairs := [][2][]string{
+		{{"a|b"}, {"a", "b"}},
+		{{"ab"}, {"a", "b"}},
+		{{"a", ""}, {"a"}},
+		{{""}, nil},
+	}
+	for _, p := range pairs {
+		if v2.For(p[0]...).Uint64() == v2.For(p[1]...).Uint64() {
+			t.Errorf("v2: For(%q) and For(%q) collide", p[0], p[1])
+		}
+	}
+	if v2.For("a").Uint64() == other.For("a").Uint64() {
+		t.Error("v2: games with different salts derived the same stream")
+	}
+	if v2.For("a").Uint64() == v1.For("a").Uint64() {
+		t.Error("v2: derived the same stream as v1")
+	}
+}
+
+func TestParseDerivation(t *testing.T) {
+	for name, want := range map[string]Derivation{"": DerivationV1, "v1": DerivationV1, "v2": DerivationV2} {
+		if got, err := ParseDerivation(name); err != nil || got != want {
+			t.Errorf("ParseDerivation(%q) = %q, %v, expected %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseDerivation("v3"); err == nil {
+		t.Error("ParseDerivation(\"v3\") expected error")
+	}
+	if _, err := NewKeyedFactory("v3", nil, nil); err == nil {
+		t.Error("NewKeyedFactory(\"v3\") expected error")
+	}
+}
 
 func TestScopedRNG_Intn(t *testing.T) {
 	masterKey := []byte("test")
@@ -0,0 +1,115 @@
+package rng
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
+
+// The state of a Scoped is a tag byte for the generator followed by its
+// state words in little-endian order, so the state of one generator can't
+// be restored into another.
+//
+// A traced stream also saves its number of draws, so the draw indices in
+// its trace continue after a restore.
+const (
+	tagAlgorithmM = 'M'
+	tagXoroshiro  = 'X'
+	tagTraced     = 'T'
+)
+
+// MarshalBinary returns the state of the generator.
+func (a *AlgorithmM) MarshalBinary() ([]byte, error) {
+	return binary.LittleEndian.AppendUint64([]byte{tagAlgorithmM}, a.seed), nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (a *AlgorithmM) UnmarshalBinary(data []byte) error {
+	words, err := stateWords(data, tagAlgorithmM, 1)
+	if err != nil {
+		return err
+	}
+	a.seed = words[0]
+	return nil
+}
+
+// MarshalBinary returns the state of the generator.
+func (r *scopedRNG) MarshalBinary() ([]byte, error) {
+	data := binary.LittleEndian.AppendUint64([]byte{tagXoroshiro}, r.rng.s[0])
+	return binary.LittleEndian.AppendUint64(data, r.rng.s[1]), nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (r *scopedRNG) UnmarshalBinary(data []byte) error {
+	words, err := stateWords(data, tagXoroshiro, 2)
+	if err != nil {
+		return err
+	}
+	if words[0] == 0 && words[1] == 0 {
+		return fmt.Errorf("rng: invalid xoroshiro128+ state")
+	}
+	r.rng.s = [2]uint64{words[0], words[1]}
+	return nil
+}
+
+// stateWords checks the tag and length of a state and returns its words.
+func stateWords(data []byte, tag byte, n int) ([]uint64, error) {
+	if len(data) == 0 || data[0] != tag {
+		return nil, fmt.Errorf("rng: state is not for this generator")
+	}
+	if len(data) != 1+8*n {
+		return nil, fmt.Errorf("rng: state has %d bytes, want %d", len(data), 1+8*n)
+	}
+	words := make([]uint64, n)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(data[1+8*i:])
+	}
+	return words, nil
+}
+
+// State returns the state of r, which must implement
+// encoding.BinaryMarshaler, as the generators in this package do.
+func State(r Scoped) ([]byte, error) {
+	m, ok := r.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("rng: %T does not export its state", r)
+	}
+	return m.MarshalBinary()
+}
+
+// Restore sets the state of r to a state returned by State, so r makes the
+// same draws that the saved generator would have made.
+func Restore(r Scoped, state []byte) error {
+	u, ok := r.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("rng: %T does not restore its state", r)
+	}
+	return u.UnmarshalBinary(state)
+}
+
+// Checkpoint holds the states of named generators, so a phase can save
+// them with store.Store and resume after a crash with the same draws.
+// In legacy mode every generator is the same stream, so one name is enough.
+type Checkpoint map[string][]byte
+
+// Save adds the state of r to the checkpoint.
+func (c Checkpoint) Save(name string, r Scoped) error {
+	state, err := State(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	c[name] = state
+	return nil
+}
+
+// Restore sets r to the state saved with the name.
+func (c Checkpoint) Restore(name string, r Scoped) error {
+	state, ok := c[name]
+	if !ok {
+		return fmt.Errorf("%s: no state in checkpoint", name)
+	}
+	if err := Restore(r, state); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
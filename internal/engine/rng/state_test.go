@@ -0,0 +1,101 @@
+package rng
+
+import (
+	"testing"
+)
+
+func TestStateRestore(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() Scoped
+	}{
+		{"AlgorithmM", func() Scoped { return NewAlgorithmM(0xDEADBEEF) }},
+		{"xoroshiro", func() Scoped { return NewFactory([]byte("test")).For("game1", "turn1") }},
+		{"legacy", func() Scoped { return NewLegacyFactory(DefaultLegacySeed).For("combat") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.new()
+			for i := 0; i < 10; i++ {
+				r.Uint64()
+			}
+			state, err := State(r)
+			if err != nil {
+				t.Fatalf("State() error = %v", err)
+			}
+			var want []uint64
+			for i := 0; i < 100; i++ {
+				want = append(want, r.Uint64())
+			}
+
+			// a new generator resumes from the saved state
+			resumed := tt.new()
+			if err := Restore(resumed, state); err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			for i, w := range want {
+				if got := resumed.Uint64(); got != w {
+					t.Fatalf("draw %d after restore: got %d, expected %d", i, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	m := NewAlgorithmM(1)
+	x := NewFactory([]byte("test")).For("a")
+	mState, _ := State(m)
+	xState, _ := State(x)
+
+	tests := map[string]struct {
+		r     Scoped
+		state []byte
+	}{
+		"empty":            {m, nil},
+		"other generator":  {m, xState},
+		"short":            {x, xState[:9]},
+		"no tag":           {x, make([]byte, 17)},
+		"zero state words": {x, append([]byte{tagXoroshiro}, make([]byte, 16)...)},
+		"long":             {m, append(mState, 0)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := Restore(tt.r, tt.state); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	factory := NewFactory([]byte("test"))
+	combat, jump := factory.For("combat"), factory.For("jump")
+	combat.Uint64()
+
+	c := Checkpoint{}
+	if err := c.Save("combat", combat); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save("jump", jump); err != nil {
+		t.Fatal(err)
+	}
+	wantCombat, wantJump := combat.Intn(1000), jump.Intn(1000)
+
+	combat, jump = factory.For("combat"), factory.For("jump")
+	if err := c.Restore("combat", combat); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restore("jump", jump); err != nil {
+		t.Fatal(err)
+	}
+	if got := combat.Intn(1000); got != wantCombat {
+		t.Errorf("combat: got %d, expected %d", got, wantCombat)
+	}
+	if got := jump.Intn(1000); got != wantJump {
+		t.Errorf("jump: got %d, expected %d", got, wantJump)
+	}
+	if err := c.Restore("production", combat); err == nil {
+		t.Error("expected error restoring a name not in the checkpoint")
+	}
+}
@@ -0,0 +1,137 @@
+package rng
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Trace is a log of the streams derived from a factory and the draws made
+// from them, so a game master can show why a roll came out as it did.
+type Trace struct {
+	Streams []TraceStream `json:"streams"` // one per call to For, in call order
+	Draws   []TraceDraw   `json:"draws"`   // in draw order
+}
+
+// TraceStream is a stream derived by a call to For.
+type TraceStream struct {
+	Keys []string `json:"keys"`
+}
+
+// TraceDraw is a single draw from a stream.
+type TraceDraw struct {
+	Stream int    `json:"stream"` // index in Trace.Streams
+	Index  int    `json:"index"`  // number of earlier draws from the stream
+	Method string `json:"method"` // Uint64, Float64 or Intn
+	N      int    `json:"n,omitempty"`
+	Value  string `json:"value"` // formatted exactly
+}
+
+// Tracer is a Factory that records every stream it derives and every draw
+// from those streams. It is safe for concurrent use.
+type Tracer struct {
+	f     Factory
+	mu    sync.Mutex
+	trace Trace
+}
+
+// NewTracer wraps a factory with a tracer.
+func NewTracer(f Factory) *Tracer {
+	return &Tracer{f: f}
+}
+
+// For derives a stream from the wrapped factory and records the keys.
+func (t *Tracer) For(keys ...string) Scoped {
+	r := t.f.For(keys...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Streams = append(t.trace.Streams, TraceStream{Keys: slices.Clone(keys)})
+	return &tracedRNG{t: t, r: r, stream: len(t.trace.Streams) - 1}
+}
+
+// Trace returns a copy of the log so far.
+func (t *Tracer) Trace() *Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &Trace{Streams: slices.Clone(t.trace.Streams), Draws: slices.Clone(t.trace.Draws)}
+}
+
+// record adds a draw to the log.
+func (t *Tracer) record(stream, index int, method string, n int, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Draws = append(t.trace.Draws, TraceDraw{Stream: stream, Index: index, Method: method, N: n, Value: value})
+}
+
+// tracedRNG records the draws from a stream.
+type tracedRNG struct {
+	t      *Tracer
+	r      Scoped
+	stream int
+	draws  int
+}
+
+func (r *tracedRNG) Uint64() uint64 {
+	v := r.r.Uint64()
+	r.t.record(r.stream, r.next(), "Uint64", 0, strconv.FormatUint(v, 10))
+	return v
+}
+
+func (r *tracedRNG) Float64() float64 {
+	v := r.r.Float64()
+	r.t.record(r.stream, r.next(), "Float64", 0, strconv.FormatFloat(v, 'g', -1, 64))
+	return v
+}
+
+func (r *tracedRNG) Intn(n int) int {
+	v := r.r.Intn(n)
+	r.t.record(r.stream, r.next(), "Intn", n, strconv.Itoa(v))
+	return v
+}
+
+// next returns the index of the next draw.
+func (r *tracedRNG) next() int {
+	r.draws++
+	return r.draws - 1
+}
+
+// MarshalBinary returns the number of draws and the state of the traced
+// stream, so traced streams can be checkpointed.
+func (r *tracedRNG) MarshalBinary() ([]byte, error) {
+	state, err := State(r.r)
+	if err != nil {
+		return nil, err
+	}
+	data := binary.LittleEndian.AppendUint64([]byte{tagTraced}, uint64(r.draws))
+	return append(data, state...), nil
+}
+
+// UnmarshalBinary restores the number of draws and the state of the
+// traced stream.
+func (r *tracedRNG) UnmarshalBinary(data []byte) error {
+	if len(data) < 9 || data[0] != tagTraced {
+		return fmt.Errorf("rng: state is not for a traced stream")
+	}
+	if err := Restore(r.r, data[9:]); err != nil {
+		return err
+	}
+	r.draws = int(binary.LittleEndian.Uint64(data[1:9]))
+	return nil
+}
+
+// Match returns the draws from streams whose keys start with the given
+// keys, in draw order. No keys match every draw.
+func (t *Trace) Match(keys ...string) []TraceDraw {
+	var draws []TraceDraw
+	for _, d := range t.Draws {
+		if d.Stream < 0 || d.Stream >= len(t.Streams) {
+			continue
+		}
+		if sk := t.Streams[d.Stream].Keys; len(sk) >= len(keys) && slices.Equal(sk[:len(keys)], keys) {
+			draws = append(draws, d)
+		}
+	}
+	return draws
+}
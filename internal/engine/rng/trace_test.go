@@ -0,0 +1,91 @@
+package rng
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	tracer := NewTracer(NewFactory([]byte("test")))
+	plain := NewFactory([]byte("test"))
+
+	combat := tracer.For("combat", "SP01")
+	jump := tracer.For("jump", "SP02")
+	wantCombat, wantJump := plain.For("combat", "SP01"), plain.For("jump", "SP02")
+
+	// draws are passed through unchanged
+	if got, want := combat.Intn(100), wantCombat.Intn(100); got != want {
+		t.Errorf("Intn: got %d, expected %d", got, want)
+	}
+	if got, want := jump.Uint64(), wantJump.Uint64(); got != want {
+		t.Errorf("Uint64: got %d, expected %d", got, want)
+	}
+	if got, want := combat.Float64(), wantCombat.Float64(); got != want {
+		t.Errorf("Float64: got %v, expected %v", got, want)
+	}
+
+	trace := tracer.Trace()
+	wantStreams := []TraceStream{{Keys: []string{"combat", "SP01"}}, {Keys: []string{"jump", "SP02"}}}
+	if !reflect.DeepEqual(trace.Streams, wantStreams) {
+		t.Errorf("Streams = %+v, expected %+v", trace.Streams, wantStreams)
+	}
+	if len(trace.Draws) != 3 {
+		t.Fatalf("expected 3 draws, got %+v", trace.Draws)
+	}
+	first := trace.Draws[0]
+	if first.Stream != 0 || first.Index != 0 || first.Method != "Intn" || first.N != 100 {
+		t.Errorf("Draws[0] = %+v", first)
+	}
+	if d := trace.Draws[2]; d.Stream != 0 || d.Index != 1 || d.Method != "Float64" {
+		t.Errorf("Draws[2] = %+v", d)
+	}
+
+	if got := trace.Match("combat"); len(got) != 2 || got[1].Method != "Float64" {
+		t.Errorf("Match(combat) = %+v", got)
+	}
+	if got := trace.Match("jump", "SP02"); len(got) != 1 || got[0].Method != "Uint64" {
+		t.Errorf("Match(jump, SP02) = %+v", got)
+	}
+	if got := trace.Match("combat", "SP01", "extra"); len(got) != 0 {
+		t.Errorf("Match with a longer key path = %+v", got)
+	}
+	if got := trace.Match(); len(got) != 3 {
+		t.Errorf("Match() = %+v", got)
+	}
+}
+
+func TestTracerCheckpoint(t *testing.T) {
+	tracer := NewTracer(NewLegacyFactory(DefaultLegacySeed))
+	r := tracer.For("combat")
+	r.Uint64()
+	state, err := State(r)
+	if err != nil {
+		t.Fatalf("State() error = %v", err)
+	}
+	want := r.Uint64()
+	if err := Restore(r, state); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := r.Uint64(); got != want {
+		t.Errorf("after restore: got %d, expected %d", got, want)
+	}
+
+	// a stream from a new tracer resumes the draw indices
+	resumed := NewTracer(NewLegacyFactory(0))
+	rr := resumed.For("combat")
+	if err := Restore(rr, state); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := rr.Uint64(); got != want {
+		t.Errorf("resumed: got %d, expected %d", got, want)
+	}
+	if draws := resumed.Trace().Draws; len(draws) != 1 || draws[0].Index != 1 {
+		t.Errorf("resumed draws = %+v, expected index 1", draws)
+	}
+
+	// the state of an untraced stream is not a traced state
+	plain, _ := State(NewAlgorithmM(DefaultLegacySeed))
+	if err := Restore(rr, plain); err == nil {
+		t.Error("expected error restoring an untraced state")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/binary"
+	"strings"
 )
 
 // xoroshiro128+ implementation for deterministic RNG.
@@ -39,26 +40,28 @@ func rotl(x uint64, k int) uint64 {
 
 // Factory implements the RNG factory.
 type factory struct {
-	masterKey []byte
+	masterKey  []byte
+	derivation Derivation
+	salt       []byte // DerivationV2 only
 }
 
-// newFactory creates a new RNG factory with the given master key.
-func newFactory(masterKey []byte) Factory {
-	return &factory{masterKey: masterKey}
-}
+// labelV2 separates DerivationV2 seeds from any other use of the master key.
+const labelV2 = "fh/rng/hmac-sha256/xoroshiro128+/v2"
 
 // For derives a scoped RNG from stable keys.
 func (f *factory) For(keys ...string) Scoped {
-	input := ""
-	for i, key := range keys {
-		if i > 0 {
-			input += "|"
+	mac := hmac.New(sha256.New, f.masterKey)
+	if f.derivation == DerivationV2 {
+		// every field is length-prefixed, so no two key lists collide
+		var input []byte
+		for _, field := range append([]string{labelV2, string(f.salt)}, keys...) {
+			input = binary.AppendUvarint(input, uint64(len(field)))
+			input = append(input, field...)
 		}
-		input += key
+		mac.Write(input)
+	} else {
+		mac.Write([]byte(strings.Join(keys, "|")))
 	}
-
-	mac := hmac.New(sha256.New, f.masterKey)
-	mac.Write([]byte(input))
 	sum := mac.Sum(nil)
 
 	s0 := binary.LittleEndian.Uint64(sum[0:8])
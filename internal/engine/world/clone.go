@@ -0,0 +1,76 @@
+package world
+
+// Clone returns a deep copy of an entity.
+// Entities held by a State are shared and must not be modified in place;
+// clone them (or use Overlay.Edit) and Upsert the copy instead.
+func Clone(e Entity) Entity {
+	if c, ok := e.(interface{ Clone() Entity }); ok {
+		return c.Clone()
+	}
+	// fall back to a round trip through the codec for registered kinds
+	se, err := Encode(e)
+	if err != nil {
+		panic("world: clone: " + err.Error())
+	}
+	clone, err := Decode(se)
+	if err != nil {
+		panic("world: clone: " + err.Error())
+	}
+	return clone
+}
+
+// Clone returns a deep copy of the galaxy.
+func (g *Galaxy) Clone() Entity {
+	clone := *g
+	return &clone
+}
+
+// Clone returns a deep copy of the star.
+func (s *Star) Clone() Entity {
+	clone := *s
+	clone.Planets = append([]ID(nil), s.Planets...)
+	clone.VisitedBy = s.VisitedBy.Clone()
+	return &clone
+}
+
+// Clone returns a deep copy of the planet.
+func (p *Planet) Clone() Entity {
+	clone := *p
+	clone.Gases = append([]GasPercent(nil), p.Gases...)
+	return &clone
+}
+
+// Clone returns a deep copy of the colony.
+func (c *Colony) Clone() Entity {
+	clone := *c
+	clone.Items = c.Items.Clone()
+	return &clone
+}
+
+// Clone returns a deep copy of the ship.
+func (s *Ship) Clone() Entity {
+	clone := *s
+	clone.Cargo = s.Cargo.Clone()
+	return &clone
+}
+
+// Clone returns a deep copy of the species.
+func (s *Species) Clone() Entity {
+	clone := *s
+	clone.NeutralGases = append([]Gas(nil), s.NeutralGases...)
+	clone.PoisonGases = append([]Gas(nil), s.PoisonGases...)
+	clone.Contacts = s.Contacts.Clone()
+	clone.Allies = s.Allies.Clone()
+	clone.Enemies = s.Enemies.Clone()
+	return &clone
+}
+
+// Clone returns a deep copy of the template.
+func (t *HomeSystemTemplate) Clone() Entity {
+	clone := *t
+	clone.Planets = make([]Planet, len(t.Planets))
+	for i := range t.Planets {
+		clone.Planets[i] = *t.Planets[i].Clone().(*Planet)
+	}
+	return &clone
+}
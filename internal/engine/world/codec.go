@@ -0,0 +1,119 @@
+package world
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// codecs maps an entity kind to a constructor for an empty entity of that kind.
+var codecs = struct {
+	sync.RWMutex
+	m map[string]func() Entity
+}{m: map[string]func() Entity{}}
+
+func init() {
+	RegisterKind(KindGalaxy, func() Entity { return &Galaxy{} })
+	RegisterKind(KindStar, func() Entity { return &Star{} })
+	RegisterKind(KindPlanet, func() Entity { return &Planet{} })
+	RegisterKind(KindColony, func() Entity { return &Colony{} })
+	RegisterKind(KindShip, func() Entity { return &Ship{} })
+	RegisterKind(KindSpecies, func() Entity { return &Species{} })
+	RegisterKind(KindHomeSystem, func() Entity { return &HomeSystemTemplate{} })
+}
+
+// RegisterKind registers the constructor used to decode entities of a kind.
+// The constructor must return a pointer that json.Unmarshal can decode into.
+// It panics if the kind is already registered.
+func RegisterKind(kind string, newEntity func() Entity) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.m[kind]; ok {
+		panic(fmt.Sprintf("world: kind %q registered twice", kind))
+	}
+	codecs.m[kind] = newEntity
+}
+
+// Kinds returns the registered kinds in sorted order.
+func Kinds() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	var kinds []string
+	for kind := range codecs.m {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Encode serializes an entity for the store.
+// The entity ID must be in the canonical format for its kind.
+func Encode(e Entity) (store.Entity, error) {
+	codecs.RLock()
+	_, ok := codecs.m[e.Kind()]
+	codecs.RUnlock()
+	if !ok {
+		return store.Entity{}, fmt.Errorf("%s: unknown kind %q", e.ID(), e.Kind())
+	}
+	if err := ids.Validate(e.Kind(), e.ID()); err != nil {
+		return store.Entity{}, err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return store.Entity{}, fmt.Errorf("%s: %w", e.ID(), err)
+	}
+	return store.Entity{ID: string(e.ID()), Kind: e.Kind(), Data: data}, nil
+}
+
+// Decode deserializes an entity from the store using the codec for its kind.
+func Decode(se store.Entity) (Entity, error) {
+	codecs.RLock()
+	newEntity, ok := codecs.m[se.Kind]
+	codecs.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown kind %q", se.ID, se.Kind)
+	}
+
+	e := newEntity()
+	if err := json.Unmarshal(se.Data, e); err != nil {
+		return nil, fmt.Errorf("%s: %w", se.ID, err)
+	}
+	if e.ID() != ID(se.ID) {
+		return nil, fmt.Errorf("%s: data has id %q", se.ID, e.ID())
+	}
+	if err := ids.Validate(se.Kind, e.ID()); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EncodeAll serializes entities for store.SaveSnapshot.
+func EncodeAll(entities []Entity) ([]store.Entity, error) {
+	list := make([]store.Entity, 0, len(entities))
+	for _, e := range entities {
+		se, err := Encode(e)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, se)
+	}
+	return list, nil
+}
+
+// DecodeAll deserializes entities from store.LoadSnapshot.
+func DecodeAll(entities []store.Entity) ([]Entity, error) {
+	list := make([]Entity, 0, len(entities))
+	for _, se := range entities {
+		e, err := Decode(se)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
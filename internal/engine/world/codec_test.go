@@ -0,0 +1,155 @@
+package world
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/fh/internal/data/store"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	entities := []Entity{
+		&Galaxy{EntityID: "GALAXY", DNumSpecies: 18, NumSpecies: 12, Radius: 24, TurnNumber: 3},
+		&Star{
+			EntityID:     "STAR:1,2,3",
+			Coords:       Coords{X: 1, Y: 2, Z: 3},
+			Type:         MainSequenceStar,
+			Color:        YellowStar,
+			Size:         4,
+			Planets:      []ID{"PLANET:1,2,3,1", "PLANET:1,2,3,2"},
+			HomeSystem:   true,
+			Wormhole:     true,
+			WormholeExit: Coords{X: 10, Y: 11, Z: 12},
+			VisitedBy:    SpeciesSet{1, 7},
+		},
+		&Planet{
+			EntityID:         "PLANET:1,2,3,1",
+			StarID:           "STAR:1,2,3",
+			Location:         Location{Coords: Coords{X: 1, Y: 2, Z: 3}, Orbit: 1},
+			TemperatureClass: 12,
+			PressureClass:    10,
+			Special:          IdealHomePlanet,
+			Gases:            []GasPercent{{Gas: N2, Percent: 78}, {Gas: O2, Percent: 22}},
+			Diameter:         13,
+			Gravity:          100,
+			MiningDifficulty: 250,
+			MDIncrease:       10,
+			EconEfficiency:   100,
+		},
+		&Colony{
+			EntityID: "COLONY:1:PLANET:1,2,3,1",
+			Name:     "Earth",
+			Species:  1,
+			PlanetID: "PLANET:1,2,3,1",
+			Location: Location{Coords: Coords{X: 1, Y: 2, Z: 3}, Orbit: 1},
+			Status:   HomePlanet | Populated,
+			MIBase:   250,
+			MABase:   300,
+			PopUnits: 1000,
+			Items:    Inventory{IU: 10, CU: 20, PD: 5},
+		},
+		&Ship{
+			EntityID: "SHIP:1:TR1 Alpha",
+			Name:     "Alpha",
+			Species:  1,
+			Location: Location{Coords: Coords{X: 1, Y: 2, Z: 3}, Orbit: 1},
+			Status:   InOrbit,
+			Type:     FTL,
+			Class:    TR,
+			Tonnage:  1,
+			Cargo:    Inventory{CU: 5},
+		},
+		&Species{
+			EntityID:       "SP01",
+			Number:         1,
+			Name:           "Humans",
+			GovtName:       "United Earth",
+			GovtType:       "Democracy",
+			Home:           Location{Coords: Coords{X: 1, Y: 2, Z: 3}, Orbit: 1},
+			RequiredGas:    O2,
+			RequiredGasMin: 10,
+			RequiredGasMax: 30,
+			NeutralGases:   []Gas{H2, He, N2},
+			PoisonGases:    []Gas{Cl2, F2},
+			TechLevel:      TechLevels{MI: 10, MA: 10, ML: 5, GV: 5, LS: 5, BI: 5},
+			Contacts:       SpeciesSet{2, 3},
+			Enemies:        SpeciesSet{3},
+		},
+	}
+
+	encoded, err := EncodeAll(entities)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	for i, se := range encoded {
+		if se.ID != string(entities[i].ID()) || se.Kind != entities[i].Kind() {
+			t.Errorf("entity %d: expected %s/%s, got %s/%s", i, entities[i].Kind(), entities[i].ID(), se.Kind, se.ID)
+		}
+	}
+
+	decoded, err := DecodeAll(encoded)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	for i := range entities {
+		if !reflect.DeepEqual(decoded[i], entities[i]) {
+			t.Errorf("entity %d: round trip mismatch\n got %#v\nwant %#v", i, decoded[i], entities[i])
+		}
+	}
+
+	// enums are stored by code, not by number
+	if data := string(encoded[2].Data); !strings.Contains(data, `"gas":"O2"`) {
+		t.Errorf("expected gas code in planet data, got %s", data)
+	}
+	if data := string(encoded[3].Data); !strings.Contains(data, `"items":{"CU":20,"IU":10,"PD":5}`) {
+		t.Errorf("expected item codes in colony data, got %s", data)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		entity store.Entity
+	}{
+		{"unknown kind", store.Entity{ID: "X", Kind: "fleet", Data: []byte(`{"id":"X"}`)}},
+		{"bad json", store.Entity{ID: "X", Kind: KindStar, Data: []byte(`{`)}},
+		{"id mismatch", store.Entity{ID: "X", Kind: KindStar, Data: []byte(`{"id":"Y"}`)}},
+		{"bad gas", store.Entity{ID: "X", Kind: KindPlanet, Data: []byte(`{"id":"X","gases":[{"gas":"Xe","percent":1}]}`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.entity); err == nil {
+				t.Errorf("Decode() expected error")
+			}
+		})
+	}
+}
+
+func TestSpeciesSet(t *testing.T) {
+	var s SpeciesSet
+	for _, sp := range []int{5, 1, 9, 5, 3} {
+		s.Add(sp)
+	}
+	if !reflect.DeepEqual(s, SpeciesSet{1, 3, 5, 9}) {
+		t.Errorf("expected sorted unique set, got %v", s)
+	}
+	s.Remove(3)
+	s.Remove(4)
+	if s.Has(3) || !s.Has(9) {
+		t.Errorf("unexpected membership after remove: %v", s)
+	}
+}
+
+func TestEncodeRequiresCanonicalID(t *testing.T) {
+	for _, e := range []Entity{
+		&Star{},
+		&Star{EntityID: "STAR:01,2,3"},
+		&Planet{EntityID: "STAR:1,2,3"},
+		&Species{EntityID: "SP1"},
+	} {
+		if _, err := Encode(e); err == nil {
+			t.Errorf("Encode(%s %q) expected error", e.Kind(), e.ID())
+		}
+	}
+}
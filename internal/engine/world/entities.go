@@ -0,0 +1,346 @@
+package world
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Entity kinds. These are stored in store.Entity.Kind.
+const (
+	KindGalaxy  = ids.KindGalaxy
+	KindStar    = ids.KindStar
+	KindPlanet  = ids.KindPlanet
+	KindColony  = ids.KindColony
+	KindShip    = ids.KindShip
+	KindSpecies = ids.KindSpecies
+
+	KindHomeSystem = ids.KindHomeSystem
+)
+
+// Coords are the coordinates of a star system.
+type Coords struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+// String returns the coordinates the way the C reports print them.
+func (c Coords) String() string {
+	return fmt.Sprintf("%d %d %d", c.X, c.Y, c.Z)
+}
+
+// Location is a position within a star system.
+// Orbit is the planet number, starting at 1; zero means deep space.
+type Location struct {
+	Coords
+	Orbit int `json:"pn"`
+}
+
+// String returns the location the way the C reports print it.
+func (l Location) String() string {
+	return fmt.Sprintf("%d %d %d %d", l.X, l.Y, l.Z, l.Orbit)
+}
+
+// SpeciesSet is a sorted set of species numbers.
+// It replaces the C bitmasks (visited_by, contact, ally and enemy).
+type SpeciesSet []int
+
+// Has reports whether the species is in the set.
+func (s SpeciesSet) Has(sp int) bool {
+	i := sort.SearchInts(s, sp)
+	return i < len(s) && s[i] == sp
+}
+
+// Add adds the species to the set.
+func (s *SpeciesSet) Add(sp int) {
+	i := sort.SearchInts(*s, sp)
+	if i < len(*s) && (*s)[i] == sp {
+		return
+	}
+	*s = append(*s, 0)
+	copy((*s)[i+1:], (*s)[i:])
+	(*s)[i] = sp
+}
+
+// Remove removes the species from the set.
+func (s *SpeciesSet) Remove(sp int) {
+	i := sort.SearchInts(*s, sp)
+	if i < len(*s) && (*s)[i] == sp {
+		*s = append((*s)[:i], (*s)[i+1:]...)
+	}
+}
+
+// Clone returns a copy of the set.
+func (s SpeciesSet) Clone() SpeciesSet {
+	if s == nil {
+		return nil
+	}
+	return append(SpeciesSet(nil), s...)
+}
+
+// TechLevels holds one value per technology field.
+type TechLevels struct {
+	MI int `json:"mi"`
+	MA int `json:"ma"`
+	ML int `json:"ml"`
+	GV int `json:"gv"`
+	LS int `json:"ls"`
+	BI int `json:"bi"`
+}
+
+// Get returns the value for the field.
+func (t TechLevels) Get(tech Tech) int {
+	switch tech {
+	case MI:
+		return t.MI
+	case MA:
+		return t.MA
+	case ML:
+		return t.ML
+	case GV:
+		return t.GV
+	case LS:
+		return t.LS
+	case BI:
+		return t.BI
+	}
+	panic(fmt.Sprintf("invalid tech %d", int(tech)))
+}
+
+// Set sets the value for the field.
+func (t *TechLevels) Set(tech Tech, value int) {
+	switch tech {
+	case MI:
+		t.MI = value
+	case MA:
+		t.MA = value
+	case ML:
+		t.ML = value
+	case GV:
+		t.GV = value
+	case LS:
+		t.LS = value
+	case BI:
+		t.BI = value
+	default:
+		panic(fmt.Sprintf("invalid tech %d", int(tech)))
+	}
+}
+
+// Galaxy holds the parameters of the cluster, from galaxy_data.
+// There is exactly one per game.
+type Galaxy struct {
+	EntityID    ID  `json:"id"`
+	DNumSpecies int `json:"d_num_species"` // design number of species
+	NumSpecies  int `json:"num_species"`   // species actually created
+	Radius      int `json:"radius"`        // in parsecs
+	TurnNumber  int `json:"turn_number"`
+}
+
+func (g *Galaxy) ID() ID       { return g.EntityID }
+func (g *Galaxy) Kind() string { return KindGalaxy }
+func (g *Galaxy) String() string {
+	return fmt.Sprintf("galaxy radius %d, %d of %d species, turn %d", g.Radius, g.NumSpecies, g.DNumSpecies, g.TurnNumber)
+}
+
+// Star is a star system, from star_data.
+type Star struct {
+	EntityID ID `json:"id"`
+	Coords
+	Type       StarType  `json:"type"`
+	Color      StarColor `json:"color"`
+	Size       int       `json:"size"`    // 0 through 9
+	Planets    []ID      `json:"planets"` // in orbit order
+	HomeSystem bool      `json:"home_system"`
+	// Wormhole is set when a natural wormhole leads from this system to WormholeExit.
+	Wormhole     bool       `json:"wormhole"`
+	WormholeExit Coords     `json:"wormhole_exit"`
+	VisitedBy    SpeciesSet `json:"visited_by"`
+	Message      int        `json:"message"`
+}
+
+func (s *Star) ID() ID       { return s.EntityID }
+func (s *Star) Kind() string { return KindStar }
+func (s *Star) String() string {
+	return fmt.Sprintf("star %s %s%s%d", s.Coords, s.Type, s.Color, s.Size)
+}
+
+// GasPercent is one gas in a planet's atmosphere.
+type GasPercent struct {
+	Gas     Gas `json:"gas"`
+	Percent int `json:"percent"`
+}
+
+// Planet is a planet orbiting a star, from planet_data.
+// Colonies on the planet are Colony entities whose PlanetID is this planet's ID.
+type Planet struct {
+	EntityID ID `json:"id"`
+	StarID   ID `json:"star_id"`
+	Location
+	TemperatureClass int           `json:"temperature_class"` // 1 through 30
+	PressureClass    int           `json:"pressure_class"`    // 0 through 29
+	Special          PlanetSpecial `json:"special"`
+	Gases            []GasPercent  `json:"gases"`    // at most 4
+	Diameter         int           `json:"diameter"` // thousands of kilometers
+	Gravity          int           `json:"gravity"`  // Earth gravity times 100
+	// MiningDifficulty is the cost multiplier for mining, times 100.
+	// MDIncrease is added to it each turn that the planet is mined.
+	MiningDifficulty int `json:"mining_difficulty"`
+	MDIncrease       int `json:"md_increase"`
+	EconEfficiency   int `json:"econ_efficiency"` // percent, usually 100
+	Message          int `json:"message"`
+}
+
+func (p *Planet) ID() ID       { return p.EntityID }
+func (p *Planet) Kind() string { return KindPlanet }
+func (p *Planet) String() string {
+	return fmt.Sprintf("planet %s temp %d pressure %d", p.Location, p.TemperatureClass, p.PressureClass)
+}
+
+// GasPercent returns the percentage of the gas in the atmosphere.
+func (p *Planet) GasPercent(gas Gas) int {
+	for _, g := range p.Gases {
+		if g.Gas == gas {
+			return g.Percent
+		}
+	}
+	return 0
+}
+
+// Colony is a named planet belonging to a species, from nampla_data.
+type Colony struct {
+	EntityID ID     `json:"id"`
+	Name     string `json:"name"`
+	Species  int    `json:"species"` // species number
+	PlanetID ID     `json:"planet_id"`
+	Location
+	Status       ColonyStatus `json:"status"`
+	Hiding       bool         `json:"hiding"`
+	Hidden       bool         `json:"hidden"`
+	SiegeEff     int          `json:"siege_eff"`
+	Shipyards    int          `json:"shipyards"`
+	IUsNeeded    int          `json:"ius_needed"`
+	AUsNeeded    int          `json:"aus_needed"`
+	AutoIUs      int          `json:"auto_ius"`
+	AutoAUs      int          `json:"auto_aus"`
+	IUsToInstall int          `json:"ius_to_install"`
+	AUsToInstall int          `json:"aus_to_install"`
+	MIBase       int          `json:"mi_base"` // tenths
+	MABase       int          `json:"ma_base"` // tenths
+	PopUnits     int          `json:"pop_units"`
+	Items        Inventory    `json:"items"`
+	UseOnAmbush  int          `json:"use_on_ambush"`
+	Message      int          `json:"message"`
+	Special      int          `json:"special"`
+}
+
+func (c *Colony) ID() ID       { return c.EntityID }
+func (c *Colony) Kind() string { return KindColony }
+func (c *Colony) String() string {
+	return fmt.Sprintf("PL %s at %s (SP%02d)", c.Name, c.Location, c.Species)
+}
+
+// Ship is a ship or starbase belonging to a species, from ship_data.
+type Ship struct {
+	EntityID ID     `json:"id"`
+	Name     string `json:"name"`
+	Species  int    `json:"species"` // species number
+	Location
+	Status             ShipStatus `json:"status"`
+	Type               ShipType   `json:"type"`
+	Dest               Coords     `json:"dest"`
+	JustJumped         bool       `json:"just_jumped"`
+	ArrivedViaWormhole bool       `json:"arrived_via_wormhole"`
+	Class              ShipClass  `json:"class"`
+	Tonnage            int        `json:"tonnage"` // in units of 10,000 tons
+	Cargo              Inventory  `json:"cargo"`
+	Age                int        `json:"age"`
+	RemainingCost      int        `json:"remaining_cost"`
+	LoadingPoint       int        `json:"loading_point"`
+	UnloadingPoint     int        `json:"unloading_point"`
+	Special            int        `json:"special"`
+}
+
+func (s *Ship) ID() ID       { return s.EntityID }
+func (s *Ship) Kind() string { return KindShip }
+func (s *Ship) String() string {
+	return fmt.Sprintf("%s %s at %s (SP%02d)", s.Class, s.Name, s.Location, s.Species)
+}
+
+// Species is a player species, from species_data.
+type Species struct {
+	EntityID ID       `json:"id"`
+	Number   int      `json:"number"` // 1 through MAX_SPECIES
+	Name     string   `json:"name"`
+	Email    string   `json:"email"` // where the player's reports are sent
+	GovtName string   `json:"govt_name"`
+	GovtType string   `json:"govt_type"`
+	Home     Location `json:"home"`
+	// RequiredGas must be present at between RequiredGasMin and RequiredGasMax percent.
+	RequiredGas      Gas        `json:"required_gas"`
+	RequiredGasMin   int        `json:"required_gas_min"`
+	RequiredGasMax   int        `json:"required_gas_max"`
+	NeutralGases     []Gas      `json:"neutral_gases"`
+	PoisonGases      []Gas      `json:"poison_gases"`
+	AutoOrders       bool       `json:"auto_orders"`
+	TechLevel        TechLevels `json:"tech_level"`
+	InitTechLevel    TechLevels `json:"init_tech_level"`
+	TechKnowledge    TechLevels `json:"tech_knowledge"`
+	TechEPs          TechLevels `json:"tech_eps"`
+	HPOriginalBase   int        `json:"hp_original_base"`
+	EconUnits        int        `json:"econ_units"`
+	FleetCost        int        `json:"fleet_cost"`
+	FleetPercentCost int        `json:"fleet_percent_cost"`
+	Contacts         SpeciesSet `json:"contacts"`
+	Allies           SpeciesSet `json:"allies"`
+	Enemies          SpeciesSet `json:"enemies"`
+}
+
+func (s *Species) ID() ID       { return s.EntityID }
+func (s *Species) Kind() string { return KindSpecies }
+func (s *Species) String() string {
+	return fmt.Sprintf("SP%02d %s", s.Number, s.Name)
+}
+
+// IsPoison reports whether the gas is poisonous to the species.
+func (s *Species) IsPoison(gas Gas) bool {
+	for _, g := range s.PoisonGases {
+		if g == gas {
+			return true
+		}
+	}
+	return false
+}
+
+// HomeSystemTemplate is a balanced home system, like the templates of the C game.
+// There is one template for each number of planets a home system can have.
+// When a species is created, the planets of its home system are replaced
+// with the planets of the template.
+type HomeSystemTemplate struct {
+	EntityID   ID  `json:"id"`
+	NumPlanets int `json:"num_planets"`
+	// Planets are in orbit order. Only the orbit and the physical fields
+	// are set; the planet IDs and coordinates come from the home star.
+	Planets []Planet `json:"planets"`
+	// Potential measures how easily the other planets can be colonized
+	// from the home planet. Balanced templates have similar potentials.
+	Potential int `json:"potential"`
+}
+
+func (t *HomeSystemTemplate) ID() ID       { return t.EntityID }
+func (t *HomeSystemTemplate) Kind() string { return KindHomeSystem }
+func (t *HomeSystemTemplate) String() string {
+	return fmt.Sprintf("home system template with %d planets, potential %d", t.NumPlanets, t.Potential)
+}
+
+// HomePlanet returns the ideal home planet of the template, or nil.
+func (t *HomeSystemTemplate) HomePlanet() *Planet {
+	for i := range t.Planets {
+		if t.Planets[i].Special == IdealHomePlanet {
+			return &t.Planets[i]
+		}
+	}
+	return nil
+}
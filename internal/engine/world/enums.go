@@ -0,0 +1,388 @@
+package world
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StarType is the type of a star, from star_data.type.
+type StarType int
+
+const (
+	DwarfStar        StarType = 1
+	DegenerateStar   StarType = 2
+	MainSequenceStar StarType = 3
+	GiantStar        StarType = 4
+)
+
+// String returns the code the C reports use for the star type.
+func (t StarType) String() string {
+	switch t {
+	case DwarfStar:
+		return "d"
+	case DegenerateStar:
+		return "D"
+	case MainSequenceStar:
+		return " "
+	case GiantStar:
+		return "g"
+	}
+	return fmt.Sprintf("StarType(%d)", int(t))
+}
+
+// StarColor is the spectral class of a star, from star_data.color.
+type StarColor int
+
+const (
+	BlueStar        StarColor = 1
+	BlueWhiteStar   StarColor = 2
+	WhiteStar       StarColor = 3
+	YellowWhiteStar StarColor = 4
+	YellowStar      StarColor = 5
+	OrangeStar      StarColor = 6
+	RedStar         StarColor = 7
+)
+
+// String returns the spectral class letter for the color.
+func (c StarColor) String() string {
+	if c >= BlueStar && c <= RedStar {
+		return string(" OBAFGKM"[c])
+	}
+	return fmt.Sprintf("StarColor(%d)", int(c))
+}
+
+// Gas is an atmospheric gas.
+// Gases marshal to JSON as their chemical formula, e.g. "O2".
+type Gas int
+
+const (
+	NoGas Gas = iota
+	H2
+	CH4
+	He
+	NH3
+	N2
+	CO2
+	O2
+	HCl
+	Cl2
+	F2
+	H2O
+	SO2
+	H2S
+)
+
+// NumGases is the number of gases, not counting NoGas.
+const NumGases = 13
+
+var gasCodes = [...]string{"", "H2", "CH4", "He", "NH3", "N2", "CO2", "O2", "HCl", "Cl2", "F2", "H2O", "SO2", "H2S"}
+
+// String returns the chemical formula for the gas.
+func (g Gas) String() string {
+	if g >= NoGas && int(g) < len(gasCodes) {
+		return gasCodes[g]
+	}
+	return fmt.Sprintf("Gas(%d)", int(g))
+}
+
+// ParseGas returns the gas with the given chemical formula.
+// Matching is case-insensitive.
+func ParseGas(s string) (Gas, error) {
+	for g := H2; g <= H2S; g++ {
+		if strings.EqualFold(gasCodes[g], s) {
+			return g, nil
+		}
+	}
+	return NoGas, fmt.Errorf("unknown gas %q", s)
+}
+
+func (g Gas) MarshalText() ([]byte, error) {
+	if g < NoGas || int(g) >= len(gasCodes) {
+		return nil, fmt.Errorf("invalid gas %d", int(g))
+	}
+	return []byte(gasCodes[g]), nil
+}
+
+func (g *Gas) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*g = NoGas
+		return nil
+	}
+	gas, err := ParseGas(string(text))
+	if err != nil {
+		return err
+	}
+	*g = gas
+	return nil
+}
+
+// Item is a kind of item carried by ships or stored on colonies.
+// Items marshal to JSON as their abbreviation, e.g. "IU".
+type Item int
+
+const (
+	RM  Item = iota // Raw Material Units
+	PD              // Planetary Defense Units
+	SU              // Starbase Units
+	DR              // Damage Repair Units
+	CU              // Colonist Units
+	IU              // Colonial Mining Units
+	AU              // Colonial Manufacturing Units
+	FS              // Fail-Safe Jump Units
+	JP              // Jump Portal Units
+	FM              // Forced Misjump Units
+	FJ              // Forced Jump Units
+	GT              // Gravitic Telescope Units
+	FD              // Field Distortion Units
+	TP              // Terraforming Plants
+	GW              // Germ Warfare Bombs
+	SG1             // Mark-1 Shield Generators
+	SG2
+	SG3
+	SG4
+	SG5
+	SG6
+	SG7
+	SG8
+	SG9
+	GU1 // Mark-1 Gun Units
+	GU2
+	GU3
+	GU4
+	GU5
+	GU6
+	GU7
+	GU8
+	GU9
+	X1 // unassigned
+	X2
+	X3
+	X4
+	X5
+)
+
+// NumItems is the number of item kinds, MAX_ITEMS in the C source.
+const NumItems = 38
+
+var itemCodes = [NumItems]string{
+	"RM", "PD", "SU", "DR", "CU", "IU", "AU", "FS", "JP", "FM", "FJ", "GT", "FD", "TP", "GW",
+	"SG1", "SG2", "SG3", "SG4", "SG5", "SG6", "SG7", "SG8", "SG9",
+	"GU1", "GU2", "GU3", "GU4", "GU5", "GU6", "GU7", "GU8", "GU9",
+	"X1", "X2", "X3", "X4", "X5",
+}
+
+// String returns the abbreviation for the item.
+func (i Item) String() string {
+	if i >= 0 && i < NumItems {
+		return itemCodes[i]
+	}
+	return fmt.Sprintf("Item(%d)", int(i))
+}
+
+// ParseItem returns the item with the given abbreviation.
+// Matching is case-insensitive.
+func ParseItem(s string) (Item, error) {
+	for i, code := range itemCodes {
+		if strings.EqualFold(code, s) {
+			return Item(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown item %q", s)
+}
+
+func (i Item) MarshalText() ([]byte, error) {
+	if i < 0 || i >= NumItems {
+		return nil, fmt.Errorf("invalid item %d", int(i))
+	}
+	return []byte(itemCodes[i]), nil
+}
+
+func (i *Item) UnmarshalText(text []byte) error {
+	item, err := ParseItem(string(text))
+	if err != nil {
+		return err
+	}
+	*i = item
+	return nil
+}
+
+// Inventory holds item quantities. Items with a zero quantity are not stored.
+type Inventory map[Item]int
+
+// Get returns the quantity of the item.
+func (inv Inventory) Get(i Item) int {
+	return inv[i]
+}
+
+// Set sets the quantity of the item, removing it when qty is zero.
+func (inv Inventory) Set(i Item, qty int) {
+	if qty == 0 {
+		delete(inv, i)
+		return
+	}
+	inv[i] = qty
+}
+
+// Clone returns a copy of the inventory.
+func (inv Inventory) Clone() Inventory {
+	if inv == nil {
+		return nil
+	}
+	clone := make(Inventory, len(inv))
+	for i, qty := range inv {
+		clone[i] = qty
+	}
+	return clone
+}
+
+// ShipClass is the class of a ship.
+// Classes marshal to JSON as their abbreviation, e.g. "TR".
+type ShipClass int
+
+const (
+	PB ShipClass = iota // Picketboat
+	CT                  // Corvette
+	ES                  // Escort
+	FF                  // Frigate
+	DD                  // Destroyer
+	CL                  // Light Cruiser
+	CS                  // Strike Cruiser
+	CA                  // Heavy Cruiser
+	CC                  // Command Cruiser
+	BC                  // Battlecruiser
+	BS                  // Battleship
+	DN                  // Dreadnought
+	SD                  // Super Dreadnought
+	BM                  // Battlemoon
+	BW                  // Battleworld
+	BR                  // Battlestar
+	BA                  // Starbase
+	TR                  // Transport
+)
+
+// NumShipClasses is the number of ship classes.
+const NumShipClasses = 18
+
+var shipClassCodes = [NumShipClasses]string{
+	"PB", "CT", "ES", "FF", "DD", "CL", "CS", "CA", "CC", "BC", "BS", "DN", "SD", "BM", "BW", "BR", "BA", "TR",
+}
+
+// shipClassTonnage is the tonnage of each class in units of 10,000 tons.
+// Starbases and transports are built to order, so their tonnage is per unit.
+var shipClassTonnage = [NumShipClasses]int{1, 2, 5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65, 70, 1, 1}
+
+// String returns the abbreviation for the class.
+func (c ShipClass) String() string {
+	if c >= 0 && c < NumShipClasses {
+		return shipClassCodes[c]
+	}
+	return fmt.Sprintf("ShipClass(%d)", int(c))
+}
+
+// Tonnage returns the standard tonnage of the class in units of 10,000 tons.
+func (c ShipClass) Tonnage() int {
+	if c >= 0 && c < NumShipClasses {
+		return shipClassTonnage[c]
+	}
+	return 0
+}
+
+// ParseShipClass returns the class with the given abbreviation.
+// Matching is case-insensitive.
+func ParseShipClass(s string) (ShipClass, error) {
+	for c, code := range shipClassCodes {
+		if strings.EqualFold(code, s) {
+			return ShipClass(c), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown ship class %q", s)
+}
+
+func (c ShipClass) MarshalText() ([]byte, error) {
+	if c < 0 || c >= NumShipClasses {
+		return nil, fmt.Errorf("invalid ship class %d", int(c))
+	}
+	return []byte(shipClassCodes[c]), nil
+}
+
+func (c *ShipClass) UnmarshalText(text []byte) error {
+	class, err := ParseShipClass(string(text))
+	if err != nil {
+		return err
+	}
+	*c = class
+	return nil
+}
+
+// ShipType is the drive type of a ship.
+type ShipType int
+
+const (
+	FTL      ShipType = 0
+	SubLight ShipType = 1
+	Starbase ShipType = 2
+)
+
+// ShipStatus is the position of a ship.
+type ShipStatus int
+
+const (
+	UnderConstruction ShipStatus = 0
+	OnSurface         ShipStatus = 1
+	InOrbit           ShipStatus = 2
+	InDeepSpace       ShipStatus = 3
+	JumpedInCombat    ShipStatus = 4
+	ForcedJump        ShipStatus = 5
+)
+
+// ColonyStatus is a bitmask describing a colony.
+type ColonyStatus int
+
+const (
+	HomePlanet      ColonyStatus = 1
+	ColonyPlanet    ColonyStatus = 2
+	Populated       ColonyStatus = 8
+	MiningColony    ColonyStatus = 16
+	ResortColony    ColonyStatus = 32
+	DisbandedColony ColonyStatus = 64
+)
+
+// Has reports whether all the bits in flag are set.
+func (s ColonyStatus) Has(flag ColonyStatus) bool {
+	return s&flag == flag
+}
+
+// PlanetSpecial marks planets created for a specific purpose.
+type PlanetSpecial int
+
+const (
+	NotSpecial          PlanetSpecial = 0
+	IdealHomePlanet     PlanetSpecial = 1
+	IdealColonyPlanet   PlanetSpecial = 2
+	RadioactiveHellhole PlanetSpecial = 3
+)
+
+// Tech is a technology field.
+type Tech int
+
+const (
+	MI Tech = iota // Mining
+	MA             // Manufacturing
+	ML             // Military
+	GV             // Gravitics
+	LS             // Life Support
+	BI             // Biology
+)
+
+// NumTechs is the number of technology fields.
+const NumTechs = 6
+
+var techCodes = [NumTechs]string{"MI", "MA", "ML", "GV", "LS", "BI"}
+
+// String returns the abbreviation for the technology.
+func (t Tech) String() string {
+	if t >= 0 && t < NumTechs {
+		return techCodes[t]
+	}
+	return fmt.Sprintf("Tech(%d)", int(t))
+}
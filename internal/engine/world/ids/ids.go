@@ -0,0 +1,420 @@
+// Package ids defines the canonical entity ID formats.
+//
+// IDs are derived from the identity of an entity, never from its position
+// in a list, so they stay the same from turn to turn and can be used as
+// store keys and as RNG keys:
+//
+//	GALAXY                          the galaxy
+//	STAR:x,y,z                      a star, by coordinates
+//	PLANET:x,y,z,orbit              a planet, by star and orbit
+//	COLONY:sp:PLANET:x,y,z,orbit    a species' colony on a planet
+//	SHIP:sp:name                    a ship, by species number and name
+//	SPnn                            a species, by number
+//	HOMESYSTEM:n                    the home system template with n planets
+package ids
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ID is a stable identifier for an entity.
+type ID string
+
+// Entity kinds, as stored in store.Entity.Kind.
+const (
+	KindGalaxy  = "galaxy"
+	KindStar    = "star"
+	KindPlanet  = "planet"
+	KindColony  = "colony"
+	KindShip    = "ship"
+	KindSpecies = "species"
+	// KindHomeSystem is a home system template, which is not part of the galaxy.
+	KindHomeSystem = "home_system"
+)
+
+// kindOrder is the sort order of the kinds. Unknown kinds sort last.
+var kindOrder = map[string]int{
+	KindGalaxy:     1,
+	KindStar:       2,
+	KindPlanet:     3,
+	KindHomeSystem: 4,
+	KindSpecies:    5,
+	KindColony:     6,
+	KindShip:       7,
+}
+
+// Parts are the fields encoded in an ID.
+// Only the fields used by the kind are set; for a species, Species is its number.
+type Parts struct {
+	Kind       string
+	X, Y, Z    int
+	Orbit      int
+	Species    int
+	Name       string
+	NumPlanets int // home system templates
+}
+
+// Galaxy returns the ID of the galaxy.
+func Galaxy() ID {
+	return "GALAXY"
+}
+
+// Star returns the ID of the star at the coordinates.
+func Star(x, y, z int) ID {
+	return ID(fmt.Sprintf("STAR:%d,%d,%d", x, y, z))
+}
+
+// Planet returns the ID of the planet in the orbit of the star at the coordinates.
+func Planet(x, y, z, orbit int) ID {
+	return ID(fmt.Sprintf("PLANET:%d,%d,%d,%d", x, y, z, orbit))
+}
+
+// Colony returns the ID of a species' colony on a planet.
+func Colony(species int, planet ID) ID {
+	return ID(fmt.Sprintf("COLONY:%d:%s", species, planet))
+}
+
+// Ship returns the ID of a species' ship.
+func Ship(species int, name string) ID {
+	return ID(fmt.Sprintf("SHIP:%d:%s", species, name))
+}
+
+// Species returns the ID of the species with the number.
+func Species(number int) ID {
+	return ID(fmt.Sprintf("SP%02d", number))
+}
+
+// HomeSystem returns the ID of the home system template with the number of planets.
+func HomeSystem(numPlanets int) ID {
+	return ID(fmt.Sprintf("HOMESYSTEM:%d", numPlanets))
+}
+
+// ID returns the canonical ID for the parts.
+func (p Parts) ID() ID {
+	switch p.Kind {
+	case KindGalaxy:
+		return Galaxy()
+	case KindStar:
+		return Star(p.X, p.Y, p.Z)
+	case KindPlanet:
+		return Planet(p.X, p.Y, p.Z, p.Orbit)
+	case KindColony:
+		return Colony(p.Species, Planet(p.X, p.Y, p.Z, p.Orbit))
+	case KindShip:
+		return Ship(p.Species, p.Name)
+	case KindSpecies:
+		return Species(p.Species)
+	case KindHomeSystem:
+		return HomeSystem(p.NumPlanets)
+	}
+	return ""
+}
+
+// Parse returns the parts of a canonical ID.
+// It returns an error if the ID is malformed or not in canonical form,
+// for example "STAR:01,2,3".
+func Parse(id ID) (Parts, error) {
+	p, err := parse(string(id))
+	if err != nil {
+		return Parts{}, fmt.Errorf("invalid id %q: %w", id, err)
+	}
+	if canonical := p.ID(); canonical != id {
+		return Parts{}, fmt.Errorf("invalid id %q: canonical form is %q", id, canonical)
+	}
+	return p, nil
+}
+
+func parse(s string) (Parts, error) {
+	switch {
+	case s == "GALAXY":
+		return Parts{Kind: KindGalaxy}, nil
+	case strings.HasPrefix(s, "STAR:"):
+		n, err := ints(strings.TrimPrefix(s, "STAR:"), 3)
+		if err != nil {
+			return Parts{}, err
+		}
+		return Parts{Kind: KindStar, X: n[0], Y: n[1], Z: n[2]}, nil
+	case strings.HasPrefix(s, "PLANET:"):
+		n, err := ints(strings.TrimPrefix(s, "PLANET:"), 4)
+		if err != nil {
+			return Parts{}, err
+		}
+		if n[3] < 1 {
+			return Parts{}, fmt.Errorf("orbit must be positive")
+		}
+		return Parts{Kind: KindPlanet, X: n[0], Y: n[1], Z: n[2], Orbit: n[3]}, nil
+	case strings.HasPrefix(s, "COLONY:"):
+		sp, rest, ok := strings.Cut(strings.TrimPrefix(s, "COLONY:"), ":")
+		if !ok {
+			return Parts{}, fmt.Errorf("missing planet")
+		}
+		n, err := number(sp)
+		if err != nil {
+			return Parts{}, err
+		}
+		p, err := parse(rest)
+		if err != nil {
+			return Parts{}, err
+		}
+		if p.Kind != KindPlanet {
+			return Parts{}, fmt.Errorf("colony must be on a planet")
+		}
+		p.Kind, p.Species = KindColony, n
+		return p, nil
+	case strings.HasPrefix(s, "SHIP:"):
+		sp, name, ok := strings.Cut(strings.TrimPrefix(s, "SHIP:"), ":")
+		if !ok {
+			return Parts{}, fmt.Errorf("missing name")
+		}
+		n, err := number(sp)
+		if err != nil {
+			return Parts{}, err
+		}
+		if err := ValidName(name); err != nil {
+			return Parts{}, err
+		}
+		return Parts{Kind: KindShip, Species: n, Name: name}, nil
+	case strings.HasPrefix(s, "HOMESYSTEM:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "HOMESYSTEM:"))
+		if err != nil || n < 1 {
+			return Parts{}, fmt.Errorf("bad number of planets")
+		}
+		return Parts{Kind: KindHomeSystem, NumPlanets: n}, nil
+	case strings.HasPrefix(s, "SP"):
+		n, err := number(strings.TrimPrefix(s, "SP"))
+		if err != nil {
+			return Parts{}, err
+		}
+		return Parts{Kind: KindSpecies, Species: n}, nil
+	}
+	return Parts{}, fmt.Errorf("unknown format")
+}
+
+// ints parses a comma separated list of count integers.
+func ints(s string, count int) ([]int, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d numbers, got %d", count, len(fields))
+	}
+	n := make([]int, count)
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", f)
+		}
+		n[i] = v
+	}
+	return n, nil
+}
+
+// number parses a species number.
+func number(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("bad species number %q", s)
+	}
+	return n, nil
+}
+
+// ValidName reports whether a name can be used in an ID.
+// Names must not be empty, must not start or end with a space, and must
+// not contain control characters or '|', which separates RNG keys.
+func ValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty name")
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("name %q has leading or trailing space", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '|' {
+			return fmt.Errorf("name %q contains %q", name, r)
+		}
+	}
+	return nil
+}
+
+// KindOf returns the kind encoded in the ID, or an empty string if the ID
+// is not in a canonical format.
+func KindOf(id ID) string {
+	p, err := Parse(id)
+	if err != nil {
+		return ""
+	}
+	return p.Kind
+}
+
+// Validate checks that the ID is canonical and belongs to an entity of the kind.
+// IDs of kinds that this package does not define are not checked.
+func Validate(kind string, id ID) error {
+	if _, ok := kindOrder[kind]; !ok {
+		if id == "" {
+			return fmt.Errorf("%s: missing id", kind)
+		}
+		return nil
+	}
+	p, err := Parse(id)
+	if err != nil {
+		return err
+	}
+	if p.Kind != kind {
+		return fmt.Errorf("id %q is a %s id, not a %s id", id, p.Kind, kind)
+	}
+	return nil
+}
+
+// Compare orders IDs by kind, then by species, then by coordinates and
+// orbit, then by name. Numbers compare numerically, so "STAR:9,0,0" sorts
+// before "STAR:10,0,0". IDs that are not canonical sort after all others,
+// in byte order. It returns -1, 0 or +1.
+//
+// Compare parses both IDs. To compare an ID many times, compare its Key.
+func Compare(a, b ID) int {
+	return KeyOf(a).Compare(KeyOf(b))
+}
+
+// Sort sorts IDs into the order defined by Compare.
+// Each ID is parsed once.
+func Sort(list []ID) {
+	keyed := make([]struct {
+		key Key
+		id  ID
+	}, len(list))
+	for i, id := range list {
+		keyed[i].key, keyed[i].id = KeyOf(id), id
+	}
+	sort.SliceStable(keyed, func(i, j int) bool { return keyed[i].key.Compare(keyed[j].key) < 0 })
+	for i := range keyed {
+		list[i] = keyed[i].id
+	}
+}
+
+// Key is the sort key of an ID: its parts in the order used by Compare.
+// Comparing keys does not parse or allocate.
+type Key struct {
+	rank                       int // kind order; IDs that are not canonical rank last
+	species, x, y, z, orbit, n int
+	name                       string // the name, or the whole ID if it is not canonical
+}
+
+// rankInvalid is the rank of IDs that are not canonical.
+const rankInvalid = 8
+
+// KeyOf returns the sort key of the ID.
+func KeyOf(id ID) Key {
+	k, ok := key(string(id))
+	if !ok {
+		return Key{rank: rankInvalid, name: string(id)}
+	}
+	return k
+}
+
+// Compare compares keys in the order of the IDs they were made from.
+// It returns -1, 0 or +1.
+func (k Key) Compare(o Key) int {
+	for _, d := range [...]int{
+		k.rank - o.rank,
+		k.species - o.species,
+		k.x - o.x,
+		k.y - o.y,
+		k.z - o.z,
+		k.orbit - o.orbit,
+		k.n - o.n,
+	} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+	return strings.Compare(k.name, o.name)
+}
+
+// key parses a canonical ID into its sort key. It accepts exactly the IDs
+// that Parse accepts, without formatting the canonical form to check them.
+func key(s string) (Key, bool) {
+	switch {
+	case s == "GALAXY":
+		return Key{rank: kindOrder[KindGalaxy]}, true
+	case strings.HasPrefix(s, "STAR:"):
+		var k Key
+		ok := coords(s[len("STAR:"):], &k.x, &k.y, &k.z)
+		k.rank = kindOrder[KindStar]
+		return k, ok
+	case strings.HasPrefix(s, "PLANET:"):
+		var k Key
+		ok := coords(s[len("PLANET:"):], &k.x, &k.y, &k.z, &k.orbit) && k.orbit >= 1
+		k.rank = kindOrder[KindPlanet]
+		return k, ok
+	case strings.HasPrefix(s, "COLONY:"):
+		sp, rest, ok := strings.Cut(s[len("COLONY:"):], ":")
+		if !ok || !strings.HasPrefix(rest, "PLANET:") {
+			return Key{}, false
+		}
+		k, ok := key(rest)
+		k.rank, k.species = kindOrder[KindColony], positive(sp)
+		return k, ok && k.species > 0
+	case strings.HasPrefix(s, "SHIP:"):
+		sp, name, ok := strings.Cut(s[len("SHIP:"):], ":")
+		k := Key{rank: kindOrder[KindShip], species: positive(sp), name: name}
+		return k, ok && k.species > 0 && ValidName(name) == nil
+	case strings.HasPrefix(s, "HOMESYSTEM:"):
+		k := Key{rank: kindOrder[KindHomeSystem], n: positive(s[len("HOMESYSTEM:"):])}
+		return k, k.n > 0
+	case strings.HasPrefix(s, "SP"):
+		digits := s[len("SP"):]
+		k := Key{rank: kindOrder[KindSpecies]}
+		// species 1 to 9 have a leading zero, as in "SP01"
+		if len(digits) == 2 && digits[0] == '0' {
+			k.species = positive(digits[1:])
+			return k, k.species > 0
+		}
+		k.species = positive(digits)
+		return k, k.species >= 10
+	}
+	return Key{}, false
+}
+
+// coords parses a comma separated list of canonical integers into dst.
+func coords(s string, dst ...*int) bool {
+	for i, p := range dst {
+		f, rest, found := strings.Cut(s, ",")
+		if found == (i == len(dst)-1) {
+			return false
+		}
+		v, ok := integer(f)
+		if !ok {
+			return false
+		}
+		*p, s = v, rest
+	}
+	return true
+}
+
+// positive returns the canonical positive integer in s, or 0 if s is not one.
+func positive(s string) int {
+	if v, ok := integer(s); ok && v > 0 {
+		return v
+	}
+	return 0
+}
+
+// integer parses an integer as formatted by %d: no sign other than a
+// leading '-', and no leading zeros.
+func integer(s string) (int, bool) {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || (digits[0] == '0' && (len(digits) > 1 || len(s) > 1)) {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+	}
+	v, err := strconv.Atoi(s)
+	return v, err == nil
+}
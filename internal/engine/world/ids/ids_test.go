@@ -0,0 +1,171 @@
+package ids
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		id   ID
+		want Parts
+	}{
+		{Galaxy(), Parts{Kind: KindGalaxy}},
+		{Star(10, 4, 7), Parts{Kind: KindStar, X: 10, Y: 4, Z: 7}},
+		{Planet(10, 4, 7, 3), Parts{Kind: KindPlanet, X: 10, Y: 4, Z: 7, Orbit: 3}},
+		{Colony(2, Planet(10, 4, 7, 3)), Parts{Kind: KindColony, Species: 2, X: 10, Y: 4, Z: 7, Orbit: 3}},
+		{Ship(12, "TR1 Alpha: the first"), Parts{Kind: KindShip, Species: 12, Name: "TR1 Alpha: the first"}},
+		{Species(7), Parts{Kind: KindSpecies, Species: 7}},
+		{Species(100), Parts{Kind: KindSpecies, Species: 100}},
+		{HomeSystem(9), Parts{Kind: KindHomeSystem, NumPlanets: 9}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.id)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.id, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.id, got, tt.want)
+		}
+		if id := got.ID(); id != tt.id {
+			t.Errorf("Parse(%q).ID() = %q", tt.id, id)
+		}
+		if err := Validate(tt.want.Kind, tt.id); err != nil {
+			t.Errorf("Validate(%s, %q) error = %v", tt.want.Kind, tt.id, err)
+		}
+	}
+	if got := string(Species(1)); got != "SP01" {
+		t.Errorf("Species(1) = %q, want SP01", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, id := range []ID{
+		"",
+		"galaxy",
+		"STAR:1,2",
+		"STAR:01,2,3",
+		"STAR: 1,2,3",
+		"STAR:1,2,x",
+		"PLANET:1,2,3,0",
+		"COLONY:1",
+		"COLONY:0:PLANET:1,2,3,1",
+		"COLONY:1:STAR:1,2,3",
+		"SHIP:1",
+		"SHIP:1:",
+		"SHIP:1: Alpha",
+		"SHIP:1:Al|pha",
+		"SHIP:x:Alpha",
+		"SP1",
+		"SP00",
+		"SP-1",
+		"HOMESYSTEM:0",
+		"HOMESYSTEM:03",
+	} {
+		if _, err := Parse(id); err == nil {
+			t.Errorf("Parse(%q) expected error", id)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(KindPlanet, Star(1, 2, 3)); err == nil {
+		t.Errorf("Validate() expected error for kind mismatch")
+	}
+	if err := Validate("template", "TEMPLATE:3"); err != nil {
+		t.Errorf("Validate() of unknown kind error = %v", err)
+	}
+	if err := Validate("template", ""); err == nil {
+		t.Errorf("Validate() expected error for missing id")
+	}
+}
+
+func TestSort(t *testing.T) {
+	list := []ID{
+		"bogus",
+		Ship(2, "Beta"),
+		Ship(10, "Alpha"),
+		Colony(1, Planet(1, 2, 3, 1)),
+		Species(10),
+		Species(9),
+		HomeSystem(4),
+		HomeSystem(3),
+		Planet(10, 0, 0, 1),
+		Planet(9, 0, 0, 2),
+		Star(10, 0, 0),
+		Star(9, 0, 0),
+		Galaxy(),
+	}
+	want := []ID{
+		"GALAXY",
+		"STAR:9,0,0",
+		"STAR:10,0,0",
+		"PLANET:9,0,0,2",
+		"PLANET:10,0,0,1",
+		"HOMESYSTEM:3",
+		"HOMESYSTEM:4",
+		"SP09",
+		"SP10",
+		"COLONY:1:PLANET:1,2,3,1",
+		"SHIP:2:Beta",
+		"SHIP:10:Alpha",
+		"bogus",
+	}
+	Sort(list)
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("Sort() = %q\nwant %q", list, want)
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	for _, id := range []ID{
+		Galaxy(),
+		Star(-10, 0, 7),
+		Planet(10, 4, 7, 3),
+		Colony(2, Planet(10, 4, 7, 3)),
+		Ship(12, "TR1 Alpha: the first"),
+		Species(7),
+		Species(100),
+		HomeSystem(9),
+		"",
+		"STAR:1,2",
+		"STAR:1,2,3,4",
+		"STAR:01,2,3",
+		"STAR:-0,2,3",
+		"STAR:+1,2,3",
+		"STAR:1,,3",
+		"STAR:99999999999999999999,2,3",
+		"PLANET:1,2,3,0",
+		"COLONY:1:STAR:1,2,3",
+		"COLONY:01:PLANET:1,2,3,1",
+		"SHIP:1: Alpha",
+		"SHIP:-1:Alpha",
+		"SP1",
+		"SP05",
+		"SP010",
+		"SPACE",
+		"HOMESYSTEM:03",
+	} {
+		p, err := Parse(id)
+		k, ok := key(string(id))
+		if ok != (err == nil) {
+			t.Errorf("key(%q) ok = %v, Parse error = %v", id, ok, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		want := Key{kindOrder[p.Kind], p.Species, p.X, p.Y, p.Z, p.Orbit, p.NumPlanets, p.Name}
+		if k != want {
+			t.Errorf("KeyOf(%q) = %+v, want %+v", id, k, want)
+		}
+	}
+}
+
+func BenchmarkCompare(b *testing.B) {
+	x, y := Colony(12, Planet(10, 4, 7, 3)), Colony(12, Planet(10, 4, 7, 4))
+	for b.Loop() {
+		Compare(x, y)
+	}
+}
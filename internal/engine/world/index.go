@@ -0,0 +1,364 @@
+package world
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// Queries are the indexed lookups available on every world view.
+// Results are sorted by entity ID, in the order of ids.Compare.
+type Queries interface {
+	// ByKind returns all entities of the kind.
+	ByKind(kind string) []Entity
+	// ByOwner returns the colonies and ships of a species, and the species itself.
+	ByOwner(species int) []Entity
+	// At returns all entities in the star system at the coordinates.
+	At(c Coords) []Entity
+	// ByName returns the entities of the kind with the name.
+	// Names are matched without regard to case.
+	ByName(kind, name string) []Entity
+
+	// Species returns the species with the number.
+	Species(number int) (*Species, bool)
+	// ShipsAt returns the ships of a species in the star system at the coordinates.
+	ShipsAt(species int, c Coords) []*Ship
+	// ColoniesOf returns the named planets of a species.
+	ColoniesOf(species int) []*Colony
+	// ColoniesOnPlanet returns the colonies of every species on the planet.
+	ColoniesOnPlanet(planet ID) []*Colony
+
+	// StarAt returns the star at the coordinates.
+	StarAt(c Coords) (*Star, bool)
+	// NearestStar returns the star closest to the coordinates.
+	NearestStar(c Coords) (*Star, bool)
+	// StarsWithin returns the stars no further than radius parsecs from the coordinates.
+	StarsWithin(c Coords, radius float64) []*Star
+	// Within returns the stars, planets, colonies and ships no further than
+	// radius parsecs from the coordinates.
+	Within(c Coords, radius float64) []Entity
+}
+
+// entityKeys are the index keys for an entity.
+type entityKeys struct {
+	kind      string
+	owner     int
+	hasOwner  bool
+	coords    Coords
+	hasCoords bool
+	name      string // upper case, empty if the entity is not named
+}
+
+func keysOf(e Entity) entityKeys {
+	k := entityKeys{kind: e.Kind()}
+	switch e := e.(type) {
+	case *Star:
+		k.coords, k.hasCoords = e.Coords, true
+	case *Planet:
+		k.coords, k.hasCoords = e.Coords, true
+	case *Colony:
+		k.owner, k.hasOwner = e.Species, true
+		k.coords, k.hasCoords = e.Coords, true
+		k.name = strings.ToUpper(e.Name)
+	case *Ship:
+		k.owner, k.hasOwner = e.Species, true
+		k.coords, k.hasCoords = e.Coords, true
+		k.name = strings.ToUpper(e.Name)
+	case *Species:
+		k.owner, k.hasOwner = e.Number, true
+		k.name = strings.ToUpper(e.Name)
+	}
+	return k
+}
+
+// nameKey is the key for the name index. Names are only unique within a kind.
+type nameKey struct {
+	kind, name string
+}
+
+// index maps a key to the IDs of matching entities, sorted by ids.Compare.
+// Buckets are shared between states and are never modified in place
+// once the state holding them has been published.
+type index[K comparable] = shardedMap[K, []ID]
+
+// indexes are the secondary indexes of a State.
+type indexes struct {
+	kind   index[string]
+	owner  index[int]
+	coords index[Coords]
+	cell   index[Coords] // spatial grid, see cellOf
+	name   index[nameKey]
+}
+
+func buildIndexes(entities shardedMap[ID, Entity]) indexes {
+	kind, owner := newShardedMap[string, []ID]().edit(), newShardedMap[int, []ID]().edit()
+	coords, cell := newShardedMap[Coords, []ID]().edit(), newShardedMap[Coords, []ID]().edit()
+	name := newShardedMap[nameKey, []ID]().edit()
+	for id, e := range entities.all() {
+		k := keysOf(e)
+		kind.set(k.kind, append(kind.get(k.kind), id))
+		if k.hasOwner {
+			owner.set(k.owner, append(owner.get(k.owner), id))
+		}
+		if k.hasCoords {
+			coords.set(k.coords, append(coords.get(k.coords), id))
+			c := cellOf(k.coords)
+			cell.set(c, append(cell.get(c), id))
+		}
+		if k.name != "" {
+			nk := nameKey{k.kind, k.name}
+			name.set(nk, append(name.get(nk), id))
+		}
+	}
+	return indexes{
+		kind:   sortBuckets(kind.done()),
+		owner:  sortBuckets(owner.done()),
+		coords: sortBuckets(coords.done()),
+		cell:   sortBuckets(cell.done()),
+		name:   sortBuckets(name.done()),
+	}
+}
+
+// sortBuckets sorts every bucket into the order of ids.Compare.
+func sortBuckets[K comparable](idx index[K]) index[K] {
+	for _, list := range idx.all() {
+		ids.Sort(list)
+	}
+	return idx
+}
+
+// indexEditor applies changes to a copy of an index.
+// The first change to a bucket copies it, so the original index is untouched.
+type indexEditor[K comparable] struct {
+	idx    *shardEditor[K, []ID]
+	copied map[K]bool
+}
+
+func editIndex[K comparable](idx index[K]) *indexEditor[K] {
+	return &indexEditor[K]{idx: idx.edit(), copied: map[K]bool{}}
+}
+
+func (ed *indexEditor[K]) bucket(k K) []ID {
+	if !ed.copied[k] {
+		ed.idx.set(k, append([]ID(nil), ed.idx.get(k)...))
+		ed.copied[k] = true
+	}
+	return ed.idx.get(k)
+}
+
+func (ed *indexEditor[K]) add(k K, id ID) {
+	list := ed.bucket(k)
+	i := search(list, id)
+	if i < len(list) && list[i] == id {
+		return
+	}
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = id
+	ed.idx.set(k, list)
+}
+
+func (ed *indexEditor[K]) remove(k K, id ID) {
+	if _, ok := ed.idx.lookup(k); !ok {
+		return
+	}
+	list := ed.bucket(k)
+	i := search(list, id)
+	if i == len(list) || list[i] != id {
+		return
+	}
+	list = append(list[:i], list[i+1:]...)
+	if len(list) == 0 {
+		ed.idx.delete(k)
+		delete(ed.copied, k)
+		return
+	}
+	ed.idx.set(k, list)
+}
+
+// search returns the position of the ID in a bucket, or where it would be
+// inserted. The ID is parsed once; the IDs in the bucket once per step.
+func search(list []ID, id ID) int {
+	k := ids.KeyOf(id)
+	return sort.Search(len(list), func(i int) bool { return ids.KeyOf(list[i]).Compare(k) >= 0 })
+}
+
+// update returns indexes with the changes applied.
+// old holds the entities being replaced or deleted; changes holds the new
+// entities, with nil marking a deletion.
+func (ix indexes) update(old, changes map[ID]Entity) indexes {
+	kind, owner := editIndex(ix.kind), editIndex(ix.owner)
+	coords, cell, name := editIndex(ix.coords), editIndex(ix.cell), editIndex(ix.name)
+	for id, e := range old {
+		k := keysOf(e)
+		kind.remove(k.kind, id)
+		if k.hasOwner {
+			owner.remove(k.owner, id)
+		}
+		if k.hasCoords {
+			coords.remove(k.coords, id)
+			cell.remove(cellOf(k.coords), id)
+		}
+		if k.name != "" {
+			name.remove(nameKey{k.kind, k.name}, id)
+		}
+	}
+	for id, e := range changes {
+		if e == nil {
+			continue
+		}
+		k := keysOf(e)
+		kind.add(k.kind, id)
+		if k.hasOwner {
+			owner.add(k.owner, id)
+		}
+		if k.hasCoords {
+			coords.add(k.coords, id)
+			cell.add(cellOf(k.coords), id)
+		}
+		if k.name != "" {
+			name.add(nameKey{k.kind, k.name}, id)
+		}
+	}
+	return indexes{kind: kind.idx.done(), owner: owner.idx.done(), coords: coords.idx.done(), cell: cell.idx.done(), name: name.idx.done()}
+}
+
+// ByKind returns all entities of the kind.
+func (s *State) ByKind(kind string) []Entity {
+	return s.resolve(s.index.kind.get(kind))
+}
+
+// ByOwner returns the colonies and ships of a species, and the species itself.
+func (s *State) ByOwner(species int) []Entity {
+	return s.resolve(s.index.owner.get(species))
+}
+
+// At returns all entities in the star system at the coordinates.
+func (s *State) At(c Coords) []Entity {
+	return s.resolve(s.index.coords.get(c))
+}
+
+// ByName returns the entities of the kind with the name, ignoring case.
+func (s *State) ByName(kind, name string) []Entity {
+	return s.resolve(s.index.name.get(nameKey{kind, strings.ToUpper(name)}))
+}
+
+func (s *State) resolve(ids []ID) []Entity {
+	list := make([]Entity, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, s.entities.get(id))
+	}
+	return list
+}
+
+func (s *State) Species(number int) (*Species, bool)   { return speciesByNumber(s, number) }
+func (s *State) ShipsAt(species int, c Coords) []*Ship { return shipsAt(s, species, c) }
+func (s *State) ColoniesOf(species int) []*Colony      { return coloniesOf(s, species) }
+func (s *State) ColoniesOnPlanet(planet ID) []*Colony  { return coloniesOnPlanet(s, planet) }
+
+// ByKind returns all entities of the kind, as changed by the overlay.
+func (o *Overlay) ByKind(kind string) []Entity {
+	return o.merge(o.base.index.kind.get(kind), func(k entityKeys) bool {
+		return k.kind == kind
+	})
+}
+
+// ByOwner returns the entities owned by a species, as changed by the overlay.
+func (o *Overlay) ByOwner(species int) []Entity {
+	return o.merge(o.base.index.owner.get(species), func(k entityKeys) bool {
+		return k.hasOwner && k.owner == species
+	})
+}
+
+// At returns all entities at the coordinates, as changed by the overlay.
+func (o *Overlay) At(c Coords) []Entity {
+	return o.merge(o.base.index.coords.get(c), func(k entityKeys) bool {
+		return k.hasCoords && k.coords == c
+	})
+}
+
+// ByName returns the entities of the kind with the name, as changed by the overlay.
+func (o *Overlay) ByName(kind, name string) []Entity {
+	name = strings.ToUpper(name)
+	return o.merge(o.base.index.name.get(nameKey{kind, name}), func(k entityKeys) bool {
+		return k.kind == kind && k.name == name
+	})
+}
+
+// merge combines an index bucket from the base state with the overlay's changes.
+// Changed entities are matched against their current keys, because an entity
+// returned by Edit may have been modified since it was recorded.
+func (o *Overlay) merge(ids []ID, match func(entityKeys) bool) []Entity {
+	list := make([]Entity, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := o.changes[id]; !ok {
+			list = append(list, o.base.entities.get(id))
+		}
+	}
+	for _, e := range o.changes {
+		if e != nil && match(keysOf(e)) {
+			list = append(list, e)
+		}
+	}
+	sortEntities(list)
+	return list
+}
+
+func (o *Overlay) Species(number int) (*Species, bool)   { return speciesByNumber(o, number) }
+func (o *Overlay) ShipsAt(species int, c Coords) []*Ship { return shipsAt(o, species, c) }
+func (o *Overlay) ColoniesOf(species int) []*Colony      { return coloniesOf(o, species) }
+func (o *Overlay) ColoniesOnPlanet(planet ID) []*Colony  { return coloniesOnPlanet(o, planet) }
+
+// The typed queries are built on the generic ones so that State and
+// Overlay share a single implementation.
+
+func speciesByNumber(q Queries, number int) (*Species, bool) {
+	for _, e := range q.ByOwner(number) {
+		if sp, ok := e.(*Species); ok {
+			return sp, true
+		}
+	}
+	return nil, false
+}
+
+func shipsAt(q Queries, species int, c Coords) []*Ship {
+	var ships []*Ship
+	for _, e := range q.At(c) {
+		if ship, ok := e.(*Ship); ok && ship.Species == species {
+			ships = append(ships, ship)
+		}
+	}
+	return ships
+}
+
+func coloniesOf(q Queries, species int) []*Colony {
+	var colonies []*Colony
+	for _, e := range q.ByOwner(species) {
+		if colony, ok := e.(*Colony); ok {
+			colonies = append(colonies, colony)
+		}
+	}
+	return colonies
+}
+
+func coloniesOnPlanet(q interface {
+	Queries
+	GetEntity(ID) (Entity, bool)
+}, planet ID) []*Colony {
+	e, ok := q.GetEntity(planet)
+	if !ok {
+		return nil
+	}
+	p, ok := e.(*Planet)
+	if !ok {
+		return nil
+	}
+	var colonies []*Colony
+	for _, e := range q.At(p.Coords) {
+		if colony, ok := e.(*Colony); ok && colony.PlanetID == planet {
+			colonies = append(colonies, colony)
+		}
+	}
+	return colonies
+}
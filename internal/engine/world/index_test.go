@@ -0,0 +1,160 @@
+package world
+
+import (
+	"reflect"
+	"testing"
+)
+
+func queryTestState(t *testing.T) *State {
+	t.Helper()
+	sol := Coords{X: 1, Y: 2, Z: 3}
+	s, err := NewState([]Entity{
+		&Species{EntityID: "SP01", Number: 1, Name: "Humans"},
+		&Species{EntityID: "SP02", Number: 2, Name: "Klingons"},
+		&Star{EntityID: "STAR:1,2,3", Coords: sol},
+		&Star{EntityID: "STAR:4,5,6", Coords: Coords{X: 4, Y: 5, Z: 6}},
+		&Planet{EntityID: "PLANET:1,2,3,3", StarID: "STAR:1,2,3", Location: Location{Coords: sol, Orbit: 3}},
+		&Planet{EntityID: "PLANET:1,2,3,4", StarID: "STAR:1,2,3", Location: Location{Coords: sol, Orbit: 4}},
+		&Colony{EntityID: "COLONY:1:PLANET:1,2,3,3", Name: "Earth", Species: 1, PlanetID: "PLANET:1,2,3,3", Location: Location{Coords: sol, Orbit: 3}},
+		&Colony{EntityID: "COLONY:2:PLANET:1,2,3,3", Name: "Terra", Species: 2, PlanetID: "PLANET:1,2,3,3", Location: Location{Coords: sol, Orbit: 3}},
+		&Colony{EntityID: "COLONY:1:PLANET:1,2,3,4", Name: "Mars", Species: 1, PlanetID: "PLANET:1,2,3,4", Location: Location{Coords: sol, Orbit: 4}},
+		&Ship{EntityID: "SHIP:1:TR1 Alpha", Name: "Alpha", Species: 1, Location: Location{Coords: sol, Orbit: 3}, Class: TR},
+		&Ship{EntityID: "SHIP:1:DD Beta", Name: "Beta", Species: 1, Location: Location{Coords: sol}, Class: DD},
+		&Ship{EntityID: "SHIP:2:DD Gamma", Name: "Gamma", Species: 2, Location: Location{Coords: sol}, Class: DD},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func idsOf[E Entity](list []E) []ID {
+	out := []ID{}
+	for _, e := range list {
+		out = append(out, e.ID())
+	}
+	return out
+}
+
+// checkQueries runs the same queries against any world view.
+func checkQueries(t *testing.T, q Snapshot, want map[string][]ID) {
+	t.Helper()
+	sol := Coords{X: 1, Y: 2, Z: 3}
+	got := map[string][]ID{
+		"kind star":       idsOf(q.ByKind(KindStar)),
+		"owner 1":         idsOf(q.ByOwner(1)),
+		"at sol":          idsOf(q.At(sol)),
+		"name earth":      idsOf(q.ByName(KindColony, "EARTH")),
+		"ships 1 at sol":  idsOf(q.ShipsAt(1, sol)),
+		"colonies of 1":   idsOf(q.ColoniesOf(1)),
+		"colonies on p3":  idsOf(q.ColoniesOnPlanet("PLANET:1,2,3,3")),
+		"colonies on bad": idsOf(q.ColoniesOnPlanet("STAR:1,2,3")),
+	}
+	for name, w := range want {
+		if !reflect.DeepEqual(got[name], w) {
+			t.Errorf("%s: got %v, want %v", name, got[name], w)
+		}
+	}
+}
+
+func TestStateQueries(t *testing.T) {
+	s := queryTestState(t)
+	checkQueries(t, s, map[string][]ID{
+		"kind star":       {"STAR:1,2,3", "STAR:4,5,6"},
+		"owner 1":         {"SP01", "COLONY:1:PLANET:1,2,3,3", "COLONY:1:PLANET:1,2,3,4", "SHIP:1:DD Beta", "SHIP:1:TR1 Alpha"},
+		"name earth":      {"COLONY:1:PLANET:1,2,3,3"},
+		"ships 1 at sol":  {"SHIP:1:DD Beta", "SHIP:1:TR1 Alpha"},
+		"colonies of 1":   {"COLONY:1:PLANET:1,2,3,3", "COLONY:1:PLANET:1,2,3,4"},
+		"colonies on p3":  {"COLONY:1:PLANET:1,2,3,3", "COLONY:2:PLANET:1,2,3,3"},
+		"colonies on bad": {},
+	})
+	if got := len(s.At(Coords{X: 1, Y: 2, Z: 3})); got != 9 {
+		t.Errorf("At(sol) returned %d entities, want 9", got)
+	}
+	if sp, ok := s.Species(2); !ok || sp.Name != "Klingons" {
+		t.Errorf("Species(2) = %v, %v", sp, ok)
+	}
+	if _, ok := s.Species(3); ok {
+		t.Errorf("Species(3) found a species")
+	}
+}
+
+func TestOverlayQueries(t *testing.T) {
+	s := queryTestState(t)
+	o := s.Mutate()
+
+	// move a ship out of the system after it was recorded by Edit
+	e, _ := o.Edit("SHIP:1:DD Beta")
+	e.(*Ship).Coords = Coords{X: 4, Y: 5, Z: 6}
+	// rename a colony
+	e, _ = o.Edit("COLONY:1:PLANET:1,2,3,3")
+	e.(*Colony).Name = "Gaia"
+	o.Delete("COLONY:1:PLANET:1,2,3,4")
+	o.Upsert(&Ship{EntityID: "SHIP:1:PB Delta", Name: "Delta", Species: 1, Location: Location{Coords: Coords{X: 1, Y: 2, Z: 3}}, Class: PB})
+
+	want := map[string][]ID{
+		"kind star":      {"STAR:1,2,3", "STAR:4,5,6"},
+		"owner 1":        {"SP01", "COLONY:1:PLANET:1,2,3,3", "SHIP:1:DD Beta", "SHIP:1:PB Delta", "SHIP:1:TR1 Alpha"},
+		"name earth":     {},
+		"ships 1 at sol": {"SHIP:1:PB Delta", "SHIP:1:TR1 Alpha"},
+		"colonies of 1":  {"COLONY:1:PLANET:1,2,3,3"},
+		"colonies on p3": {"COLONY:1:PLANET:1,2,3,3", "COLONY:2:PLANET:1,2,3,3"},
+	}
+	checkQueries(t, o, want)
+	if got := idsOf(o.ByName(KindColony, "gaia")); !reflect.DeepEqual(got, []ID{"COLONY:1:PLANET:1,2,3,3"}) {
+		t.Errorf("ByName(gaia) = %v", got)
+	}
+
+	// the committed state answers the same way from its own indexes
+	next := o.Commit()
+	checkQueries(t, next, want)
+	if !reflect.DeepEqual(next.index, buildIndexes(next.entities)) {
+		t.Errorf("committed indexes differ from rebuilt indexes")
+	}
+
+	// and the original state is unchanged
+	checkQueries(t, s, map[string][]ID{
+		"name earth":     {"COLONY:1:PLANET:1,2,3,3"},
+		"ships 1 at sol": {"SHIP:1:DD Beta", "SHIP:1:TR1 Alpha"},
+		"colonies of 1":  {"COLONY:1:PLANET:1,2,3,3", "COLONY:1:PLANET:1,2,3,4"},
+	})
+	if !reflect.DeepEqual(s.index, buildIndexes(s.entities)) {
+		t.Errorf("base indexes were modified by commit")
+	}
+}
+
+func TestQueriesCanonicalOrder(t *testing.T) {
+	s, err := NewState([]Entity{
+		&Star{EntityID: "STAR:10,0,0", Coords: Coords{X: 10}},
+		&Star{EntityID: "STAR:9,0,0", Coords: Coords{X: 9}},
+		&Planet{EntityID: "PLANET:9,0,0,10", StarID: "STAR:9,0,0", Location: Location{Coords: Coords{X: 9}, Orbit: 10}},
+		&Planet{EntityID: "PLANET:9,0,0,2", StarID: "STAR:9,0,0", Location: Location{Coords: Coords{X: 9}, Orbit: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ID{"STAR:9,0,0", "STAR:10,0,0"}
+	if got := idsOf(s.ByKind(KindStar)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ByKind(star) = %v, want %v", got, want)
+	}
+	want = []ID{"STAR:9,0,0", "PLANET:9,0,0,2", "PLANET:9,0,0,10"}
+	if got := idsOf(s.At(Coords{X: 9})); !reflect.DeepEqual(got, want) {
+		t.Errorf("At(9,0,0) = %v, want %v", got, want)
+	}
+
+	// an overlay inserts into the buckets in the same order
+	o := s.Mutate()
+	o.Upsert(&Star{EntityID: "STAR:2,0,0", Coords: Coords{X: 2}})
+	want = []ID{"STAR:2,0,0", "STAR:9,0,0", "STAR:10,0,0"}
+	if got := idsOf(o.ByKind(KindStar)); !reflect.DeepEqual(got, want) {
+		t.Errorf("overlay ByKind(star) = %v, want %v", got, want)
+	}
+	next := o.Commit()
+	if got := idsOf(next.ByKind(KindStar)); !reflect.DeepEqual(got, want) {
+		t.Errorf("committed ByKind(star) = %v, want %v", got, want)
+	}
+	want = []ID{"STAR:2,0,0", "STAR:9,0,0", "STAR:10,0,0", "PLANET:9,0,0,2", "PLANET:9,0,0,10"}
+	if got := idsOf(next.Entities()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Entities() = %v, want %v", got, want)
+	}
+}
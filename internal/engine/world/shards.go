@@ -0,0 +1,141 @@
+package world
+
+import (
+	"hash/maphash"
+	"iter"
+	"maps"
+)
+
+// shardCount is the number of shards in a shardedMap.
+// A commit copies only the shards it changes, each about 1/shardCount of
+// the map.
+const shardCount = 256
+
+// shardedMap is a map split into shards by a hash of the key, so that a
+// changed copy shares every shard it does not change with the original.
+// Shards are never modified once the map holding them has been published;
+// changes are made through a shardEditor. An empty shard is nil, so maps
+// with the same contents are deeply equal.
+type shardedMap[K comparable, V any] struct {
+	shards []map[K]V
+	n      int
+}
+
+func newShardedMap[K comparable, V any]() shardedMap[K, V] {
+	return shardedMap[K, V]{shards: make([]map[K]V, shardCount)}
+}
+
+// get returns the value for the key, or the zero value if it is missing.
+func (m shardedMap[K, V]) get(k K) V {
+	return m.shards[shardOf(k)][k]
+}
+
+// lookup returns the value for the key and whether it is present.
+func (m shardedMap[K, V]) lookup(k K) (V, bool) {
+	v, ok := m.shards[shardOf(k)][k]
+	return v, ok
+}
+
+func (m shardedMap[K, V]) len() int {
+	return m.n
+}
+
+// all returns the keys and values in no particular order.
+func (m shardedMap[K, V]) all() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, shard := range m.shards {
+			for k, v := range shard {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// edit returns an editor for a copy of the map.
+func (m shardedMap[K, V]) edit() *shardEditor[K, V] {
+	return &shardEditor[K, V]{m: shardedMap[K, V]{shards: append([]map[K]V(nil), m.shards...), n: m.n}}
+}
+
+// shardEditor applies changes to a copy of a shardedMap.
+// The first change to a shard copies it, so the original map is untouched.
+type shardEditor[K comparable, V any] struct {
+	m      shardedMap[K, V]
+	copied [shardCount]bool
+}
+
+func (ed *shardEditor[K, V]) get(k K) V {
+	return ed.m.get(k)
+}
+
+func (ed *shardEditor[K, V]) lookup(k K) (V, bool) {
+	return ed.m.lookup(k)
+}
+
+func (ed *shardEditor[K, V]) shard(i int) map[K]V {
+	if !ed.copied[i] {
+		shard := make(map[K]V, len(ed.m.shards[i])+1)
+		maps.Copy(shard, ed.m.shards[i])
+		ed.m.shards[i], ed.copied[i] = shard, true
+	}
+	return ed.m.shards[i]
+}
+
+func (ed *shardEditor[K, V]) set(k K, v V) {
+	shard := ed.shard(shardOf(k))
+	if _, ok := shard[k]; !ok {
+		ed.m.n++
+	}
+	shard[k] = v
+}
+
+func (ed *shardEditor[K, V]) delete(k K) {
+	i := shardOf(k)
+	if _, ok := ed.m.shards[i][k]; !ok {
+		return
+	}
+	shard := ed.shard(i)
+	delete(shard, k)
+	ed.m.n--
+	if len(shard) == 0 {
+		ed.m.shards[i], ed.copied[i] = nil, false
+	}
+}
+
+// done returns the edited map. The editor must not be used afterwards.
+func (ed *shardEditor[K, V]) done() shardedMap[K, V] {
+	return ed.m
+}
+
+// shardSeed seeds the hash of string keys. Shards only need to be the same
+// within a process, like the iteration order of a map.
+var shardSeed = maphash.MakeSeed()
+
+// shardOf returns the shard of a key.
+func shardOf[K comparable](k K) int {
+	var h uint64
+	switch k := any(k).(type) {
+	case ID:
+		h = maphash.String(shardSeed, string(k))
+	case string:
+		h = maphash.String(shardSeed, k)
+	case int:
+		h = mix(0, k)
+	case Coords:
+		h = mix(mix(mix(0, k.X), k.Y), k.Z)
+	case nameKey:
+		h = mix(maphash.String(shardSeed, k.kind), int(maphash.String(shardSeed, k.name)))
+	default:
+		panic("world: no shard hash for the key type")
+	}
+	return int(h % shardCount)
+}
+
+// mix adds an integer to a hash. It is the finalizer of SplitMix64.
+func mix(h uint64, v int) uint64 {
+	h += uint64(v) + 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return h ^ h>>31
+}
@@ -0,0 +1,200 @@
+package world
+
+import (
+	"math"
+
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// cellSize is the edge of a spatial grid cell in parsecs.
+// A galaxy of a few thousand stars has a few stars per cell.
+const cellSize = 8
+
+// cellOf returns the grid cell containing the coordinates.
+func cellOf(c Coords) Coords {
+	return Coords{X: floorDiv(c.X, cellSize), Y: floorDiv(c.Y, cellSize), Z: floorDiv(c.Z, cellSize)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// DistanceSquared returns the square of the distance between two points.
+func (c Coords) DistanceSquared(o Coords) int {
+	dx, dy, dz := c.X-o.X, c.Y-o.Y, c.Z-o.Z
+	return dx*dx + dy*dy + dz*dz
+}
+
+// Distance returns the distance between two points in parsecs.
+func (c Coords) Distance(o Coords) float64 {
+	return math.Sqrt(float64(c.DistanceSquared(o)))
+}
+
+// cellQueries is the primitive that the spatial queries are built on.
+type cellQueries interface {
+	Queries
+	// inCells returns the entities with coordinates in the cells from lo to hi inclusive.
+	inCells(lo, hi Coords) []Entity
+	// hasStars reports whether there is at least one star.
+	hasStars() bool
+}
+
+func (s *State) hasStars() bool {
+	return len(s.index.kind.get(KindStar)) != 0
+}
+
+func (o *Overlay) hasStars() bool {
+	n := len(o.base.index.kind.get(KindStar))
+	for id, e := range o.changes {
+		if prev, ok := o.base.entities.lookup(id); ok && prev.Kind() == KindStar {
+			n--
+		}
+		if e != nil && e.Kind() == KindStar {
+			n++
+		}
+	}
+	return n > 0
+}
+
+func (s *State) inCells(lo, hi Coords) []Entity {
+	var list []Entity
+	forCells(lo, hi, func(cell Coords) {
+		for _, id := range s.index.cell.get(cell) {
+			list = append(list, s.entities.get(id))
+		}
+	})
+	return list
+}
+
+func (o *Overlay) inCells(lo, hi Coords) []Entity {
+	var list []Entity
+	forCells(lo, hi, func(cell Coords) {
+		for _, id := range o.base.index.cell.get(cell) {
+			if _, ok := o.changes[id]; !ok {
+				list = append(list, o.base.entities.get(id))
+			}
+		}
+	})
+	for _, e := range o.changes {
+		if e == nil {
+			continue
+		}
+		if k := keysOf(e); k.hasCoords && inBox(cellOf(k.coords), lo, hi) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+func forCells(lo, hi Coords, fn func(Coords)) {
+	for x := lo.X; x <= hi.X; x++ {
+		for y := lo.Y; y <= hi.Y; y++ {
+			for z := lo.Z; z <= hi.Z; z++ {
+				fn(Coords{X: x, Y: y, Z: z})
+			}
+		}
+	}
+}
+
+func inBox(c, lo, hi Coords) bool {
+	return c.X >= lo.X && c.X <= hi.X && c.Y >= lo.Y && c.Y <= hi.Y && c.Z >= lo.Z && c.Z <= hi.Z
+}
+
+func (s *State) StarAt(c Coords) (*Star, bool)                  { return starAt(s, c) }
+func (s *State) NearestStar(c Coords) (*Star, bool)             { return nearestStar(s, c) }
+func (s *State) StarsWithin(c Coords, radius float64) []*Star   { return starsWithin(s, c, radius) }
+func (s *State) Within(c Coords, radius float64) []Entity       { return within(s, c, radius) }
+func (o *Overlay) StarAt(c Coords) (*Star, bool)                { return starAt(o, c) }
+func (o *Overlay) NearestStar(c Coords) (*Star, bool)           { return nearestStar(o, c) }
+func (o *Overlay) StarsWithin(c Coords, radius float64) []*Star { return starsWithin(o, c, radius) }
+func (o *Overlay) Within(c Coords, radius float64) []Entity     { return within(o, c, radius) }
+
+func starAt(q Queries, c Coords) (*Star, bool) {
+	for _, e := range q.At(c) {
+		if star, ok := e.(*Star); ok {
+			return star, true
+		}
+	}
+	return nil, false
+}
+
+// within returns the positioned entities no further than radius from c,
+// sorted by ID.
+func within(q cellQueries, c Coords, radius float64) []Entity {
+	if radius < 0 {
+		return nil
+	}
+	r := int(math.Ceil(radius))
+	lo := cellOf(Coords{X: c.X - r, Y: c.Y - r, Z: c.Z - r})
+	hi := cellOf(Coords{X: c.X + r, Y: c.Y + r, Z: c.Z + r})
+	var list []Entity
+	for _, e := range q.inCells(lo, hi) {
+		if k := keysOf(e); float64(k.coords.DistanceSquared(c)) <= radius*radius {
+			list = append(list, e)
+		}
+	}
+	sortEntities(list)
+	return list
+}
+
+func starsWithin(q cellQueries, c Coords, radius float64) []*Star {
+	var stars []*Star
+	for _, e := range within(q, c, radius) {
+		if star, ok := e.(*Star); ok {
+			stars = append(stars, star)
+		}
+	}
+	return stars
+}
+
+// nearestStar searches shells of grid cells around c, moving outward until
+// no unsearched cell can hold a star closer than the best one found.
+// Ties are broken by ID so the result is stable.
+func nearestStar(q cellQueries, c Coords) (*Star, bool) {
+	if !q.hasStars() {
+		return nil, false
+	}
+	center := cellOf(c)
+	var best *Star
+	bestD2 := 0
+	for ring := 0; ; ring++ {
+		for _, box := range shell(center, ring) {
+			for _, e := range q.inCells(box[0], box[1]) {
+				star, ok := e.(*Star)
+				if !ok {
+					continue
+				}
+				d2 := star.Coords.DistanceSquared(c)
+				if best == nil || d2 < bestD2 || (d2 == bestD2 && ids.Compare(star.EntityID, best.EntityID) < 0) {
+					best, bestD2 = star, d2
+				}
+			}
+		}
+		// every point outside the searched cells is more than ring*cellSize away
+		if best != nil && bestD2 <= (ring*cellSize)*(ring*cellSize) {
+			return best, true
+		}
+	}
+}
+
+// shell returns the cells at Chebyshev distance ring from center
+// as up to six non-overlapping boxes, one per face of the cube.
+func shell(center Coords, ring int) [][2]Coords {
+	lo := Coords{X: center.X - ring, Y: center.Y - ring, Z: center.Z - ring}
+	hi := Coords{X: center.X + ring, Y: center.Y + ring, Z: center.Z + ring}
+	if ring == 0 {
+		return [][2]Coords{{lo, hi}}
+	}
+	return [][2]Coords{
+		{{X: lo.X, Y: lo.Y, Z: lo.Z}, {X: lo.X, Y: hi.Y, Z: hi.Z}},
+		{{X: hi.X, Y: lo.Y, Z: lo.Z}, {X: hi.X, Y: hi.Y, Z: hi.Z}},
+		{{X: lo.X + 1, Y: lo.Y, Z: lo.Z}, {X: hi.X - 1, Y: lo.Y, Z: hi.Z}},
+		{{X: lo.X + 1, Y: hi.Y, Z: lo.Z}, {X: hi.X - 1, Y: hi.Y, Z: hi.Z}},
+		{{X: lo.X + 1, Y: lo.Y + 1, Z: lo.Z}, {X: hi.X - 1, Y: hi.Y - 1, Z: lo.Z}},
+		{{X: lo.X + 1, Y: lo.Y + 1, Z: hi.Z}, {X: hi.X - 1, Y: hi.Y - 1, Z: hi.Z}},
+	}
+}
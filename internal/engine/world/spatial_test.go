@@ -0,0 +1,184 @@
+package world
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomGalaxy returns a state with n stars placed in a sphere of the given radius.
+func randomGalaxy(t testing.TB, n, radius int, seed int64) *State {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	seen := map[Coords]bool{}
+	var entities []Entity
+	for len(entities) < n {
+		c := Coords{X: r.Intn(2*radius + 1), Y: r.Intn(2*radius + 1), Z: r.Intn(2*radius + 1)}
+		if seen[c] || c.DistanceSquared(Coords{X: radius, Y: radius, Z: radius}) > radius*radius {
+			continue
+		}
+		seen[c] = true
+		entities = append(entities, &Star{EntityID: ID(fmt.Sprintf("STAR:%d,%d,%d", c.X, c.Y, c.Z)), Coords: c})
+	}
+	s, err := NewState(entities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func bruteNearest(s *State, c Coords) *Star {
+	var best *Star
+	for _, e := range s.ByKind(KindStar) {
+		star := e.(*Star)
+		if best == nil || star.DistanceSquared(c) < best.DistanceSquared(c) {
+			best = star
+		}
+	}
+	return best
+}
+
+func bruteWithin(s *State, c Coords, radius float64) []ID {
+	out := []ID{}
+	for _, e := range s.ByKind(KindStar) {
+		if e.(*Star).Distance(c) <= radius {
+			out = append(out, e.ID())
+		}
+	}
+	return out
+}
+
+func TestSpatialMatchesBruteForce(t *testing.T) {
+	s := randomGalaxy(t, 2000, 60, 1)
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		c := Coords{X: r.Intn(140) - 10, Y: r.Intn(140) - 10, Z: r.Intn(140) - 10}
+
+		star, ok := s.NearestStar(c)
+		if want := bruteNearest(s, c); !ok || star.DistanceSquared(c) != want.DistanceSquared(c) {
+			t.Fatalf("NearestStar(%v) = %v, want distance %v", c, star, want.Distance(c))
+		}
+
+		radius := r.Float64() * 20
+		if got, want := idsOf(s.StarsWithin(c, radius)), bruteWithin(s, c, radius); !reflect.DeepEqual(got, want) {
+			t.Fatalf("StarsWithin(%v, %.2f) = %v, want %v", c, radius, got, want)
+		}
+	}
+
+	c := Coords{X: 60, Y: 60, Z: 60}
+	if star, ok := s.StarAt(bruteNearest(s, c).Coords); !ok || star != bruteNearest(s, c) {
+		t.Errorf("StarAt() did not find the star")
+	}
+}
+
+func TestSpatialEmpty(t *testing.T) {
+	s, err := NewState(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.NearestStar(Coords{}); ok {
+		t.Errorf("NearestStar() found a star in an empty galaxy")
+	}
+	if _, ok := s.StarAt(Coords{}); ok {
+		t.Errorf("StarAt() found a star in an empty galaxy")
+	}
+}
+
+func TestSpatialOverlayMove(t *testing.T) {
+	s := queryTestState(t)
+	o := s.Mutate()
+	here, there := Coords{X: 1, Y: 2, Z: 3}, Coords{X: 40, Y: 40, Z: 40}
+
+	e, _ := o.Edit("SHIP:1:DD Beta")
+	e.(*Ship).Coords = there
+	o.Upsert(&Star{EntityID: "STAR:41,40,40", Coords: Coords{X: 41, Y: 40, Z: 40}})
+
+	check := func(q Snapshot) {
+		t.Helper()
+		if got := idsOf(q.Within(there, 0)); !reflect.DeepEqual(got, []ID{"SHIP:1:DD Beta"}) {
+			t.Errorf("Within(there, 0) = %v", got)
+		}
+		for _, e := range q.Within(here, 1) {
+			if e.ID() == "SHIP:1:DD Beta" {
+				t.Errorf("Within(here, 1) still has the moved ship")
+			}
+		}
+		if star, ok := q.NearestStar(there); !ok || star.EntityID != "STAR:41,40,40" {
+			t.Errorf("NearestStar(there) = %v", star)
+		}
+	}
+	check(o)
+	next := o.Commit()
+	check(next)
+
+	// the base state still has the ship where it was
+	if got := idsOf(s.ShipsAt(1, here)); !reflect.DeepEqual(got, []ID{"SHIP:1:DD Beta", "SHIP:1:TR1 Alpha"}) {
+		t.Errorf("base ShipsAt(here) = %v", got)
+	}
+	if star, _ := s.NearestStar(there); star.EntityID != "STAR:4,5,6" {
+		t.Errorf("base NearestStar(there) = %v", star)
+	}
+}
+
+func TestCellOf(t *testing.T) {
+	tests := []struct{ in, want Coords }{
+		{Coords{X: 0, Y: 7, Z: 8}, Coords{X: 0, Y: 0, Z: 1}},
+		{Coords{X: -1, Y: -8, Z: -9}, Coords{X: -1, Y: -1, Z: -2}},
+	}
+	for _, tt := range tests {
+		if got := cellOf(tt.in); got != tt.want {
+			t.Errorf("cellOf(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkNearestStar(b *testing.B) {
+	s := randomGalaxy(b, 5000, 80, 1)
+	r := rand.New(rand.NewSource(2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.NearestStar(Coords{X: r.Intn(160), Y: r.Intn(160), Z: r.Intn(160)})
+	}
+}
+
+func BenchmarkStarsWithin(b *testing.B) {
+	s := randomGalaxy(b, 5000, 80, 1)
+	r := rand.New(rand.NewSource(2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.StarsWithin(Coords{X: r.Intn(160), Y: r.Intn(160), Z: r.Intn(160)}, 10)
+	}
+}
+
+func BenchmarkStarsWithinBruteForce(b *testing.B) {
+	s := randomGalaxy(b, 5000, 80, 1)
+	r := rand.New(rand.NewSource(2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteWithin(s, Coords{X: r.Intn(160), Y: r.Intn(160), Z: r.Intn(160)}, 10)
+	}
+}
+
+func BenchmarkStarAt(b *testing.B) {
+	s := randomGalaxy(b, 5000, 80, 1)
+	stars := s.ByKind(KindStar)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.StarAt(stars[i%len(stars)].(*Star).Coords)
+	}
+}
+
+func BenchmarkCommitMoveShip(b *testing.B) {
+	s := randomGalaxy(b, 5000, 80, 1)
+	o := s.Mutate()
+	o.Upsert(&Ship{EntityID: "SHIP:1:PB Scout", Name: "Scout", Species: 1, Class: PB})
+	s = o.Commit()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		o := s.Mutate()
+		e, _ := o.Edit("SHIP:1:PB Scout")
+		e.(*Ship).Coords = Coords{X: i % 160, Y: i % 150, Z: i % 140}
+		o.Commit()
+	}
+}
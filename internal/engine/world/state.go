@@ -0,0 +1,228 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/world/ids"
+)
+
+// State is an immutable world state.
+// It is never modified after it is created, so a *State can be shared
+// between goroutines without locking. Changes are made through an Overlay
+// and committed to produce a new State that shares every unchanged entity
+// with the old one.
+//
+// Entities returned by a State must be treated as read-only.
+type State struct {
+	entities shardedMap[ID, Entity]
+	index    indexes
+}
+
+// NewState returns a state holding the entities.
+// It returns an error if an entity has no ID or if two entities share an ID.
+func NewState(entities []Entity) (*State, error) {
+	ed := newShardedMap[ID, Entity]().edit()
+	for _, e := range entities {
+		if e.ID() == "" {
+			return nil, fmt.Errorf("%s: missing id", e.Kind())
+		}
+		if _, ok := ed.lookup(e.ID()); ok {
+			return nil, fmt.Errorf("%s: duplicate id", e.ID())
+		}
+		ed.set(e.ID(), e)
+	}
+	s := &State{entities: ed.done()}
+	s.index = buildIndexes(s.entities)
+	return s, nil
+}
+
+// Load decodes the snapshot for a turn into a new state.
+func Load(ctx context.Context, st store.Store, gameID string, turnNum int) (*State, error) {
+	list, err := st.LoadSnapshot(ctx, gameID, turnNum)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := DecodeAll(list)
+	if err != nil {
+		return nil, fmt.Errorf("game %s turn %d: %w", gameID, turnNum, err)
+	}
+	return NewState(entities)
+}
+
+// Save encodes the state and stores it as the snapshot for a turn.
+func (s *State) Save(ctx context.Context, st store.Store, gameID string, turnNum int) error {
+	list, err := EncodeAll(s.Entities())
+	if err != nil {
+		return fmt.Errorf("game %s turn %d: %w", gameID, turnNum, err)
+	}
+	return st.SaveSnapshot(ctx, gameID, turnNum, list)
+}
+
+// GetEntity returns the entity with the given ID.
+func (s *State) GetEntity(id ID) (Entity, bool) {
+	return s.entities.lookup(id)
+}
+
+// Len returns the number of entities in the state.
+func (s *State) Len() int {
+	return s.entities.len()
+}
+
+// Entities returns all entities sorted by ID.
+func (s *State) Entities() []Entity {
+	list := make([]Entity, 0, s.entities.len())
+	for _, e := range s.entities.all() {
+		list = append(list, e)
+	}
+	sortEntities(list)
+	return list
+}
+
+// Mutate returns an empty overlay on top of the state.
+func (s *State) Mutate() *Overlay {
+	return &Overlay{base: s, changes: map[ID]Entity{}}
+}
+
+// Overlay records changes to a State copy-on-write.
+// Reads fall through to the base state for entities the overlay has not
+// touched. Discarding an overlay costs nothing; committing it copies the
+// changed entities and the parts of the entity map and indexes that hold
+// them, and shares everything else with the base state.
+//
+// An Overlay is not safe for concurrent use. Several overlays may share
+// the same base state.
+type Overlay struct {
+	base *State
+	// changes holds upserted entities; a nil value marks a deletion.
+	changes map[ID]Entity
+}
+
+// Base returns the state the overlay was created from.
+func (o *Overlay) Base() *State {
+	return o.base
+}
+
+// GetEntity returns the entity with the given ID, as changed by the overlay.
+func (o *Overlay) GetEntity(id ID) (Entity, bool) {
+	if e, ok := o.changes[id]; ok {
+		return e, e != nil
+	}
+	return o.base.GetEntity(id)
+}
+
+// Edit returns a copy of the entity that the caller may modify in place.
+// The copy is made the first time an entity is edited; later calls
+// return the same copy. It returns false if the entity does not exist.
+func (o *Overlay) Edit(id ID) (Entity, bool) {
+	if e, ok := o.changes[id]; ok {
+		return e, e != nil
+	}
+	e, ok := o.base.GetEntity(id)
+	if !ok {
+		return nil, false
+	}
+	e = Clone(e)
+	o.changes[id] = e
+	return e, true
+}
+
+// Upsert adds or replaces an entity.
+// The overlay takes ownership of the entity; the caller must not modify it
+// afterwards except through Edit.
+func (o *Overlay) Upsert(e Entity) {
+	if e == nil || e.ID() == "" {
+		panic("world: upsert of entity without id")
+	}
+	o.changes[e.ID()] = e
+}
+
+// Delete removes an entity. Deleting a missing entity is not an error.
+func (o *Overlay) Delete(id ID) {
+	if _, ok := o.base.GetEntity(id); !ok {
+		delete(o.changes, id)
+		return
+	}
+	o.changes[id] = nil
+}
+
+// Changed returns the IDs of entities upserted or deleted, in the order of
+// ids.Compare.
+func (o *Overlay) Changed() []ID {
+	list := make([]ID, 0, len(o.changes))
+	for id := range o.changes {
+		list = append(list, id)
+	}
+	ids.Sort(list)
+	return list
+}
+
+// Entities returns all entities as changed by the overlay, sorted by ID.
+func (o *Overlay) Entities() []Entity {
+	list := make([]Entity, 0, o.base.Len()+len(o.changes))
+	for id, e := range o.base.entities.all() {
+		if _, ok := o.changes[id]; !ok {
+			list = append(list, e)
+		}
+	}
+	for _, e := range o.changes {
+		if e != nil {
+			list = append(list, e)
+		}
+	}
+	sortEntities(list)
+	return list
+}
+
+// Commit returns a new state with the overlay's changes applied.
+// The base state and its indexes are not modified. The overlay is reset
+// on top of the new state, so entities it returned earlier must not be
+// modified any more.
+func (o *Overlay) Commit() *State {
+	if len(o.changes) == 0 {
+		return o.base
+	}
+	entities := o.base.entities.edit()
+	old := map[ID]Entity{}
+	for id, e := range o.changes {
+		if prev, ok := entities.lookup(id); ok {
+			old[id] = prev
+		}
+		if e == nil {
+			entities.delete(id)
+		} else {
+			entities.set(id, e)
+		}
+	}
+	s := &State{entities: entities.done(), index: o.base.index.update(old, o.changes)}
+	o.base, o.changes = s, map[ID]Entity{}
+	return s
+}
+
+// Discard drops all changes recorded by the overlay.
+func (o *Overlay) Discard() {
+	o.changes = map[ID]Entity{}
+}
+
+// sortEntities sorts entities by ID, in the order of ids.Compare.
+// Each ID is parsed once.
+func sortEntities(list []Entity) {
+	keyed := make([]struct {
+		key ids.Key
+		e   Entity
+	}, len(list))
+	for i, e := range list {
+		keyed[i].key, keyed[i].e = ids.KeyOf(e.ID()), e
+	}
+	sort.SliceStable(keyed, func(i, j int) bool { return keyed[i].key.Compare(keyed[j].key) < 0 })
+	for i := range keyed {
+		list[i] = keyed[i].e
+	}
+}
+
+var (
+	_ Snapshot = (*State)(nil)
+	_ Mutable  = (*Overlay)(nil)
+)
@@ -0,0 +1,208 @@
+package world
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/playbymail/fh/internal/data/store"
+)
+
+func testEntities() []Entity {
+	return []Entity{
+		&Star{EntityID: "STAR:1,2,3", Coords: Coords{X: 1, Y: 2, Z: 3}, Type: MainSequenceStar, Color: YellowStar},
+		&Planet{EntityID: "PLANET:1,2,3,1", StarID: "STAR:1,2,3", Location: Location{Coords: Coords{X: 1, Y: 2, Z: 3}, Orbit: 1}},
+		&Ship{EntityID: "SHIP:1:TR1 Alpha", Name: "Alpha", Species: 1, Class: TR, Cargo: Inventory{CU: 5}},
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	defer st.Close()
+	if err := st.CreateGame(ctx, "g1", "Test"); err != nil {
+		t.Fatal(err)
+	}
+	for turn := 0; turn <= 1; turn++ {
+		if err := st.CreateTurn(ctx, "g1", turn, "orders"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewState(testEntities())
+	if err != nil {
+		t.Fatalf("NewState() error = %v", err)
+	}
+	if err := s.Save(ctx, st, "g1", 0); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(ctx, st, "g1", 0)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Entities(), s.Entities()) {
+		t.Errorf("Load() mismatch\n got %v\nwant %v", loaded.Entities(), s.Entities())
+	}
+
+	if _, err := Load(ctx, st, "g2", 0); err == nil {
+		t.Errorf("Load() of unknown game expected error")
+	}
+}
+
+func TestNewStateErrors(t *testing.T) {
+	if _, err := NewState([]Entity{&Star{}}); err == nil {
+		t.Errorf("NewState() expected error for missing id")
+	}
+	if _, err := NewState([]Entity{&Star{EntityID: "S"}, &Planet{EntityID: "S"}}); err == nil {
+		t.Errorf("NewState() expected error for duplicate id")
+	}
+}
+
+func TestOverlayCopyOnWrite(t *testing.T) {
+	base, err := NewState(testEntities())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := base.Mutate()
+
+	e, ok := o.Edit("SHIP:1:TR1 Alpha")
+	if !ok {
+		t.Fatalf("Edit() did not find ship")
+	}
+	ship := e.(*Ship)
+	ship.Cargo.Set(CU, 0)
+	ship.Name = "Beta"
+	if again, _ := o.Edit("SHIP:1:TR1 Alpha"); again != e {
+		t.Errorf("Edit() returned a second copy")
+	}
+
+	o.Delete("PLANET:1,2,3,1")
+	o.Upsert(&Star{EntityID: "STAR:4,5,6", Coords: Coords{X: 4, Y: 5, Z: 6}})
+	o.Delete("STAR:9,9,9")
+
+	// the base state is unchanged
+	orig, _ := base.GetEntity("SHIP:1:TR1 Alpha")
+	if orig.(*Ship).Name != "Alpha" || orig.(*Ship).Cargo.Get(CU) != 5 {
+		t.Errorf("base ship was modified: %#v", orig)
+	}
+	if _, ok := base.GetEntity("PLANET:1,2,3,1"); !ok {
+		t.Errorf("base planet was deleted")
+	}
+
+	// the overlay sees its own changes
+	if _, ok := o.GetEntity("PLANET:1,2,3,1"); ok {
+		t.Errorf("overlay still has deleted planet")
+	}
+	if _, ok := o.GetEntity("STAR:4,5,6"); !ok {
+		t.Errorf("overlay is missing upserted star")
+	}
+	wantChanged := []ID{"STAR:4,5,6", "PLANET:1,2,3,1", "SHIP:1:TR1 Alpha"}
+	if got := o.Changed(); !reflect.DeepEqual(got, wantChanged) {
+		t.Errorf("Changed() = %v, want %v", got, wantChanged)
+	}
+	if got := len(o.Entities()); got != 3 {
+		t.Errorf("Entities() returned %d entities, want 3", got)
+	}
+
+	next := o.Commit()
+	if next == base {
+		t.Fatalf("Commit() returned the base state")
+	}
+	if next.Len() != 3 || base.Len() != 3 {
+		t.Errorf("Len() = %d/%d, want 3/3", next.Len(), base.Len())
+	}
+	// unchanged entities are shared, not copied
+	a, _ := base.GetEntity("STAR:1,2,3")
+	b, _ := next.GetEntity("STAR:1,2,3")
+	if a != b {
+		t.Errorf("Commit() copied an unchanged entity")
+	}
+	if o.Base() != next || len(o.Changed()) != 0 {
+		t.Errorf("Commit() did not reset the overlay")
+	}
+}
+
+func TestCommitSharesShards(t *testing.T) {
+	base := randomGalaxy(t, 1000, 40, 1)
+	o := base.Mutate()
+	star := base.ByKind(KindStar)[0].(*Star)
+	e, _ := o.Edit(star.EntityID)
+	e.(*Star).Color = RedStar
+	next := o.Commit()
+
+	// only the shard holding the star is copied
+	if n := copiedShards(base.entities, next.entities); n != 1 {
+		t.Errorf("Commit() copied %d entity shards, want 1", n)
+	}
+	if n := copiedShards(base.index.cell, next.index.cell); n != 1 {
+		t.Errorf("Commit() copied %d cell index shards, want 1", n)
+	}
+	if !reflect.DeepEqual(next.index, buildIndexes(next.entities)) {
+		t.Errorf("committed indexes differ from rebuilt indexes")
+	}
+}
+
+// copiedShards returns the number of shards of b that are not shared with a.
+func copiedShards[K comparable, V any](a, b shardedMap[K, V]) int {
+	n := 0
+	for i := range b.shards {
+		if reflect.ValueOf(a.shards[i]).UnsafePointer() != reflect.ValueOf(b.shards[i]).UnsafePointer() {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOverlayDiscard(t *testing.T) {
+	base, err := NewState(testEntities())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := base.Mutate()
+	o.Delete("STAR:1,2,3")
+	o.Discard()
+	if _, ok := o.GetEntity("STAR:1,2,3"); !ok {
+		t.Errorf("Discard() did not restore deleted star")
+	}
+	if o.Commit() != base {
+		t.Errorf("Commit() of empty overlay should return the base state")
+	}
+}
+
+func TestOverlaysShareBase(t *testing.T) {
+	base, err := NewState(testEntities())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o := base.Mutate()
+			for j := 0; j < 100; j++ {
+				e, _ := o.Edit("SHIP:1:TR1 Alpha")
+				e.(*Ship).Age++
+				_ = base.Entities()
+			}
+		}()
+	}
+	wg.Wait()
+	e, _ := base.GetEntity("SHIP:1:TR1 Alpha")
+	if age := e.(*Ship).Age; age != 0 {
+		t.Errorf("base ship age = %d, want 0", age)
+	}
+}
+
+func TestClone(t *testing.T) {
+	for _, e := range testEntities() {
+		clone := Clone(e)
+		if !reflect.DeepEqual(clone, e) {
+			t.Errorf("Clone(%s) mismatch", e.ID())
+		}
+		if clone == e {
+			t.Errorf("Clone(%s) returned the same pointer", e.ID())
+		}
+	}
+}
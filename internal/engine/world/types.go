@@ -1,13 +1,16 @@
 // Package world implements world state and entity models.
 package world
 
-// ID is a stable identifier for entities, e.g., "SYS:SOL", "FLEET:1234".
-type ID string
+import "github.com/playbymail/fh/internal/engine/world/ids"
+
+// ID is a stable identifier for entities, e.g., "STAR:10,4,7" or "SP01".
+// The formats are defined by package ids.
+type ID = ids.ID
 
 // Snapshot provides read-only access to world state.
 type Snapshot interface {
 	GetEntity(id ID) (Entity, bool)
-	// TODO: Add indexes and queries
+	Queries
 }
 
 // Mutable provides write access to world state.
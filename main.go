@@ -2,12 +2,13 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"log"
 	"os"
 
 	"github.com/playbymail/fh/internal/cerrs"
 	"github.com/playbymail/fh/internal/data/store"
+	"github.com/playbymail/fh/internal/engine/rng"
 	"github.com/spf13/cobra"
 )
 
@@ -29,23 +30,36 @@ func main() {
 	var createGalaxyCmd = &cobra.Command{
 		Use:   "galaxy",
 		Short: "Create a new galaxy",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return cerrs.ErrNotImplemented
-		},
-	}
+		RunE:  runCreateGalaxy,
+	}
+	createGalaxyCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	createGalaxyCmd.Flags().String("game", "", "Game ID")
+	createGalaxyCmd.Flags().String("seed", "", "Seed for the random number generator (default random)")
+	createGalaxyCmd.Flags().String("rng", "keyed", "Random number generator for the game, keyed or legacy")
+	createGalaxyCmd.Flags().String("rng-derivation", string(rng.LatestDerivation), "Seed derivation for keyed rng, v1 to replay older games or v2")
+	createGalaxyCmd.Flags().Bool("trace-rng", false, "Save a trace of every rng draw with each turn of the game")
 	createGalaxyCmd.Flags().Int("species", 0, "Number of species")
 	createGalaxyCmd.Flags().Int("stars", 0, "Number of stars")
 	createGalaxyCmd.Flags().Int("radius", 0, "Galactic radius in parsecs")
-	createGalaxyCmd.Flags().Bool("suggest-values", false, "Suggest appropriate values")
+	createGalaxyCmd.Flags().Bool("suggest-values", false, "Print suggested values and exit without creating the galaxy")
 	createGalaxyCmd.Flags().Bool("less-crowded", false, "Create a less crowded galaxy")
+	if err := createGalaxyCmd.MarkFlagRequired("species"); err != nil {
+		log.Fatalf("create galaxy --species: %v\n", err)
+	}
 	createCmd.AddCommand(createGalaxyCmd)
 
 	var createHomeSystemTemplatesCmd = &cobra.Command{
 		Use:   "home-system-templates",
 		Short: "Create home system templates",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return cerrs.ErrNotImplemented
-		},
+		RunE:  runCreateHomeSystemTemplates,
+	}
+	createHomeSystemTemplatesCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	createHomeSystemTemplatesCmd.Flags().String("game", "", "Game ID")
+	createHomeSystemTemplatesCmd.Flags().String("seed", "", "Seed for the random number generator (default the seed of the game)")
+	for _, name := range []string{"store", "game"} {
+		if err := createHomeSystemTemplatesCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("create home-system-templates --%s: %v\n", name, err)
+		}
 	}
 	createCmd.AddCommand(createHomeSystemTemplatesCmd)
 
@@ -70,14 +84,17 @@ func main() {
 	var createSpeciesCmd = &cobra.Command{
 		Use:   "species",
 		Short: "Create species",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return cerrs.ErrNotImplemented
-		},
+		RunE:  runCreateSpecies,
 	}
+	createSpeciesCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	createSpeciesCmd.Flags().String("game", "", "Game ID")
 	createSpeciesCmd.Flags().String("config", "", "Configuration file")
-	createSpeciesCmd.Flags().Int("radius", 10, "Radius")
-	if err := createSpeciesCmd.MarkFlagRequired("config"); err != nil {
-		log.Fatalf("create species --config: %v\n", err)
+	createSpeciesCmd.Flags().Int("radius", 10, "Minimum distance between home systems in parsecs")
+	createSpeciesCmd.Flags().String("seed", "", "Seed for the random number generator (default the seed of the game)")
+	for _, name := range []string{"store", "game", "config"} {
+		if err := createSpeciesCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("create species --%s: %v\n", name, err)
+		}
 	}
 	createCmd.AddCommand(createSpeciesCmd)
 
@@ -87,53 +104,91 @@ func main() {
 	}
 	rootCmd.AddCommand(exportCmd)
 
+	var exportLegacyCmd = &cobra.Command{
+		Use:   "legacy",
+		Short: "Export a game snapshot to the binary data files of the C engine",
+		RunE:  runExportLegacy,
+	}
+	exportLegacyCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	exportLegacyCmd.Flags().String("game", "", "Game ID")
+	exportLegacyCmd.Flags().Int("turn", 0, "Turn number")
+	exportLegacyCmd.Flags().String("output", "", "Output directory for the data files")
+	for _, name := range []string{"store", "game", "output"} {
+		if err := exportLegacyCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("export legacy --%s: %v\n", name, err)
+		}
+	}
+	exportCmd.AddCommand(exportLegacyCmd)
+
 	var exportSnapshotCmd = &cobra.Command{
 		Use:   "snapshot",
 		Short: "Export a game snapshot to JSON",
-		Run: func(cmd *cobra.Command, args []string) {
-			storePath, _ := cmd.Flags().GetString("store")
-			gameID, _ := cmd.Flags().GetString("game")
-			turnNum, _ := cmd.Flags().GetInt("turn")
-			outputPath, _ := cmd.Flags().GetString("output")
-			_, _, _ = gameID, turnNum, outputPath
-
-			st, err := store.OpenSQLiteStore(storePath)
-			if err != nil {
-				log.Fatalf("failed to open store: %v\n", err)
-			}
-			defer st.Close()
-
-			fmt.Printf("not implemented")
-			os.Exit(1)
-		},
+		RunE:  runExportSnapshot,
 	}
-	exportSnapshotCmd.Flags().String("store", "", "Path to SQLite store")
+	exportSnapshotCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
 	exportSnapshotCmd.Flags().String("game", "", "Game ID")
 	exportSnapshotCmd.Flags().Int("turn", 0, "Turn number")
 	exportSnapshotCmd.Flags().String("output", "", "Output directory for JSON files")
-	if err := exportSnapshotCmd.MarkFlagRequired("store"); err != nil {
-		log.Fatalf("config species --store: %v\n", err)
-	}
-	if err := exportSnapshotCmd.MarkFlagRequired("game"); err != nil {
-		log.Fatalf("config species --game: %v\n", err)
+	for _, name := range []string{"store", "game", "turn", "output"} {
+		if err := exportSnapshotCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("export snapshot --%s: %v\n", name, err)
+		}
 	}
-	if err := exportSnapshotCmd.MarkFlagRequired("turn"); err != nil {
-		log.Fatalf("config species --turn: %v\n", err)
+	exportCmd.AddCommand(exportSnapshotCmd)
+
+	var exportSpeciesCmd = &cobra.Command{
+		Use:   "species",
+		Short: "Export species to JSON in the format of the C engine",
+		RunE:  runExportSpecies,
 	}
-	if err := exportSnapshotCmd.MarkFlagRequired("output"); err != nil {
-		log.Fatalf("config species --output: %v\n", err)
+	exportSpeciesCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	exportSpeciesCmd.Flags().String("game", "", "Game ID")
+	exportSpeciesCmd.Flags().Int("turn", 0, "Turn number")
+	exportSpeciesCmd.Flags().String("output", "", "Output directory for JSON files")
+	for _, name := range []string{"store", "game", "output"} {
+		if err := exportSpeciesCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("export species --%s: %v\n", name, err)
+		}
 	}
-	exportCmd.AddCommand(exportSnapshotCmd)
+	exportCmd.AddCommand(exportSpeciesCmd)
 
 	var importCmd = &cobra.Command{
 		Use:   "import",
 		Short: "Import game data",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return cerrs.ErrNotImplemented
-		},
 	}
 	rootCmd.AddCommand(importCmd)
 
+	var importLegacyCmd = &cobra.Command{
+		Use:   "legacy",
+		Short: "Import the binary data files of the C engine",
+		RunE:  runImportLegacy,
+	}
+	importLegacyCmd.Flags().String("dir", "", "Directory with the C data files")
+	importLegacyCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	importLegacyCmd.Flags().String("game", "", "Game ID")
+	importLegacyCmd.Flags().Int("turn", 0, "Turn number (default the turn in galaxy.dat)")
+	for _, name := range []string{"dir", "store", "game"} {
+		if err := importLegacyCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("import legacy --%s: %v\n", name, err)
+		}
+	}
+	importCmd.AddCommand(importLegacyCmd)
+
+	var importSnapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Import a game snapshot exported with export snapshot",
+		RunE:  runImportSnapshot,
+	}
+	importSnapshotCmd.Flags().String("dir", "", "Directory with the snapshot")
+	importSnapshotCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	importSnapshotCmd.Flags().String("game", "", "Game ID (default the game in the manifest)")
+	importSnapshotCmd.Flags().Int("turn", 0, "Turn number (default the turn in the manifest)")
+	importSnapshotCmd.Flags().Bool("dry-run", false, "Check the snapshot without importing it")
+	if err := importSnapshotCmd.MarkFlagRequired("dir"); err != nil {
+		log.Fatalf("import snapshot --dir: %v\n", err)
+	}
+	importCmd.AddCommand(importSnapshotCmd)
+
 	var initCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initialize commands",
@@ -147,7 +202,15 @@ func main() {
 			path, _ := cmd.Flags().GetString("path")
 			force, _ := cmd.Flags().GetBool("force")
 
-			st, err := store.NewSQLiteStore(path, force)
+			jsonFiles, _ := cmd.Flags().GetBool("json")
+
+			var st store.Store
+			var err error
+			if jsonFiles {
+				st, err = store.NewJSONStore(path, force)
+			} else {
+				st, err = store.NewSQLiteStore(path, force)
+			}
 			if err != nil {
 				log.Fatalf("failed to initialize store: %v\n", err)
 			}
@@ -159,6 +222,7 @@ func main() {
 	initGameCmd.Flags().String("path", ".", "Path to the data store")
 	initGameCmd.Flags().String("id", "", "Game ID")
 	initGameCmd.Flags().Bool("force", false, "Force overwriting existing store")
+	initGameCmd.Flags().Bool("json", false, "Create a directory of JSON files instead of a SQLite database")
 	if err := initGameCmd.MarkFlagRequired("id"); err != nil {
 		log.Fatalf("init game --id")
 	}
@@ -167,12 +231,26 @@ func main() {
 	var inspectCmd = &cobra.Command{
 		Use:   "inspect",
 		Short: "Inspect game state",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return cerrs.ErrNotImplemented
-		},
 	}
 	rootCmd.AddCommand(inspectCmd)
 
+	var inspectRNGCmd = &cobra.Command{
+		Use:   "rng",
+		Short: "Print the rng draws saved with a turn",
+		RunE:  runInspectRNG,
+	}
+	inspectRNGCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	inspectRNGCmd.Flags().String("game", "", "Game ID")
+	inspectRNGCmd.Flags().Int("turn", 0, "Turn number")
+	inspectRNGCmd.Flags().StringArray("key", nil, "Only print draws from streams whose keys start with these keys (repeatable)")
+	inspectRNGCmd.Flags().String("phase", "", "Only print draws from this phase")
+	for _, name := range []string{"store", "game", "turn"} {
+		if err := inspectRNGCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("inspect rng --%s: %v\n", name, err)
+		}
+	}
+	inspectCmd.AddCommand(inspectRNGCmd)
+
 	var listCmd = &cobra.Command{
 		Use:   "list",
 		Short: "List game elements",
@@ -281,6 +359,20 @@ func main() {
 	}
 	showCmd.AddCommand(showDNumSpeciesCmd)
 
+	var showHomeSystemTemplatesCmd = &cobra.Command{
+		Use:   "home-system-templates",
+		Short: "Show home system templates",
+		RunE:  runShowHomeSystemTemplates,
+	}
+	showHomeSystemTemplatesCmd.Flags().String("store", "", "Path to the store, a SQLite database or a JSON store directory")
+	showHomeSystemTemplatesCmd.Flags().String("game", "", "Game ID")
+	for _, name := range []string{"store", "game"} {
+		if err := showHomeSystemTemplatesCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("show home-system-templates --%s: %v\n", name, err)
+		}
+	}
+	showCmd.AddCommand(showHomeSystemTemplatesCmd)
+
 	var showNumNaturalWormholesCmd = &cobra.Command{
 		Use:   "num-natural-wormholes",
 		Short: "Show number of natural wormholes in cluster",
@@ -344,6 +436,17 @@ func main() {
 	}
 	rootCmd.AddCommand(showTurnCmd)
 
+	dbMigrateCmd.PersistentFlags().String("store", "", "Path to SQLite store")
+	if err := dbMigrateCmd.MarkPersistentFlagRequired("store"); err != nil {
+		log.Fatalf("db migrate --store: %v\n", err)
+	}
+	dbMigrateDownCmd.Flags().Int("steps", 1, "Number of migrations to revert")
+	dbMigrateCmd.AddCommand(dbMigrateStatusCmd)
+	dbMigrateCmd.AddCommand(dbMigrateUpCmd)
+	dbMigrateCmd.AddCommand(dbMigrateDownCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	rootCmd.AddCommand(dbCmd)
+
 	updateGoldenCmd.AddCommand(updateGoldenRngCmd)
 	updateCmd.AddCommand(updateGoldenCmd)
 	rootCmd.AddCommand(updateCmd)
@@ -352,7 +455,9 @@ func main() {
 	rootCmd.AddCommand(versionCmd)
 
 	err := rootCmd.Execute()
-	if err != nil {
+	if errors.Is(err, cerrs.ErrNotExist) {
+		os.Exit(2)
+	} else if err != nil {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/playbymail/fh/internal/cerrs"
+	"github.com/playbymail/fh/internal/engine/galaxy"
+	"github.com/playbymail/fh/internal/engine/world"
+	"github.com/spf13/cobra"
+)
+
+// runShowHomeSystemTemplates prints the planets of each home system template,
+// with the life support each planet needs from the home planet.
+func runShowHomeSystemTemplates(cmd *cobra.Command, args []string) error {
+	storePath, _ := cmd.Flags().GetString("store")
+	gameID, _ := cmd.Flags().GetString("game")
+
+	st, err := openStore(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	state, err := world.Load(cmd.Context(), st, gameID, 0)
+	if err != nil {
+		return err
+	}
+	templates := state.ByKind(world.KindHomeSystem)
+	if len(templates) == 0 {
+		return fmt.Errorf("game %s has no home system templates: %w", gameID, cerrs.ErrNotExist)
+	}
+	for _, e := range templates {
+		t := e.(*world.HomeSystemTemplate)
+		home := t.HomePlanet()
+		fmt.Printf("%d planets, potential %d\n", t.NumPlanets, t.Potential)
+		fmt.Printf("  %5s %4s %5s %3s %3s %6s %4s  %s\n", "orbit", "dia", "grav", "tc", "pc", "md", "lsn", "atmosphere")
+		for i := range t.Planets {
+			p := &t.Planets[i]
+			lsn := "home"
+			if p != home && home != nil {
+				lsn = fmt.Sprint(galaxy.LifeSupportNeeded(home, p))
+			}
+			fmt.Printf("  %5d %4d %2d.%02d %3d %3d %3d.%02d %4s  %s\n",
+				p.Orbit, p.Diameter, p.Gravity/100, p.Gravity%100, p.TemperatureClass, p.PressureClass,
+				p.MiningDifficulty/100, p.MiningDifficulty%100, lsn, atmosphere(p.Gases))
+		}
+	}
+	return nil
+}
+
+// atmosphere formats the gases of a planet the way the reports do.
+func atmosphere(gases []world.GasPercent) string {
+	if len(gases) == 0 {
+		return "none"
+	}
+	list := make([]string, len(gases))
+	for i, g := range gases {
+		list[i] = fmt.Sprintf("%s(%d%%)", g.Gas, g.Percent)
+	}
+	return strings.Join(list, ",")
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/playbymail/fh/internal/engine/rng"
+	"github.com/playbymail/fh/internal/engine/rng/dice"
 	"github.com/spf13/cobra"
 )
 
@@ -50,6 +51,24 @@ var updateGoldenRngCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Update the dice golden files
+		diceRoot := filepath.Join("internal", "engine", "rng", "dice", "testdata")
+		if err := os.MkdirAll(diceRoot, 0755); err != nil {
+			fmt.Printf("failed to create %s: %v\n", diceRoot, err)
+			os.Exit(1)
+		}
+		for name, numbers := range dice.GenerateGolden(0xDEADBEEF) {
+			b.Reset()
+			for _, n := range numbers {
+				b.WriteString(fmt.Sprintf("%d\n", n))
+			}
+			goldenFile := filepath.Join(diceRoot, name)
+			if err := os.WriteFile(goldenFile, b.Bytes(), 0644); err != nil {
+				fmt.Printf("failed to write %s: %v\n", goldenFile, err)
+				os.Exit(1)
+			}
+		}
+
 		fmt.Println("Updated RNG golden files")
 	},
 }